@@ -0,0 +1,89 @@
+package main
+
+import "unicode"
+
+// Glyphs of the bitmap font are 3x5 pixels, drawn in cells of bitmapFontCellWidth x bitmapFontCellHeight pixels.
+const (
+	bitmapFontCellWidth  = 4
+	bitmapFontCellHeight = 6
+)
+
+// bitmapFont is a tiny uppercase font for the debug overlays, which cannot count on a font library.
+// Lowercase letters are drawn with the uppercase glyphs and unknown characters as a filled box.
+var bitmapFont = map[rune][5]string{
+	' ': {"...", "...", "...", "...", "..."},
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", ".##", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", ".#.", ".#.", ".#."},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'A': {"###", "#.#", "###", "#.#", "#.#"},
+	'B': {"##.", "#.#", "##.", "#.#", "##."},
+	'C': {"###", "#..", "#..", "#..", "###"},
+	'D': {"##.", "#.#", "#.#", "#.#", "##."},
+	'E': {"###", "#..", "###", "#..", "###"},
+	'F': {"###", "#..", "###", "#..", "#.."},
+	'G': {"###", "#..", "#.#", "#.#", "###"},
+	'H': {"#.#", "#.#", "###", "#.#", "#.#"},
+	'I': {"###", ".#.", ".#.", ".#.", "###"},
+	'J': {"..#", "..#", "..#", "#.#", "###"},
+	'K': {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'L': {"#..", "#..", "#..", "#..", "###"},
+	'M': {"#.#", "###", "###", "#.#", "#.#"},
+	'N': {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'O': {".#.", "#.#", "#.#", "#.#", ".#."},
+	'P': {"###", "#.#", "###", "#..", "#.."},
+	'Q': {"###", "#.#", "#.#", "###", "..#"},
+	'R': {"##.", "#.#", "##.", "#.#", "#.#"},
+	'S': {"###", "#..", "###", "..#", "###"},
+	'T': {"###", ".#.", ".#.", ".#.", ".#."},
+	'U': {"#.#", "#.#", "#.#", "#.#", "###"},
+	'V': {"#.#", "#.#", "#.#", "#.#", ".#."},
+	'W': {"#.#", "#.#", "###", "###", "#.#"},
+	'X': {"#.#", "#.#", ".#.", "#.#", "#.#"},
+	'Y': {"#.#", "#.#", ".#.", ".#.", ".#."},
+	'Z': {"###", "..#", ".#.", "#..", "###"},
+	'.': {"...", "...", "...", "...", ".#."},
+	',': {"...", "...", "...", ".#.", "#.."},
+	':': {"...", ".#.", "...", ".#.", "..."},
+	'-': {"...", "...", "###", "...", "..."},
+	'+': {"...", ".#.", "###", ".#.", "..."},
+	'=': {"...", "###", "...", "###", "..."},
+	'<': {"..#", ".#.", "#..", ".#.", "..#"},
+	'>': {"#..", ".#.", "..#", ".#.", "#.."},
+	'[': {"##.", "#..", "#..", "#..", "##."},
+	']': {".##", "..#", "..#", "..#", ".##"},
+	'(': {".#.", "#..", "#..", "#..", ".#."},
+	')': {".#.", "..#", "..#", "..#", ".#."},
+	'/': {"..#", "..#", ".#.", "#..", "#.."},
+	'#': {"#.#", "###", "#.#", "###", "#.#"},
+	'|': {".#.", ".#.", ".#.", ".#.", ".#."},
+	'*': {"...", "#.#", ".#.", "#.#", "..."},
+	'!': {".#.", ".#.", ".#.", "...", ".#."},
+	'?': {"###", "..#", ".##", "...", ".#."},
+	'_': {"...", "...", "...", "...", "###"},
+}
+
+var bitmapFontUnknown = [5]string{"###", "###", "###", "###", "###"}
+
+// DrawBitmapText calls plot for every lit pixel of the text, the top-left corner of the first glyph being at (0, 0).
+func DrawBitmapText(text string, plot func(positionX int, positionY int)) {
+	for index, character := range []rune(text) {
+		glyph, exists := bitmapFont[unicode.ToUpper(character)]
+		if !exists {
+			glyph = bitmapFontUnknown
+		}
+		for row, bits := range glyph {
+			for column, bit := range bits {
+				if bit == '#' {
+					plot(index*bitmapFontCellWidth+column, row)
+				}
+			}
+		}
+	}
+}
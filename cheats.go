@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// Cheat freezes a byte of Memory or a register to a value.
+type Cheat struct {
+	Target      string // Target is a hex address, of up to 32 bits to reach the MegaChip memory, V0 to VF, I, DT or ST.
+	Value       uint32 // Value is written to the target at every frame.
+	Description string
+	Enabled     bool
+}
+
+// ParseCheats reads a cheat list, one cheat per line with its target, an equal sign, the value and an optional
+// description. Addresses and values are hexadecimal, with or without a 0x prefix; lines starting with # are
+// comments and lines starting with ! are disabled cheats:
+//
+//	# Pong
+//	2F0 = 09    score
+//	V3 = 05     lives
+//	!DT = 00    no waiting
+func ParseCheats(text string) ([]Cheat, error) {
+	var cheats []Cheat
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cheat := Cheat{Enabled: true}
+		if strings.HasPrefix(line, "!") {
+			cheat.Enabled = false
+			line = strings.TrimSpace(line[1:])
+		}
+		target, rest, found := strings.Cut(line, "=")
+		fields := strings.Fields(rest)
+		if !found || len(fields) == 0 {
+			return nil, fmt.Errorf("line %d: expected TARGET = VALUE", lineNumber)
+		}
+		cheat.Target = strings.ToUpper(strings.TrimSpace(target))
+		if err := checkCheatTarget(cheat.Target); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		value, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(fields[0]), "0x"), 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid value %q", lineNumber, fields[0])
+		}
+		cheat.Value = uint32(value)
+		cheat.Description = strings.Join(fields[1:], " ")
+		cheats = append(cheats, cheat)
+	}
+	return cheats, nil
+}
+
+// FormatCheats writes a cheat list in the format read by ParseCheats.
+func FormatCheats(cheats []Cheat) string {
+	var text strings.Builder
+	for _, cheat := range cheats {
+		if !cheat.Enabled {
+			text.WriteString("!")
+		}
+		fmt.Fprintf(&text, "%s = %02X", cheat.Target, cheat.Value)
+		if cheat.Description != "" {
+			text.WriteString("    " + cheat.Description)
+		}
+		text.WriteString("\n")
+	}
+	return text.String()
+}
+
+func checkCheatTarget(target string) error {
+	switch {
+	case target == "I" || target == "DT" || target == "ST":
+		return nil
+	case len(target) == 2 && target[0] == 'V' && strings.ContainsRune("0123456789ABCDEF", rune(target[1])):
+		return nil
+	}
+	if _, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(target), "0x"), 16, 32); err != nil {
+		return fmt.Errorf("invalid target %q, expected an address, V0 to VF, I, DT or ST", target)
+	}
+	return nil
+}
+
+// apply writes the value of an enabled cheat to its target; addresses outside of Memory are ignored.
+func (cheat *Cheat) apply(core *chip8.Chip8Core) {
+	if !cheat.Enabled {
+		return
+	}
+	switch {
+	case cheat.Target == "I":
+		core.SetI(cheat.Value)
+	case cheat.Target == "DT":
+		core.DelayTimer = byte(cheat.Value)
+	case cheat.Target == "ST":
+		core.SoundTimer = byte(cheat.Value)
+	case len(cheat.Target) == 2 && cheat.Target[0] == 'V':
+		index, _ := strconv.ParseUint(cheat.Target[1:], 16, 8)
+		core.V[index] = byte(cheat.Value)
+	default:
+		address, _ := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(cheat.Target), "0x"), 16, 32)
+		if int(address) < len(core.Memory) {
+			core.Memory[address] = byte(cheat.Value)
+		}
+	}
+}
+
+// CheatEngine applies the cheat list of the loaded program at the end of every frame and holds the memory
+// search used to find new cheats. Cheat lists are kept per program, in Directory, in files named after the
+// SHA-1 of the program with the .cht extension; they are loaded when the program is.
+type CheatEngine struct {
+	Directory string        // Directory holds the cheat lists, none are loaded or saved when it is empty.
+	Cheats    []Cheat       // Cheats are the cheats of the loaded program.
+	Search    *MemorySearch // Search is the memory search in progress, nil when none has been started.
+
+	machine *chip8.Machine
+}
+
+// DefaultCheatDirectory is where cheat lists live, next to the other per-user configuration.
+func DefaultCheatDirectory() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "chip8", "cheats")
+}
+
+// NewCheatEngine hooks the engine to the machine and loads the cheat list of the program already loaded.
+func NewCheatEngine(machine *chip8.Machine, directory string) (*CheatEngine, error) {
+	cheatEngine := &CheatEngine{Directory: directory, machine: machine}
+	machine.OnFrame(func(core *chip8.Chip8Core) {
+		for index := range cheatEngine.Cheats {
+			cheatEngine.Cheats[index].apply(core)
+		}
+	})
+	machine.OnLoad(func() {
+		if err := cheatEngine.Load(); err != nil {
+			fmt.Println("Error loading cheats:", err)
+		}
+	})
+	return cheatEngine, cheatEngine.Load()
+}
+
+// Path is the file of the cheat list of the loaded program, empty without a Directory.
+func (cheatEngine *CheatEngine) Path() string {
+	if cheatEngine.Directory == "" {
+		return ""
+	}
+	return filepath.Join(cheatEngine.Directory, chip8.ROMHash(cheatEngine.machine.ROM())+".cht")
+}
+
+// Load replaces the cheats with the list of the loaded program, leaving none when it has no list yet.
+func (cheatEngine *CheatEngine) Load() error {
+	cheatEngine.Cheats = nil
+	cheatEngine.Search = nil
+	if cheatEngine.Path() == "" {
+		return nil
+	}
+	content, err := os.ReadFile(cheatEngine.Path())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cheats, err := ParseCheats(string(content))
+	if err != nil {
+		return fmt.Errorf("%s: %w", cheatEngine.Path(), err)
+	}
+	cheatEngine.Cheats = cheats
+	return nil
+}
+
+// Save writes the cheats to the list of the loaded program.
+func (cheatEngine *CheatEngine) Save() error {
+	if cheatEngine.Path() == "" {
+		return fmt.Errorf("no cheat directory")
+	}
+	if err := os.MkdirAll(cheatEngine.Directory, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(cheatEngine.Path(), []byte(FormatCheats(cheatEngine.Cheats)), 0o644)
+}
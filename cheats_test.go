@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// TestCheatsBeyond64K checks that cheats and memory searches reach the whole MegaChip memory.
+func TestCheatsBeyond64K(t *testing.T) {
+	core := chip8.NewChip8Core()
+	core.Memory = make([]byte, chip8.MegaChipMemorySize)
+	memorySearch := NewMemorySearch(core)
+
+	cheats, err := ParseCheats("1234567 = 2A  lives\nI = 1FFFFFF\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index := range cheats {
+		cheats[index].apply(core)
+	}
+	if core.Memory[0x1234567] != 0x2A || core.I != 0x1FFFFFF {
+		t.Errorf("the cheats left %02X at 1234567 and I at %X, want 2A and 1FFFFFF", core.Memory[0x1234567], core.I)
+	}
+
+	memorySearch.Narrow(core, CompareEqual, 0x2A)
+	if candidates := memorySearch.Candidates(10); len(candidates) != 1 || candidates[0] != 0x1234567 {
+		t.Errorf("the search found %X, want 1234567", candidates)
+	}
+}
+
+func TestFormatCheats(t *testing.T) {
+	cheats := []Cheat{
+		{Target: "2F0", Value: 0x09, Description: "score", Enabled: true},
+		{Target: "V3", Value: 0x05, Description: "lives", Enabled: true},
+		{Target: "DT", Value: 0x00, Description: "no waiting", Enabled: false},
+		{Target: "I", Value: 0x300, Enabled: true},
+	}
+	text := FormatCheats(cheats)
+	if expected := "2F0 = 09    score\nV3 = 05    lives\n!DT = 00    no waiting\nI = 300\n"; text != expected {
+		t.Errorf("formatted %q, want %q", text, expected)
+	}
+	parsed, err := ParseCheats(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(parsed, cheats) {
+		t.Errorf("the cheats read back as %+v, want %+v", parsed, cheats)
+	}
+}
+
+func TestParseCheatsErrors(t *testing.T) {
+	cases := []struct {
+		Text  string
+		Error string
+	}{
+		{"2F0 09", "line 1: expected TARGET = VALUE"},
+		{"# comment\n\n2F0 =", "line 3: expected TARGET = VALUE"},
+		{"VG = 01", `line 1: invalid target "VG"`},
+		{"PC = 0200", `line 1: invalid target "PC"`},
+		{"2F0 = 0xZZ", `line 1: invalid value "0xZZ"`},
+		{"2F0 = 100000000", `line 1: invalid value "100000000"`},
+	}
+	for _, errorCase := range cases {
+		if _, err := ParseCheats(errorCase.Text); err == nil || !strings.Contains(err.Error(), errorCase.Error) {
+			t.Errorf("%q gave the error %v, want one containing %q", errorCase.Text, err, errorCase.Error)
+		}
+	}
+}
+
+// TestCheatEngine checks that the cheats are applied again after every frame, and kept per program.
+func TestCheatEngine(t *testing.T) {
+	machine := chip8.NewMachine(chip8.NewManualClock())
+	// 0200: v3 := 1, 0202: i := 0x300, 0204: save v0, 0206: jump 0200
+	program := []byte{0x63, 0x01, 0xA3, 0x00, 0xF0, 0x55, 0x12, 0x00}
+	if err := machine.LoadROM(program); err != nil {
+		t.Fatal(err)
+	}
+	directory := t.TempDir()
+	cheatEngine, err := NewCheatEngine(machine, directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cheatEngine.Cheats, err = ParseCheats("300 = 2A\nV3 = 07\n!301 = FF\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for frame := 0; frame < 3; frame++ {
+		machine.RunFrame()
+		if machine.Core.Memory[0x300] != 0x2A || machine.Core.V[3] != 7 || machine.Core.Memory[0x301] != 0 {
+			t.Fatalf("frame %d left %02X at 0300, V3 at %d and %02X at 0301, want 2A, 7 and 00", frame,
+				machine.Core.Memory[0x300], machine.Core.V[3], machine.Core.Memory[0x301])
+		}
+	}
+
+	if err := cheatEngine.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cheatEngine.Path()); err != nil || !strings.HasPrefix(cheatEngine.Path(), directory) {
+		t.Errorf("the cheats were not saved in %s: %v", directory, err)
+	}
+	if err := machine.LoadROM([]byte{0x12, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	if len(cheatEngine.Cheats) != 0 {
+		t.Errorf("another program has the cheats %+v, want none", cheatEngine.Cheats)
+	}
+	if err := machine.LoadROM(program); err != nil {
+		t.Fatal(err)
+	}
+	if len(cheatEngine.Cheats) != 3 || cheatEngine.Cheats[0].Value != 0x2A {
+		t.Errorf("the program reloaded has the cheats %+v, want the 3 saved", cheatEngine.Cheats)
+	}
+}
@@ -0,0 +1,229 @@
+package chip8
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Chip8Core represents the chip8Core of the Chip-8 machine.
+// It contains all the necessary components to emulate a Chip-8 system, including Memory,
+// registers, counters, the call stack, and timers.
+type Chip8Core struct {
+	Memory []byte     // Memory represents the total Memory of the machine, 4096 bytes unless the platform has more.
+	V      [16]byte   // V contains the 16 general-purpose registers, named from V0 to VF.
+	I      uint32     // I is the index register used for store and load operations, 16 bits wide unless Memory is larger.
+	PC     uint16     // PC is the program counter that points to the current location in Memory from where to read the next instruction.
+	Stack  [16]uint16 // Stack is the call stack that holds return addresses when subroutines are called.
+	SP     uint16     // SP is the stack pointer that points to the top of the call stack.
+	Keys   [16]bool   // Keys represents the state of the 16-key hex keyboard, where each index corresponds to a specific key.
+	Screen [][]bool   // Screen represents the current state of the display, indexed by row then column, 64x32 pixels unless another resolution is selected.
+
+	DelayTimer byte // DelayTimer is the delay timer that is decremented at a frequency of 60Hz when it's non-zero.
+	SoundTimer byte // SoundTimer is the sound timer that is decremented at a frequency of 60Hz when it's non-zero.
+
+	Quirks Quirks  // Quirks selects the interpreter behaviour the running program expects.
+	Flags  [8]byte // Flags are the user flags of the HP48, which SUPER-CHIP programs save registers to with FX75.
+
+	MegaChip *MegaChip `json:"megaChip,omitempty"` // MegaChip holds the state of the MegaChip-8 extensions, nil until a program uses them.
+	Chip8X   *Chip8X   `json:"chip8X,omitempty"`   // Chip8X holds the state of the CHIP-8X hardware, nil on the other platforms.
+
+	exited  bool // exited is set by 00FD, for the machine to halt.
+	onWrite func(address uint32, value byte)
+	onRead  func(address uint32)
+	random  *rand.Rand
+	vip     *vipHardware
+}
+
+func NewChip8Core() *Chip8Core {
+	chip8Core := &Chip8Core{}
+	sprites := []byte{
+		0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
+		0x20, 0x60, 0x20, 0x20, 0x70, // 1
+		0xF0, 0x10, 0xF0, 0x80, 0xF0, // 2
+		0xF0, 0x10, 0xF0, 0x10, 0xF0, // 3
+		0x90, 0x90, 0xF0, 0x10, 0x10, // 4
+		0xF0, 0x80, 0xF0, 0x10, 0xF0, // 5
+		0xF0, 0x80, 0xF0, 0x90, 0xF0, // 6
+		0xF0, 0x10, 0x20, 0x40, 0x40, // 7
+		0xF0, 0x90, 0xF0, 0x90, 0xF0, // 8
+		0xF0, 0x90, 0xF0, 0x10, 0xF0, // 9
+		0xF0, 0x90, 0xF0, 0x90, 0x90, // A
+		0xE0, 0x90, 0xE0, 0x90, 0xE0, // B
+		0xF0, 0x80, 0x80, 0x80, 0xF0, // C
+		0xE0, 0x90, 0x90, 0x90, 0xE0, // D
+		0xF0, 0x80, 0xF0, 0x80, 0xF0, // E
+		0xF0, 0x80, 0xF0, 0x80, 0x80, // F
+	}
+	chip8Core.Memory = make([]byte, 4096)
+	chip8Core.PC = 0x200
+	chip8Core.SP = 0
+	chip8Core.Quirks = DefaultQuirks
+	copy(chip8Core.Memory[0x000:], sprites)
+	copy(chip8Core.Memory[bigFontAddress:], bigFont)
+	chip8Core.SetResolution(64, 32)
+	return chip8Core
+}
+
+// LoadROM copies the program to Memory at PC, where its execution starts.
+func (chip8Core *Chip8Core) LoadROM(data []byte) error {
+	if int(chip8Core.PC)+len(data) > len(chip8Core.Memory) {
+		return fmt.Errorf("program of %d bytes does not fit in Memory at %#04x", len(data), chip8Core.PC)
+	}
+	copy(chip8Core.Memory[chip8Core.PC:], data)
+	return nil
+}
+
+// SetMemorySize resizes Memory, keeping its content.
+func (chip8Core *Chip8Core) SetMemorySize(size int) {
+	memory := make([]byte, size)
+	copy(memory, chip8Core.Memory)
+	chip8Core.Memory = memory
+}
+
+// WriteMemory stores a byte on behalf of the program, notifying the machine watching the writes if any.
+// Addresses past the end of Memory wrap around to its start, as on the original interpreters.
+func (chip8Core *Chip8Core) WriteMemory(address uint32, value byte) {
+	address = chip8Core.wrapAddress(address)
+	chip8Core.Memory[address] = value
+	if chip8Core.onWrite != nil {
+		chip8Core.onWrite(address, value)
+	}
+}
+
+// ReadMemory loads a byte on behalf of the program, notifying the machine watching the reads if any.
+// Addresses past the end of Memory wrap around to its start.
+func (chip8Core *Chip8Core) ReadMemory(address uint32) byte {
+	address = chip8Core.wrapAddress(address)
+	if chip8Core.onRead != nil {
+		chip8Core.onRead(address)
+	}
+	return chip8Core.Memory[address]
+}
+
+func (chip8Core *Chip8Core) FetchOpcode() uint16 {
+	return uint16(chip8Core.Memory[chip8Core.PC])<<8 | uint16(chip8Core.Memory[chip8Core.wrapAddress(uint32(chip8Core.PC+1))])
+}
+
+// wrapAddress brings an address back inside Memory.
+func (chip8Core *Chip8Core) wrapAddress(address uint32) uint32 {
+	return uint32(int(address) % len(chip8Core.Memory))
+}
+
+// Seed makes the random numbers of CXNN a reproducible sequence, for tests and replays.
+func (chip8Core *Chip8Core) Seed(seed int64) {
+	chip8Core.random = rand.New(rand.NewSource(seed))
+}
+
+// RandomByte returns the next random number of CXNN, from the seeded sequence if any.
+func (chip8Core *Chip8Core) RandomByte() byte {
+	if chip8Core.random != nil {
+		return byte(chip8Core.random.Intn(256))
+	}
+	return byte(rand.Intn(256))
+}
+
+func (chip8Core *Chip8Core) UpdateTimers() {
+	if chip8Core.DelayTimer > 0 {
+		chip8Core.DelayTimer--
+	}
+	if chip8Core.SoundTimer > 0 {
+		chip8Core.SoundTimer--
+	}
+}
+
+// SetKey presses or releases a key; only the low nibble of index counts, as on the COSMAC VIP, but on CHIP-8X
+// where 0x10 to 0x1F are the keys of the second keypad.
+func (chip8Core *Chip8Core) SetKey(index uint8, value bool) {
+	if chip8Core.Chip8X != nil && index&0xF0 == 0x10 {
+		chip8Core.Chip8X.SecondKeys[index&0xF] = value
+		return
+	}
+	chip8Core.Keys[index&0xF] = value
+}
+
+// GetKey tells whether a key is pressed; only the low nibble of index counts, as on the COSMAC VIP.
+func (chip8Core *Chip8Core) GetKey(index uint8) bool {
+	return chip8Core.Keys[index&0xF]
+}
+
+func (chip8Core *Chip8Core) SetPixel(positionX uint8, positionY uint8, value bool) {
+	chip8Core.Screen[positionY][positionX] = value
+}
+
+func (chip8Core *Chip8Core) GetPixel(positionX uint8, positionY uint8) bool {
+	return chip8Core.Screen[positionY][positionX]
+}
+
+func (chip8Core *Chip8Core) SetRegister(index uint8, value byte) {
+	chip8Core.V[index] = value
+}
+
+func (chip8Core *Chip8Core) GetRegister(index uint8) byte {
+	return chip8Core.V[index]
+}
+
+// SetI loads the index register, which wraps around at 16 bits, or at the end of a larger Memory.
+func (chip8Core *Chip8Core) SetI(value uint32) {
+	chip8Core.I = value % uint32(max(len(chip8Core.Memory), 0x10000))
+}
+
+func (chip8Core *Chip8Core) GetI() uint32 {
+	return chip8Core.I
+}
+
+// IncrementPC moves PC forward, wrapping around at the end of Memory.
+func (chip8Core *Chip8Core) IncrementPC(value uint16) {
+	chip8Core.PC = uint16(chip8Core.wrapAddress(uint32(chip8Core.PC + value)))
+}
+
+// SetPC moves PC to an address, wrapping around at the end of Memory.
+func (chip8Core *Chip8Core) SetPC(value uint16) {
+	chip8Core.PC = uint16(chip8Core.wrapAddress(uint32(value)))
+}
+
+func (chip8Core *Chip8Core) GetPC() uint16 {
+	return chip8Core.PC
+}
+
+func (chip8Core *Chip8Core) GetSP() uint16 {
+	return chip8Core.SP
+}
+
+func (chip8Core *Chip8Core) PushStack(value uint16) {
+	chip8Core.Stack[chip8Core.SP] = value
+	chip8Core.SP++
+}
+
+func (chip8Core *Chip8Core) PopStack() uint16 {
+	chip8Core.SP--
+	return chip8Core.Stack[chip8Core.SP]
+}
+
+// ClearScreen blanks the display; in MegaChip mode it first shows what was drawn since the last call.
+func (chip8Core *Chip8Core) ClearScreen() {
+	if chip8Core.MegaChipEnabled() {
+		chip8Core.presentMegaChipFrame()
+		return
+	}
+	for positionY := 0; positionY < chip8Core.ScreenHeight(); positionY++ {
+		for positionX := 0; positionX < chip8Core.ScreenWidth(); positionX++ {
+			chip8Core.SetPixel(uint8(positionX), uint8(positionY), false)
+		}
+	}
+}
+
+// SetResolution switches the display to width x height pixels, such as 128x64 for the high resolution modes, and clears it.
+func (chip8Core *Chip8Core) SetResolution(width int, height int) {
+	chip8Core.Screen = make([][]bool, height)
+	for positionY := range chip8Core.Screen {
+		chip8Core.Screen[positionY] = make([]bool, width)
+	}
+}
+
+func (chip8Core *Chip8Core) ScreenWidth() int {
+	return len(chip8Core.Screen[0])
+}
+
+func (chip8Core *Chip8Core) ScreenHeight() int {
+	return len(chip8Core.Screen)
+}
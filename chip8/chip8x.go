@@ -0,0 +1,141 @@
+package chip8
+
+import (
+	"image"
+	"image/color"
+)
+
+// CHIP-8X ran on a COSMAC VIP with the VP-590 color board and a second keypad. The color board divides the
+// 64x32 display in zones 8 pixels wide, each row of a zone having one of 8 foreground colors, on a background
+// of one of 4 colors; the VP-595 sound board and other I/O devices are reached through a port.
+const (
+	chip8XZoneWidth  = 8
+	chip8XZoneHeight = 4 // chip8XZoneHeight is the height of the zones BXY0 colors, BXYN coloring single rows.
+	chip8XColumns    = 64 / chip8XZoneWidth
+	chip8XRows       = 32
+)
+
+// Chip8XForegrounds are the colors of the lit pixels, selected by the 3 low bits of VY: red, blue and green
+// from the lowest bit.
+var Chip8XForegrounds = [8]color.RGBA{
+	{A: 0xFF},
+	{R: 0xFF, A: 0xFF},
+	{B: 0xFF, A: 0xFF},
+	{R: 0xFF, B: 0xFF, A: 0xFF},
+	{G: 0xFF, A: 0xFF},
+	{R: 0xFF, G: 0xFF, A: 0xFF},
+	{G: 0xFF, B: 0xFF, A: 0xFF},
+	{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+}
+
+// Chip8XBackgrounds are the background colors 02A0 goes through: blue, black, green and red.
+var Chip8XBackgrounds = [4]color.RGBA{
+	{B: 0x80, A: 0xFF},
+	{A: 0xFF},
+	{G: 0x80, A: 0xFF},
+	{R: 0x80, A: 0xFF},
+}
+
+// Chip8X holds the state the CHIP-8X hardware adds to the core.
+type Chip8X struct {
+	Background int                              // Background is the index of the background color in Chip8XBackgrounds.
+	Colors     [chip8XRows][chip8XColumns]uint8 // Colors are the foreground colors of the zones, per row of pixels.
+	SecondKeys [16]bool                         // SecondKeys is the state of the second keypad.
+	OutputPort byte                             // OutputPort is the last byte FXF8 sent to the I/O port.
+	InputPort  byte                             // InputPort is the byte FXFB reads from the I/O port.
+}
+
+// NewChip8X returns the state of the hardware after a reset: red pixels on a blue background.
+func NewChip8X() *Chip8X {
+	chip8X := &Chip8X{}
+	for row := range chip8X.Colors {
+		for column := range chip8X.Colors[row] {
+			chip8X.Colors[row][column] = 1
+		}
+	}
+	return chip8X
+}
+
+// CycleBackground moves to the next background color.
+func (chip8X *Chip8X) CycleBackground() {
+	chip8X.Background = (chip8X.Background + 1) % len(Chip8XBackgrounds)
+}
+
+// ColorZones colors the zones of 8x4 pixels from the packed position in horizontal and vertical: the low nibble
+// is the first zone and the high nibble the number of zones after it, the zones past the display being ignored.
+func (chip8X *Chip8X) ColorZones(horizontal uint8, vertical uint8, foreground uint8) {
+	for zoneY := int(vertical & 0xF); zoneY <= int(vertical&0xF)+int(vertical>>4); zoneY++ {
+		for zoneX := int(horizontal & 0xF); zoneX <= int(horizontal&0xF)+int(horizontal>>4) && zoneX < chip8XColumns; zoneX++ {
+			for row := zoneY * chip8XZoneHeight; row < (zoneY+1)*chip8XZoneHeight && row < chip8XRows; row++ {
+				chip8X.Colors[row][zoneX] = foreground & 7
+			}
+		}
+	}
+}
+
+// ColorRows colors count rows of pixels from row in the zone holding the pixel column positionX.
+func (chip8X *Chip8X) ColorRows(positionX uint8, row uint8, count uint8, foreground uint8) {
+	zoneX := int(positionX) / chip8XZoneWidth % chip8XColumns
+	for index := 0; index < int(count); index++ {
+		chip8X.Colors[(int(row)+index)%chip8XRows][zoneX] = foreground & 7
+	}
+}
+
+// Chip8XDecoder decodes the instructions of CHIP-8X, which replace 5XY1, BNNN, EXF2, EXF5, FXF8 and FXFB and add
+// 02A0.
+type Chip8XDecoder struct {
+	OpcodeDecoder
+}
+
+func (chip8XDecoder *Chip8XDecoder) Decode(opcode uint16) Instruction {
+	switch {
+	case opcode == 0x02A0:
+		return &CycleBackground{GenericInstruction{opcode}}
+	case opcode&0xF00F == 0x5001:
+		return &AddVyToVxNibbles{GenericInstruction{opcode}}
+	case opcode&0xF00F == 0xB000:
+		return &SetColorZones{GenericInstruction{opcode}}
+	case opcode&0xF000 == 0xB000:
+		return &SetColorRows{GenericInstruction{opcode}}
+	case opcode&0xF0FF == 0xE0F2:
+		return &SkipIfSecondKeyPressed{GenericInstruction{opcode}}
+	case opcode&0xF0FF == 0xE0F5:
+		return &SkipIfSecondKeyNotPressed{GenericInstruction{opcode}}
+	case opcode&0xF0FF == 0xF0F8:
+		return &OutputToPort{GenericInstruction{opcode}}
+	case opcode&0xF0FF == 0xF0FB:
+		return &InputFromPort{GenericInstruction{opcode}}
+	}
+	return chip8XDecoder.OpcodeDecoder.Decode(opcode)
+}
+
+// chip8XState returns the CHIP-8X state of the core, creating it on the first CHIP-8X instruction.
+func (chip8Core *Chip8Core) chip8XState() *Chip8X {
+	if chip8Core.Chip8X == nil {
+		chip8Core.Chip8X = NewChip8X()
+	}
+	return chip8Core.Chip8X
+}
+
+// GetSecondKey tells whether a key of the second keypad is pressed; only the low nibble of index counts.
+func (chip8Core *Chip8Core) GetSecondKey(index uint8) bool {
+	return chip8Core.chip8XState().SecondKeys[index&0xF]
+}
+
+// Chip8XImage renders the Screen in the colors of the zones, each pixel becoming a scale x scale square.
+func Chip8XImage(core *Chip8Core, scale int) *image.RGBA {
+	chip8X := core.Chip8X
+	width, height := core.ScreenWidth(), core.ScreenHeight()
+	screenImage := image.NewRGBA(image.Rect(0, 0, width*scale, height*scale))
+	for positionY := 0; positionY < height*scale; positionY++ {
+		for positionX := 0; positionX < width*scale; positionX++ {
+			pixelX, pixelY := positionX/scale, positionY/scale
+			fill := Chip8XBackgrounds[chip8X.Background]
+			if core.GetPixel(uint8(pixelX), uint8(pixelY)) {
+				fill = Chip8XForegrounds[chip8X.Colors[pixelY%chip8XRows][pixelX/chip8XZoneWidth%chip8XColumns]]
+			}
+			screenImage.SetRGBA(positionX, positionY, fill)
+		}
+	}
+	return screenImage
+}
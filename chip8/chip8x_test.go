@@ -0,0 +1,128 @@
+package chip8
+
+import "testing"
+
+// loadPlatformProgram returns a machine running program on a platform, before its first instruction.
+func loadPlatformProgram(t *testing.T, platformName string, program []byte) *Machine {
+	t.Helper()
+	machine := NewMachine(NewManualClock())
+	if err := machine.LoadROMImage(&ROMImage{Data: program, Platform: Platforms[platformName]}); err != nil {
+		t.Fatal(err)
+	}
+	return machine
+}
+
+func TestChip8XInstructions(t *testing.T) {
+	t.Run("02A0", func(t *testing.T) {
+		machine := loadPlatformProgram(t, "chip8x", []byte{0x02, 0xA0, 0x02, 0xA0, 0x02, 0xA0, 0x02, 0xA0})
+		for _, expected := range []int{1, 2, 3, 0} {
+			machine.Step()
+			if machine.Core.Chip8X.Background != expected {
+				t.Fatalf("background is %d, want %d", machine.Core.Chip8X.Background, expected)
+			}
+		}
+	})
+
+	t.Run("5XY1", func(t *testing.T) {
+		machine := loadPlatformProgram(t, "chip8x", []byte{0x51, 0x21})
+		machine.Core.V[1], machine.Core.V[2] = 0x35, 0x46
+		machine.Step()
+		if machine.Core.V[1] != 0x73 {
+			t.Errorf("V1 is %02X, want 73", machine.Core.V[1])
+		}
+		if machine.Core.PC != 0x302 {
+			t.Errorf("PC is %04X, want 0302", machine.Core.PC)
+		}
+	})
+
+	t.Run("BXY0", func(t *testing.T) {
+		machine := loadPlatformProgram(t, "chip8x", []byte{0xB1, 0x30})
+		// Zones 2 and 3 across, zone 1 down (rows 4 to 7), in color 6.
+		machine.Core.V[1], machine.Core.V[2], machine.Core.V[3] = 0x12, 0x01, 0x06
+		machine.Step()
+		for row := range machine.Core.Chip8X.Colors {
+			for column, foreground := range machine.Core.Chip8X.Colors[row] {
+				expected := uint8(1)
+				if row >= 4 && row < 8 && (column == 2 || column == 3) {
+					expected = 6
+				}
+				if foreground != expected {
+					t.Fatalf("row %d of zone %d has color %d, want %d", row, column, foreground, expected)
+				}
+			}
+		}
+	})
+
+	t.Run("BXYN", func(t *testing.T) {
+		machine := loadPlatformProgram(t, "chip8x", []byte{0xB1, 0x32})
+		// Rows 10 and 11 of the zone holding column 24, in color 5.
+		machine.Core.V[1], machine.Core.V[2], machine.Core.V[3] = 24, 10, 0x05
+		machine.Step()
+		colored := 0
+		for row := range machine.Core.Chip8X.Colors {
+			for column, foreground := range machine.Core.Chip8X.Colors[row] {
+				if foreground == 5 {
+					colored++
+					if column != 3 || (row != 10 && row != 11) {
+						t.Errorf("row %d of zone %d is colored", row, column)
+					}
+				}
+			}
+		}
+		if colored != 2 {
+			t.Errorf("%d rows colored, want 2", colored)
+		}
+	})
+
+	for _, skipCase := range []struct {
+		Name     string
+		Program  []byte
+		Pressed  bool
+		Expected uint16
+	}{
+		{"EXF2 pressed", []byte{0xE1, 0xF2}, true, 0x304},
+		{"EXF2 released", []byte{0xE1, 0xF2}, false, 0x302},
+		{"EXF5 pressed", []byte{0xE1, 0xF5}, true, 0x302},
+		{"EXF5 released", []byte{0xE1, 0xF5}, false, 0x304},
+	} {
+		t.Run(skipCase.Name, func(t *testing.T) {
+			machine := loadPlatformProgram(t, "chip8x", skipCase.Program)
+			machine.Core.V[1] = 3
+			machine.Core.SetKey(0x13, skipCase.Pressed)
+			// The first keypad must not count.
+			machine.Core.SetKey(0x03, !skipCase.Pressed)
+			machine.Step()
+			if machine.Core.PC != skipCase.Expected {
+				t.Errorf("PC is %04X, want %04X", machine.Core.PC, skipCase.Expected)
+			}
+		})
+	}
+
+	t.Run("FXF8 FXFB", func(t *testing.T) {
+		machine := loadPlatformProgram(t, "chip8x", []byte{0xF1, 0xF8, 0xF2, 0xFB})
+		machine.Core.V[1] = 0x42
+		machine.Core.Chip8X.InputPort = 0x99
+		machine.Step()
+		machine.Step()
+		if machine.Core.Chip8X.OutputPort != 0x42 {
+			t.Errorf("output port holds %02X, want 42", machine.Core.Chip8X.OutputPort)
+		}
+		if machine.Core.V[2] != 0x99 {
+			t.Errorf("V2 is %02X, want 99", machine.Core.V[2])
+		}
+	})
+}
+
+func TestHiResClearScreen(t *testing.T) {
+	program := make([]byte, 0xC2)
+	program[0xC0], program[0xC1] = 0x02, 0x30
+	machine := loadPlatformProgram(t, "hires", program)
+	if machine.Core.PC != 0x2C0 || machine.Core.ScreenWidth() != 64 || machine.Core.ScreenHeight() != 64 {
+		t.Fatalf("starts at %04X with a %dx%d display, want 02C0 with 64x64", machine.Core.PC, machine.Core.ScreenWidth(), machine.Core.ScreenHeight())
+	}
+	machine.Core.SetPixel(10, 60, true)
+	machine.Step()
+	if machine.Core.GetPixel(10, 60) {
+		t.Error("0230 left the bottom of the display lit")
+	}
+}
@@ -0,0 +1,224 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FrameRate is the number of frames per second of CHIP-8 machines, the rate of their timers.
+const FrameRate = 60
+
+// FramePeriod is the duration of a frame in real time.
+const FramePeriod = time.Second / FrameRate
+
+// maxFrameLag is how far behind a RealTimeClock may fall, after the host was suspended for instance,
+// before it gives up catching up and starts again from the current time.
+const maxFrameLag = 5
+
+// Clock paces the frames of a Machine driven by Run. The implementations are interchangeable: real time for
+// playing, manual for tests advancing the frames themselves and fast for benchmarks and headless runs.
+type Clock interface {
+	Start()          // Start begins counting frames from now.
+	Stop()           // Stop ends the counting; a pending WaitFrame returns false.
+	WaitFrame() bool // WaitFrame blocks until the next frame is due and returns false once the clock is stopped.
+}
+
+// SpeedClock is a Clock whose pace can change while it runs. A speed of 2 runs two frames in the time of one,
+// 0.5 one frame in the time of two and 0 as many as the host can execute.
+type SpeedClock interface {
+	Clock
+	SetSpeed(speed float64)
+}
+
+// ClockNames lists the clocks NewClock knows.
+var ClockNames = []string{"realtime", "fast", "manual"}
+
+// NewClock creates a clock by name: realtime, fast or manual.
+func NewClock(name string) (Clock, error) {
+	switch name {
+	case "realtime":
+		return NewRealTimeClock(), nil
+	case "fast":
+		return NewFastClock(), nil
+	case "manual":
+		return NewManualClock(), nil
+	}
+	return nil, fmt.Errorf("unknown clock %q (available: %s)", name, strings.Join(ClockNames, ", "))
+}
+
+// RealTimeClock schedules the frames at exactly FrameRate per second, times its speed. Each frame is due at
+// a fixed offset from the start rather than a period after the previous one, so late wake-ups do not
+// accumulate into drift.
+type RealTimeClock struct {
+	mutex  sync.Mutex
+	start  time.Time
+	frames int64
+	speed  float64
+	done   chan struct{}
+}
+
+func NewRealTimeClock() *RealTimeClock {
+	return &RealTimeClock{speed: 1}
+}
+
+func (realTimeClock *RealTimeClock) Start() {
+	realTimeClock.mutex.Lock()
+	defer realTimeClock.mutex.Unlock()
+	realTimeClock.start = time.Now()
+	realTimeClock.frames = 0
+	realTimeClock.done = make(chan struct{})
+}
+
+// SetSpeed changes the pace from the next frame on.
+func (realTimeClock *RealTimeClock) SetSpeed(speed float64) {
+	realTimeClock.mutex.Lock()
+	defer realTimeClock.mutex.Unlock()
+	realTimeClock.speed = speed
+	realTimeClock.start = time.Now()
+	realTimeClock.frames = 0
+}
+
+// Stop may be called before Start and more than once.
+func (realTimeClock *RealTimeClock) Stop() {
+	realTimeClock.mutex.Lock()
+	defer realTimeClock.mutex.Unlock()
+	if realTimeClock.done == nil {
+		return
+	}
+	select {
+	case <-realTimeClock.done:
+	default:
+		close(realTimeClock.done)
+	}
+}
+
+func (realTimeClock *RealTimeClock) WaitFrame() bool {
+	realTimeClock.mutex.Lock()
+	wait := realTimeClock.nextFrame(time.Now())
+	done := realTimeClock.done
+	realTimeClock.mutex.Unlock()
+	if done == nil {
+		return false
+	}
+	// An overdue frame would leave select to choose between done and the timer at random.
+	select {
+	case <-done:
+		return false
+	default:
+	}
+	if wait <= 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// nextFrame counts a frame and returns how long after now it is due, with the mutex held.
+func (realTimeClock *RealTimeClock) nextFrame(now time.Time) time.Duration {
+	if realTimeClock.speed <= 0 {
+		return 0
+	}
+	realTimeClock.frames++
+	period := float64(time.Second) / (FrameRate * realTimeClock.speed)
+	wait := realTimeClock.start.Add(time.Duration(float64(realTimeClock.frames) * period)).Sub(now)
+	if wait < -maxFrameLag*time.Duration(period) {
+		realTimeClock.start = now
+		realTimeClock.frames = 0
+		return 0
+	}
+	return wait
+}
+
+// FastClock lets the frames run as fast as the host can execute them.
+type FastClock struct {
+	mutex   sync.Mutex
+	stopped bool
+}
+
+func NewFastClock() *FastClock {
+	return &FastClock{}
+}
+
+func (fastClock *FastClock) Start() {
+	fastClock.mutex.Lock()
+	defer fastClock.mutex.Unlock()
+	fastClock.stopped = false
+}
+
+func (fastClock *FastClock) Stop() {
+	fastClock.mutex.Lock()
+	defer fastClock.mutex.Unlock()
+	fastClock.stopped = true
+}
+
+func (fastClock *FastClock) WaitFrame() bool {
+	fastClock.mutex.Lock()
+	defer fastClock.mutex.Unlock()
+	return !fastClock.stopped
+}
+
+// ManualClock only lets frames run when told to, for tests stepping a running machine deterministically.
+// Its time is virtual: Elapsed counts the frames that ran, not the time that passed.
+type ManualClock struct {
+	mutex   sync.Mutex
+	changed *sync.Cond
+	pending int
+	frames  int64
+	stopped bool
+}
+
+func NewManualClock() *ManualClock {
+	manualClock := &ManualClock{}
+	manualClock.changed = sync.NewCond(&manualClock.mutex)
+	return manualClock
+}
+
+func (manualClock *ManualClock) Start() {
+	manualClock.mutex.Lock()
+	defer manualClock.mutex.Unlock()
+	manualClock.stopped = false
+}
+
+func (manualClock *ManualClock) Stop() {
+	manualClock.mutex.Lock()
+	defer manualClock.mutex.Unlock()
+	manualClock.stopped = true
+	manualClock.changed.Broadcast()
+}
+
+// Advance lets count more frames run.
+func (manualClock *ManualClock) Advance(count int) {
+	manualClock.mutex.Lock()
+	defer manualClock.mutex.Unlock()
+	manualClock.pending += count
+	manualClock.changed.Broadcast()
+}
+
+func (manualClock *ManualClock) WaitFrame() bool {
+	manualClock.mutex.Lock()
+	defer manualClock.mutex.Unlock()
+	for manualClock.pending == 0 && !manualClock.stopped {
+		manualClock.changed.Wait()
+	}
+	if manualClock.stopped {
+		return false
+	}
+	manualClock.pending--
+	manualClock.frames++
+	return true
+}
+
+// Elapsed is the virtual time of the frames let through so far.
+func (manualClock *ManualClock) Elapsed() time.Duration {
+	manualClock.mutex.Lock()
+	defer manualClock.mutex.Unlock()
+	return time.Duration(manualClock.frames) * FramePeriod
+}
@@ -0,0 +1,144 @@
+package chip8
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManualClock(t *testing.T) {
+	manualClock := NewManualClock()
+	manualClock.Start()
+	manualClock.Advance(2)
+	for frame := 0; frame < 2; frame++ {
+		if !manualClock.WaitFrame() {
+			t.Fatalf("frame %d did not run", frame)
+		}
+	}
+	if manualClock.Elapsed() != 2*FramePeriod {
+		t.Errorf("elapsed %v after 2 frames, want %v", manualClock.Elapsed(), 2*FramePeriod)
+	}
+
+	waited := make(chan bool)
+	go func() { waited <- manualClock.WaitFrame() }()
+	select {
+	case <-waited:
+		t.Fatal("a frame ran without Advance")
+	case <-time.After(20 * time.Millisecond):
+	}
+	manualClock.Advance(1)
+	if ran := <-waited; !ran {
+		t.Error("the frame let through by Advance did not run")
+	}
+
+	go func() { waited <- manualClock.WaitFrame() }()
+	manualClock.Stop()
+	if ran := <-waited; ran {
+		t.Error("WaitFrame returned true after Stop")
+	}
+	if manualClock.Elapsed() != 3*FramePeriod {
+		t.Errorf("elapsed %v after 3 frames, want %v", manualClock.Elapsed(), 3*FramePeriod)
+	}
+}
+
+// TestRealTimeClockSchedule checks that frames are due at fixed offsets from the start, so a late frame is
+// caught up on rather than pushing the following ones back, and that a clock too far behind starts over.
+func TestRealTimeClockSchedule(t *testing.T) {
+	realTimeClock := NewRealTimeClock()
+	realTimeClock.Start()
+	start := realTimeClock.start
+	period := FramePeriod
+
+	cases := []struct {
+		Name     string
+		Now      time.Duration // Now is the time of the call since the start.
+		Expected time.Duration
+	}{
+		{"first frame", 0, period},
+		{"on time", period, period},
+		// The host woke up 1.5 frames late: the next frames are due at once, then on the original schedule.
+		{"late", 3*period + period/2, -period / 2},
+		{"caught up", 3*period + period/2, period / 2},
+		{"back on schedule", 4 * period, period},
+		// More than maxFrameLag frames behind, the schedule starts over from now.
+		{"far behind", 20 * period, 0},
+		{"started over", 20 * period, period},
+	}
+	for _, scheduleCase := range cases {
+		wait := realTimeClock.nextFrame(start.Add(scheduleCase.Now))
+		if difference := wait - scheduleCase.Expected; difference < -time.Microsecond || difference > time.Microsecond {
+			t.Errorf("%s: frame due in %v, want %v", scheduleCase.Name, wait, scheduleCase.Expected)
+		}
+	}
+
+	realTimeClock.SetSpeed(2)
+	start = realTimeClock.start
+	if wait := realTimeClock.nextFrame(start); wait != period/2 {
+		t.Errorf("at double speed the first frame is due in %v, want %v", wait, period/2)
+	}
+	realTimeClock.SetSpeed(0)
+	if wait := realTimeClock.nextFrame(start); wait != 0 {
+		t.Errorf("uncapped, the frame is due in %v, want at once", wait)
+	}
+}
+
+func TestRealTimeClock(t *testing.T) {
+	realTimeClock := NewRealTimeClock()
+	// Stopping a clock that never started does nothing.
+	realTimeClock.Stop()
+
+	realTimeClock.Start()
+	began := time.Now()
+	for frame := 0; frame < 3; frame++ {
+		if !realTimeClock.WaitFrame() {
+			t.Fatalf("frame %d did not run", frame)
+		}
+	}
+	if elapsed := time.Since(began); elapsed < 3*FramePeriod-time.Millisecond {
+		t.Errorf("3 frames took %v, want at least %v", elapsed, 3*FramePeriod)
+	}
+
+	waited := make(chan bool)
+	go func() { waited <- realTimeClock.WaitFrame() }()
+	realTimeClock.Stop()
+	realTimeClock.Stop()
+	if ran := <-waited; ran {
+		t.Error("WaitFrame returned true after Stop")
+	}
+}
+
+// TestRealTimeClockStopOverdue checks that once stopped the clock lets no frame through, even overdue ones.
+func TestRealTimeClockStopOverdue(t *testing.T) {
+	realTimeClock := NewRealTimeClock()
+	realTimeClock.Start()
+	realTimeClock.start = time.Now().Add(-time.Second)
+	realTimeClock.Stop()
+	for frame := 0; frame < 100; frame++ {
+		if realTimeClock.WaitFrame() {
+			t.Fatalf("overdue frame %d ran after Stop", frame)
+		}
+	}
+
+	realTimeClock.Start()
+	realTimeClock.SetSpeed(0)
+	realTimeClock.Stop()
+	for frame := 0; frame < 100; frame++ {
+		if realTimeClock.WaitFrame() {
+			t.Fatalf("uncapped frame %d ran after Stop", frame)
+		}
+	}
+}
+
+func TestNewClock(t *testing.T) {
+	for _, name := range ClockNames {
+		if clock, err := NewClock(name); err != nil || clock == nil {
+			t.Errorf("NewClock(%q) failed: %v", name, err)
+		}
+	}
+	clock, _ := NewClock("manual")
+	if _, ok := clock.(*ManualClock); !ok {
+		t.Error("the manual clock is not a ManualClock")
+	}
+	if _, err := NewClock("sundial"); err == nil {
+		t.Error("an unknown clock was created")
+	}
+}
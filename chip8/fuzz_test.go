@@ -0,0 +1,86 @@
+package chip8
+
+import (
+	"os"
+	"testing"
+)
+
+// FuzzDecode checks that every opcode decodes, on every platform, to an instruction that describes itself, and
+// that the CHIP-8 instruction runs on a fresh core.
+func FuzzDecode(f *testing.F) {
+	for _, opcode := range []uint16{0x00E0, 0x00EE, 0x1FFF, 0x2FFF, 0x8006, 0xBFFF, 0xD01F, 0xEF9E, 0xF00A, 0xF033, 0xFF55, 0xFF65} {
+		f.Add(opcode)
+	}
+	decoder := NewOpcodeDecoder()
+	f.Fuzz(func(t *testing.T, opcode uint16) {
+		for name, platform := range Platforms {
+			instruction := platform.Decoder.Decode(opcode)
+			if instruction == nil {
+				t.Fatalf("opcode %04X decodes to nil on %s", opcode, name)
+			}
+			if instruction.Opcode() != opcode {
+				t.Fatalf("opcode %04X decodes to an instruction of opcode %04X on %s", opcode, instruction.Opcode(), name)
+			}
+			if instruction.String() == "" {
+				t.Fatalf("opcode %04X has no mnemonic on %s", opcode, name)
+			}
+		}
+		instruction := decoder.Decode(opcode)
+		core := NewChip8Core()
+		instruction.Execute(core)
+		checkCoreInvariants(t, core)
+	})
+}
+
+// FuzzExecute runs programs with key presses and quirks and checks that the core stays consistent. Every tenth
+// instruction the timers tick and the next byte of keys presses (high bit clear) or releases (high bit set) a key.
+func FuzzExecute(f *testing.F) {
+	f.Add([]byte{0x22, 0x00}, []byte{}, byte(0))                                                 // calls itself until the stack is full
+	f.Add([]byte{0x00, 0xEE}, []byte{}, byte(0))                                                 // returns with an empty stack
+	f.Add([]byte{0xAF, 0xFF, 0xFF, 0x65, 0xD0, 0x1F, 0xFF, 0x33, 0xFF, 0x55}, []byte{}, byte(0)) // reads, draws and writes past the end of memory
+	f.Add([]byte{0x60, 0xFF, 0xE0, 0x9E, 0xBF, 0xFF}, []byte{0x0F}, byte(0xFF))                  // checks key FF and jumps past the end of memory
+	f.Add([]byte{0x12, 0x00}, []byte{0x05, 0x85}, byte(0x3F))
+	if pong, err := os.ReadFile("../roms/PONG"); err == nil {
+		f.Add(pong, []byte{0x01, 0x81, 0x04, 0x84}, byte(0))
+	}
+	decoder := NewOpcodeDecoder()
+	f.Fuzz(func(t *testing.T, rom []byte, keys []byte, quirks byte) {
+		core := NewChip8Core()
+		core.Quirks = Quirks{
+			Shift:     quirks&0x01 != 0,
+			LoadStore: quirks&0x02 != 0,
+			Jump:      quirks&0x04 != 0,
+			Logic:     quirks&0x08 != 0,
+			Clip:      quirks&0x10 != 0,
+			VBlank:    quirks&0x20 != 0,
+		}
+		rom = rom[:min(len(rom), len(core.Memory)-int(core.PC))]
+		if err := core.LoadROM(rom); err != nil {
+			t.Fatal(err)
+		}
+		for step := 0; step < 1000; step++ {
+			if step%10 == 0 {
+				core.UpdateTimers()
+				if len(keys) > 0 {
+					key := keys[step/10%len(keys)]
+					core.SetKey(key, key&0x80 == 0)
+				}
+			}
+			decoder.Decode(core.FetchOpcode()).Execute(core)
+			checkCoreInvariants(t, core)
+		}
+	})
+}
+
+func checkCoreInvariants(t *testing.T, core *Chip8Core) {
+	t.Helper()
+	if int(core.PC) >= len(core.Memory) {
+		t.Fatalf("PC %04X is outside of the %d bytes of memory", core.PC, len(core.Memory))
+	}
+	if int(core.SP) > len(core.Stack) {
+		t.Fatalf("SP is %d, the stack has %d entries", core.SP, len(core.Stack))
+	}
+	if core.ScreenWidth() != 64 || core.ScreenHeight() != 32 {
+		t.Fatalf("screen is %dx%d", core.ScreenWidth(), core.ScreenHeight())
+	}
+}
@@ -0,0 +1,119 @@
+package chip8
+
+import "slices"
+
+// IdleState tells whether the program spins in a loop changing nothing, and what could get it out. A frame is
+// idle when it leaves the registers, the stack and memory as it found them and executed nothing that changes
+// the screen, the timers or depends on random numbers: the next frame can only differ by the keys or the
+// delay timer it reads.
+type IdleState int
+
+const (
+	NotIdle             IdleState = iota // NotIdle is a program doing work.
+	IdleWaitingForKey                    // IdleWaitingForKey is a program waiting in FX0A or polling the keys with SKP and SKNP.
+	IdleWaitingForTimer                  // IdleWaitingForTimer is a program waiting for the delay timer to run out.
+	IdleForever                          // IdleForever is a program caught in an endless loop, such as a 1NNN jump to itself.
+)
+
+func (state IdleState) String() string {
+	switch state {
+	case IdleWaitingForKey:
+		return "waiting for a key"
+	case IdleWaitingForTimer:
+		return "waiting for the delay timer"
+	case IdleForever:
+		return "endless loop"
+	}
+	return ""
+}
+
+// idleMemoryLimit is the largest memory idle frames are detected with: comparing the 32 MiB of MegaChip-8 every
+// frame would cost more than running the frame.
+const idleMemoryLimit = 0x10000
+
+// idleSnapshot is the state of the core a frame depends on, the timers aside.
+type idleSnapshot struct {
+	registers [16]byte
+	index     uint32
+	pc        uint16
+	stack     [16]uint16
+	sp        uint16
+	keys      [16]bool
+	keys2     [16]bool // keys2 is the second keypad of CHIP-8X.
+	memory    []byte
+}
+
+func (snapshot *idleSnapshot) capture(core *Chip8Core) {
+	snapshot.registers = core.V
+	snapshot.index = core.I
+	snapshot.pc = core.PC
+	snapshot.stack = core.Stack
+	snapshot.sp = core.SP
+	snapshot.keys = core.Keys
+	snapshot.keys2 = [16]bool{}
+	if core.Chip8X != nil {
+		snapshot.keys2 = core.Chip8X.SecondKeys
+	}
+	snapshot.memory = append(snapshot.memory[:0], core.Memory...)
+}
+
+func (snapshot *idleSnapshot) equal(other *idleSnapshot) bool {
+	return snapshot.equalExcept(other, 0)
+}
+
+// equalExcept compares the snapshots but for the registers set in a mask, bit X for VX.
+func (snapshot *idleSnapshot) equalExcept(other *idleSnapshot, registerMask uint16) bool {
+	for index := range snapshot.registers {
+		if registerMask&(1<<index) == 0 && snapshot.registers[index] != other.registers[index] {
+			return false
+		}
+	}
+	return snapshot.index == other.index && snapshot.pc == other.pc && snapshot.stack == other.stack &&
+		snapshot.sp == other.sp && snapshot.keys == other.keys && snapshot.keys2 == other.keys2 && slices.Equal(snapshot.memory, other.memory)
+}
+
+// idleWatch records what the instructions of a frame depend on or change besides the registers and memory.
+type idleWatch struct {
+	busy           bool   // busy is set by an instruction changing the screen or the timers, or reading random numbers.
+	keys           bool   // keys is set by an instruction reading the keys.
+	timer          bool   // timer is set by FX07 reading the delay timer.
+	timerRegisters uint16 // timerRegisters has bit X set when FX07 loaded the delay timer into VX.
+}
+
+func (watch *idleWatch) observe(instruction Instruction) {
+	switch instruction := instruction.(type) {
+	case *SkipIfKeyPressed, *SkipIfKeyNotPressed, *WaitForKeyPress, *SkipIfSecondKeyPressed, *SkipIfSecondKeyNotPressed:
+		watch.keys = true
+	case *SetVxDelayTimer:
+		watch.timer = true
+		watch.timerRegisters |= 1 << instruction.x()
+	case *JumpToAddress, *JumpToAddressPlusV0, *CallSubroutine, *ReturnFromSubroutine, *SkipIfVxEqual,
+		*SkipIfVxNotEqual, *SkipIfVxVyEqual, *SkipIfVxVyNotEqual, *SetVx, *AddToVx, *SetVxVy, *SetVxOrVy,
+		*SetVxAndVy, *SetVxXorVy, *AddVyToVx, *SubtractVyFromVx, *ShiftVxRight, *SetVxVyMinusVx, *ShiftVxLeft,
+		*SetI, *SetIPlusVx, *SetISprite, *StoreBCD, *Storeregisters, *Fillregisters, *AddVyToVxNibbles,
+		*UnknownInstruction:
+		// Only the registers and memory, compared between frames.
+	default:
+		watch.busy = true
+	}
+}
+
+// classify tells how idle a frame was from the state before and after it, and the delay timer it started with.
+// A program waiting for the delay timer copies it into registers, which are left out of the comparison; as
+// frames waiting for the timer are still executed, the approximation only affects what Idle reports.
+func (watch *idleWatch) classify(before *idleSnapshot, after *idleSnapshot, delayTimer byte) IdleState {
+	switch {
+	case watch.busy:
+		return NotIdle
+	case watch.timer && delayTimer > 0:
+		if before.equalExcept(after, watch.timerRegisters) {
+			return IdleWaitingForTimer
+		}
+		return NotIdle
+	case !before.equal(after):
+		return NotIdle
+	case watch.keys:
+		return IdleWaitingForKey
+	}
+	return IdleForever
+}
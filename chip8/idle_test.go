@@ -0,0 +1,89 @@
+package chip8
+
+import "testing"
+
+func TestIdle(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Program    []byte
+		DelayTimer byte
+		Expected   IdleState
+	}{
+		{"1NNN to itself", []byte{0x12, 0x00}, 0, IdleForever},
+		{"FX0A", []byte{0xF0, 0x0A, 0x12, 0x00}, 0, IdleWaitingForKey},
+		{"EXA1 polling", []byte{0xE0, 0xA1, 0x12, 0x06, 0x12, 0x00, 0x12, 0x06}, 0, IdleWaitingForKey},
+		// v0 := delay if v0 != 0 then jump 0200
+		{"FX07 3X00 delay loop", []byte{0xF0, 0x07, 0x30, 0x00, 0x12, 0x00, 0x12, 0x06}, 30, IdleWaitingForTimer},
+		{"FX07 with the timer out", []byte{0xF0, 0x07, 0x30, 0x00, 0x12, 0x00, 0x12, 0x06}, 0, NotIdle},
+		{"busy loop", []byte{0x70, 0x01, 0x12, 0x00}, 0, NotIdle},
+		{"drawing loop", []byte{0xD0, 0x01, 0x12, 0x00}, 0, NotIdle},
+	}
+	for _, idleCase := range cases {
+		machine := loadPlatformProgram(t, "chip8", idleCase.Program)
+		machine.Core.DelayTimer = idleCase.DelayTimer
+		// Idle frames end where they started: the frames run whole turns of the loops.
+		machine.CyclesPerFrame = 12
+		machine.RunFrame()
+		if machine.Idle() != idleCase.Expected {
+			t.Errorf("%s is %q, want %q", idleCase.Name, machine.Idle(), idleCase.Expected)
+		}
+	}
+}
+
+// TestIdleDelayLoopEnds checks that a program leaving its delay loop is caught in the endless loop after it.
+func TestIdleDelayLoopEnds(t *testing.T) {
+	machine := loadPlatformProgram(t, "chip8", []byte{0xF0, 0x07, 0x30, 0x00, 0x12, 0x00, 0x12, 0x06})
+	machine.Core.DelayTimer = 3
+	for frame := 0; frame < 5; frame++ {
+		machine.RunFrame()
+	}
+	if machine.Idle() != IdleForever || machine.Core.PC != 0x206 {
+		t.Errorf("the program is %q at %04X, want an endless loop at 0206", machine.Idle(), machine.Core.PC)
+	}
+}
+
+func TestHaltOnLoop(t *testing.T) {
+	machine := loadPlatformProgram(t, "chip8", []byte{0x12, 0x00})
+	machine.HaltOnLoop = true
+	machine.RunFrame()
+	if !machine.Halted() || machine.HaltReason() != "endless loop at 0200" {
+		t.Errorf("halted %t because of %q, want halted because of an endless loop at 0200", machine.Halted(), machine.HaltReason())
+	}
+
+	// The sound plays out first.
+	machine = loadPlatformProgram(t, "chip8", []byte{0x12, 0x00})
+	machine.HaltOnLoop = true
+	machine.Core.SoundTimer = 2
+	machine.RunFrame()
+	if machine.Halted() {
+		t.Error("the machine halted while the sound played")
+	}
+	machine.RunFrame()
+	machine.RunFrame()
+	if !machine.Halted() {
+		t.Error("the machine did not halt once the sound stopped")
+	}
+}
+
+// TestIdleFrameSkipped checks that the frames repeating an idle frame are not executed, and that the next key
+// brings the program back.
+func TestIdleFrameSkipped(t *testing.T) {
+	machine := loadPlatformProgram(t, "chip8", []byte{0xF3, 0x0A, 0x12, 0x02})
+	machine.RunFrame()
+	if machine.Idle() != IdleWaitingForKey {
+		t.Fatalf("the program is %q, want waiting for a key", machine.Idle())
+	}
+	machine.traceCount = 0
+	machine.RunFrame()
+	if machine.traceCount != 0 || machine.Idle() != IdleWaitingForKey {
+		t.Errorf("the repeated frame executed %d instructions and left the program %q, want none and waiting for a key",
+			machine.traceCount, machine.Idle())
+	}
+
+	machine.Core.SetKey(7, true)
+	machine.RunFrame()
+	if machine.traceCount == 0 || machine.Core.V[3] != 7 || machine.Core.PC != 0x202 {
+		t.Errorf("after the key the frame executed %d instructions, leaving V3 at %d and PC at %04X, want some, 7 and 0202",
+			machine.traceCount, machine.Core.V[3], machine.Core.PC)
+	}
+}
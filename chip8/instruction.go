@@ -0,0 +1,1167 @@
+package chip8
+
+import (
+	"fmt"
+)
+
+// Instruction is a decoded opcode. String disassembles it with the usual CHIP-8 mnemonics and VIPCycles gives
+// what it would cost on the COSMAC VIP, before it executes.
+type Instruction interface {
+	Execute(core *Chip8Core)
+	Opcode() uint16
+	String() string
+	VIPCycles(core *Chip8Core) int
+}
+
+type GenericInstruction struct {
+	opcode uint16
+}
+
+func (instruction *GenericInstruction) Opcode() uint16 {
+	return instruction.opcode
+}
+
+// The operand fields of the opcode, named after the NNN, NN, N, X and Y of the opcode tables.
+func (instruction *GenericInstruction) address() uint16 { return instruction.opcode & 0x0FFF }
+func (instruction *GenericInstruction) value() uint8    { return uint8(instruction.opcode & 0x00FF) }
+func (instruction *GenericInstruction) nibble() uint8   { return uint8(instruction.opcode & 0x000F) }
+func (instruction *GenericInstruction) x() uint8        { return uint8((instruction.opcode & 0x0F00) >> 8) }
+func (instruction *GenericInstruction) y() uint8        { return uint8((instruction.opcode & 0x00F0) >> 4) }
+
+type ClearScreen struct {
+	GenericInstruction
+}
+
+func (instruction *ClearScreen) Execute(core *Chip8Core) {
+	core.ClearScreen()
+	core.IncrementPC(2)
+}
+
+func (instruction *ClearScreen) String() string {
+	return "CLS"
+}
+
+type ReturnFromSubroutine struct {
+	GenericInstruction
+}
+
+func (instruction *ReturnFromSubroutine) Execute(core *Chip8Core) {
+	if core.GetSP() == 0 {
+		return
+	}
+	core.SetPC(core.PopStack())
+	core.IncrementPC(2)
+}
+
+func (instruction *ReturnFromSubroutine) String() string {
+	return "RET"
+}
+
+type JumpToAddress struct {
+	GenericInstruction
+}
+
+func (instruction *JumpToAddress) Execute(core *Chip8Core) {
+	address := instruction.opcode & 0x0FFF
+	core.SetPC(address)
+}
+
+func (instruction *JumpToAddress) String() string {
+	return fmt.Sprintf("JP #%03X", instruction.address())
+}
+
+type CallSubroutine struct {
+	GenericInstruction
+}
+
+func (instruction *CallSubroutine) Execute(core *Chip8Core) {
+	if int(core.GetSP()) == len(core.Stack) {
+		return
+	}
+	address := instruction.opcode & 0x0FFF
+	core.PushStack(core.PC)
+	core.SetPC(address)
+}
+
+func (instruction *CallSubroutine) String() string {
+	return fmt.Sprintf("CALL #%03X", instruction.address())
+}
+
+type SkipIfVxEqual struct {
+	GenericInstruction
+}
+
+func (instruction *SkipIfVxEqual) Execute(core *Chip8Core) {
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	value := uint8(instruction.opcode & 0x00FF)
+	registerValue := core.GetRegister(registerIndex)
+	if registerValue == value {
+		core.IncrementPC(4)
+	} else {
+		core.IncrementPC(2)
+	}
+}
+
+func (instruction *SkipIfVxEqual) String() string {
+	return fmt.Sprintf("SE V%X, #%02X", instruction.x(), instruction.value())
+}
+
+type SkipIfVxNotEqual struct {
+	GenericInstruction
+}
+
+func (instruction *SkipIfVxNotEqual) Execute(core *Chip8Core) {
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	value := uint8(instruction.opcode & 0x00FF)
+	registerValue := core.GetRegister(registerIndex)
+	if registerValue != value {
+		core.IncrementPC(4)
+	} else {
+		core.IncrementPC(2)
+	}
+}
+
+func (instruction *SkipIfVxNotEqual) String() string {
+	return fmt.Sprintf("SNE V%X, #%02X", instruction.x(), instruction.value())
+}
+
+type SkipIfVxVyEqual struct {
+	GenericInstruction
+}
+
+func (instruction *SkipIfVxVyEqual) Execute(core *Chip8Core) {
+	xRegisterIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	yRegisterIndex := uint8((instruction.opcode & 0x00F0) >> 4)
+	xRegisterValue := core.GetRegister(xRegisterIndex)
+	yRegisterValue := core.GetRegister(yRegisterIndex)
+	if xRegisterValue == yRegisterValue {
+		core.IncrementPC(4)
+	} else {
+		core.IncrementPC(2)
+	}
+}
+
+func (instruction *SkipIfVxVyEqual) String() string {
+	return fmt.Sprintf("SE V%X, V%X", instruction.x(), instruction.y())
+}
+
+type SetVx struct {
+	GenericInstruction
+}
+
+func (instruction *SetVx) Execute(core *Chip8Core) {
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	value := uint8(instruction.opcode & 0x00FF)
+	core.SetRegister(registerIndex, value)
+	core.IncrementPC(2)
+}
+
+func (instruction *SetVx) String() string {
+	return fmt.Sprintf("LD V%X, #%02X", instruction.x(), instruction.value())
+}
+
+type AddToVx struct {
+	GenericInstruction
+}
+
+func (instruction *AddToVx) Execute(core *Chip8Core) {
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	value := uint8(instruction.opcode & 0x00FF)
+	registerValue := core.GetRegister(registerIndex)
+	core.SetRegister(registerIndex, registerValue+value)
+	core.IncrementPC(2)
+}
+
+func (instruction *AddToVx) String() string {
+	return fmt.Sprintf("ADD V%X, #%02X", instruction.x(), instruction.value())
+}
+
+type SetVxVy struct {
+	GenericInstruction
+}
+
+func (instruction *SetVxVy) Execute(core *Chip8Core) {
+	xRegisterIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	yRegisterIndex := uint8((instruction.opcode & 0x00F0) >> 4)
+	yRegisterValue := core.GetRegister(yRegisterIndex)
+	core.SetRegister(xRegisterIndex, yRegisterValue)
+	core.IncrementPC(2)
+}
+
+func (instruction *SetVxVy) String() string {
+	return fmt.Sprintf("LD V%X, V%X", instruction.x(), instruction.y())
+}
+
+type SetVxOrVy struct {
+	GenericInstruction
+}
+
+func (instruction *SetVxOrVy) Execute(core *Chip8Core) {
+	xRegisterIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	yRegisterIndex := uint8((instruction.opcode & 0x00F0) >> 4)
+	xRegisterValue := core.GetRegister(xRegisterIndex)
+	yRegisterValue := core.GetRegister(yRegisterIndex)
+	core.SetRegister(xRegisterIndex, xRegisterValue|yRegisterValue)
+	if core.Quirks.Logic {
+		core.SetRegister(0xF, 0)
+	}
+	core.IncrementPC(2)
+}
+
+func (instruction *SetVxOrVy) String() string {
+	return fmt.Sprintf("OR V%X, V%X", instruction.x(), instruction.y())
+}
+
+type SetVxAndVy struct {
+	GenericInstruction
+}
+
+func (instruction *SetVxAndVy) Execute(core *Chip8Core) {
+	xRegisterIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	yRegisterIndex := uint8((instruction.opcode & 0x00F0) >> 4)
+	xRegisterValue := core.GetRegister(xRegisterIndex)
+	yRegisterValue := core.GetRegister(yRegisterIndex)
+	core.SetRegister(xRegisterIndex, xRegisterValue&yRegisterValue)
+	if core.Quirks.Logic {
+		core.SetRegister(0xF, 0)
+	}
+	core.IncrementPC(2)
+}
+
+func (instruction *SetVxAndVy) String() string {
+	return fmt.Sprintf("AND V%X, V%X", instruction.x(), instruction.y())
+}
+
+type SetVxXorVy struct {
+	GenericInstruction
+}
+
+func (instruction *SetVxXorVy) Execute(core *Chip8Core) {
+	xRegisterIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	yRegisterIndex := uint8((instruction.opcode & 0x00F0) >> 4)
+	xRegisterValue := core.GetRegister(xRegisterIndex)
+	yRegisterValue := core.GetRegister(yRegisterIndex)
+	core.SetRegister(xRegisterIndex, xRegisterValue^yRegisterValue)
+	if core.Quirks.Logic {
+		core.SetRegister(0xF, 0)
+	}
+	core.IncrementPC(2)
+}
+
+func (instruction *SetVxXorVy) String() string {
+	return fmt.Sprintf("XOR V%X, V%X", instruction.x(), instruction.y())
+}
+
+type AddVyToVx struct {
+	GenericInstruction
+}
+
+func (instruction *AddVyToVx) Execute(core *Chip8Core) {
+	xRegisterIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	yRegisterIndex := uint8((instruction.opcode & 0x00F0) >> 4)
+	xRegisterValue := core.GetRegister(xRegisterIndex)
+	yRegisterValue := core.GetRegister(yRegisterIndex)
+	result := xRegisterValue + yRegisterValue
+	if result > 0xFF {
+		core.SetRegister(0xF, 1)
+	} else {
+		core.SetRegister(0xF, 0)
+	}
+	core.SetRegister(xRegisterIndex, uint8(result&0xFF))
+	core.IncrementPC(2)
+}
+
+func (instruction *AddVyToVx) String() string {
+	return fmt.Sprintf("ADD V%X, V%X", instruction.x(), instruction.y())
+}
+
+type SubtractVyFromVx struct {
+	GenericInstruction
+}
+
+func (instruction *SubtractVyFromVx) Execute(core *Chip8Core) {
+	xRegisterIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	yRegisterIndex := uint8((instruction.opcode & 0x00F0) >> 4)
+	xRegisterValue := core.GetRegister(xRegisterIndex)
+	yRegisterValue := core.GetRegister(yRegisterIndex)
+	if xRegisterValue >= yRegisterValue {
+		core.SetRegister(0xF, 1)
+	} else {
+		core.SetRegister(0xF, 0)
+	}
+	core.SetRegister(xRegisterIndex, xRegisterValue-yRegisterValue)
+	core.IncrementPC(2)
+}
+
+func (instruction *SubtractVyFromVx) String() string {
+	return fmt.Sprintf("SUB V%X, V%X", instruction.x(), instruction.y())
+}
+
+type ShiftVxRight struct {
+	GenericInstruction
+}
+
+func (instruction *ShiftVxRight) Execute(core *Chip8Core) {
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	sourceIndex := uint8((instruction.opcode & 0x00F0) >> 4)
+	if core.Quirks.Shift {
+		sourceIndex = registerIndex
+	}
+	originalValue := core.GetRegister(sourceIndex)
+	core.SetRegister(registerIndex, originalValue>>1)
+	core.SetRegister(0xF, originalValue&0x01)
+	core.IncrementPC(2)
+}
+
+func (instruction *ShiftVxRight) String() string {
+	return fmt.Sprintf("SHR V%X, V%X", instruction.x(), instruction.y())
+}
+
+type SetVxVyMinusVx struct {
+	GenericInstruction
+}
+
+func (instruction *SetVxVyMinusVx) Execute(core *Chip8Core) {
+	xRegisterIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	yRegisterIndex := uint8((instruction.opcode & 0x00F0) >> 4)
+	xRegisterValue := core.GetRegister(xRegisterIndex)
+	yRegisterValue := core.GetRegister(yRegisterIndex)
+
+	result := yRegisterValue - xRegisterValue
+	if result >= 0 {
+		core.SetRegister(0xF, 1)
+	} else {
+		core.SetRegister(0xF, 0)
+	}
+	core.SetRegister(xRegisterIndex, uint8(result&0xFF))
+	core.IncrementPC(2)
+}
+
+func (instruction *SetVxVyMinusVx) String() string {
+	return fmt.Sprintf("SUBN V%X, V%X", instruction.x(), instruction.y())
+}
+
+type ShiftVxLeft struct {
+	GenericInstruction
+}
+
+func (instruction *ShiftVxLeft) Execute(core *Chip8Core) {
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	sourceIndex := uint8((instruction.opcode & 0x00F0) >> 4)
+	if core.Quirks.Shift {
+		sourceIndex = registerIndex
+	}
+	originalValue := core.GetRegister(sourceIndex)
+	core.SetRegister(registerIndex, originalValue<<1)
+	core.SetRegister(0xF, originalValue>>7)
+	core.IncrementPC(2)
+}
+
+func (instruction *ShiftVxLeft) String() string {
+	return fmt.Sprintf("SHL V%X, V%X", instruction.x(), instruction.y())
+}
+
+type SkipIfVxVyNotEqual struct {
+	GenericInstruction
+}
+
+func (instruction *SkipIfVxVyNotEqual) Execute(core *Chip8Core) {
+	xRegisterIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	yRegisterIndex := uint8((instruction.opcode & 0x00F0) >> 4)
+	xRegisterValue := core.GetRegister(xRegisterIndex)
+	yRegisterValue := core.GetRegister(yRegisterIndex)
+	if xRegisterValue != yRegisterValue {
+		core.IncrementPC(4)
+	} else {
+		core.IncrementPC(2)
+	}
+}
+
+func (instruction *SkipIfVxVyNotEqual) String() string {
+	return fmt.Sprintf("SNE V%X, V%X", instruction.x(), instruction.y())
+}
+
+type SetI struct {
+	GenericInstruction
+}
+
+func (instruction *SetI) Execute(core *Chip8Core) {
+	iRegisterValue := instruction.opcode & 0x0FFF
+	core.SetI(uint32(iRegisterValue))
+	core.IncrementPC(2)
+}
+
+func (instruction *SetI) String() string {
+	return fmt.Sprintf("LD I, #%03X", instruction.address())
+}
+
+type JumpToAddressPlusV0 struct {
+	GenericInstruction
+}
+
+func (instruction *JumpToAddressPlusV0) Execute(core *Chip8Core) {
+	address := instruction.opcode & 0x0FFF
+	registerIndex := uint8(0)
+	if core.Quirks.Jump {
+		registerIndex = uint8((instruction.opcode & 0x0F00) >> 8)
+	}
+	jumpAddress := address + uint16(core.GetRegister(registerIndex))
+	core.SetPC(jumpAddress)
+}
+
+func (instruction *JumpToAddressPlusV0) String() string {
+	return fmt.Sprintf("JP V0, #%03X", instruction.address())
+}
+
+type SetVxRandom struct {
+	GenericInstruction
+}
+
+func (instruction *SetVxRandom) Execute(core *Chip8Core) {
+	randomByte := core.RandomByte()
+	constant := uint8(instruction.opcode & 0x00FF)
+	randomValue := randomByte & constant
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	core.SetRegister(registerIndex, randomValue)
+	core.IncrementPC(2)
+}
+
+func (instruction *SetVxRandom) String() string {
+	return fmt.Sprintf("RND V%X, #%02X", instruction.x(), instruction.value())
+}
+
+type DrawSprite struct {
+	GenericInstruction
+}
+
+func (instruction *DrawSprite) Execute(core *Chip8Core) {
+	xRegisterIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	yRegisterIndex := uint8((instruction.opcode & 0x00F0) >> 4)
+	xRegisterValue := core.GetRegister(xRegisterIndex)
+	yRegisterValue := core.GetRegister(yRegisterIndex)
+	if core.MegaChipEnabled() {
+		collision := core.DrawMegaChipSprite(xRegisterValue, yRegisterValue, instruction.nibble())
+		core.SetRegister(0xF, 0)
+		if collision {
+			core.SetRegister(0xF, 1)
+		}
+		core.IncrementPC(2)
+		return
+	}
+	drawSprite(core, xRegisterValue, yRegisterValue, 8, int(instruction.nibble()))
+	core.IncrementPC(2)
+}
+
+// drawSprite draws the sprite at I, rows of width pixels, 8 or 16, each row taking width/8 bytes.
+func drawSprite(core *Chip8Core, xRegisterValue uint8, yRegisterValue uint8, width int, rows int) {
+	iRegisterValue := core.GetI()
+	var spriteData byte
+	core.SetRegister(0xF, 0)
+	for row := 0; row < rows; row++ {
+		for column := 0; column < width; column++ {
+			if column%8 == 0 {
+				spriteData = core.ReadMemory(iRegisterValue + uint32((row*width+column)/8))
+			}
+			pixel := spriteData & (0x80 >> (column % 8))
+			if pixel != 0 {
+				// The origin always wraps around; the rest of the sprite wraps too unless the clip quirk is set.
+				originX := int(xRegisterValue) % core.ScreenWidth()
+				originY := int(yRegisterValue) % core.ScreenHeight()
+				if core.Quirks.Clip && (originX+column >= core.ScreenWidth() || originY+row >= core.ScreenHeight()) {
+					continue
+				}
+				positionX := uint8((originX + column) % core.ScreenWidth())
+				positionY := uint8((originY + row) % core.ScreenHeight())
+				if !core.GetPixel(positionX, positionY) {
+					core.SetRegister(0xF, 1)
+				}
+				pixel := (core.GetPixel(positionX, positionY) || true) && !(core.GetPixel(positionX, positionY) && true)
+				core.SetPixel(positionX, positionY, pixel)
+			}
+		}
+	}
+}
+
+func (instruction *DrawSprite) String() string {
+	return fmt.Sprintf("DRW V%X, V%X, %d", instruction.x(), instruction.y(), instruction.nibble())
+}
+
+type SkipIfKeyPressed struct {
+	GenericInstruction
+}
+
+func (instruction *SkipIfKeyPressed) Execute(core *Chip8Core) {
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	key := core.GetRegister(registerIndex)
+	if core.GetKey(key) {
+		core.IncrementPC(4)
+	} else {
+		core.IncrementPC(2)
+	}
+}
+
+func (instruction *SkipIfKeyPressed) String() string {
+	return fmt.Sprintf("SKP V%X", instruction.x())
+}
+
+type SkipIfKeyNotPressed struct {
+	GenericInstruction
+}
+
+func (instruction *SkipIfKeyNotPressed) Execute(core *Chip8Core) {
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	key := core.GetRegister(registerIndex)
+	if !core.GetKey(key) {
+		core.IncrementPC(4)
+	} else {
+		core.IncrementPC(2)
+	}
+}
+
+func (instruction *SkipIfKeyNotPressed) String() string {
+	return fmt.Sprintf("SKNP V%X", instruction.x())
+}
+
+type SetVxDelayTimer struct {
+	GenericInstruction
+}
+
+func (instruction *SetVxDelayTimer) Execute(core *Chip8Core) {
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	core.SetRegister(registerIndex, core.DelayTimer)
+	core.IncrementPC(2)
+}
+
+func (instruction *SetVxDelayTimer) String() string {
+	return fmt.Sprintf("LD V%X, DT", instruction.x())
+}
+
+type WaitForKeyPress struct {
+	GenericInstruction
+}
+
+func (instruction *WaitForKeyPress) Execute(core *Chip8Core) {
+	keyPressed := false
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	for keyIndex, isPressed := range core.Keys {
+		if isPressed {
+			core.SetRegister(registerIndex, byte(keyIndex))
+			keyPressed = true
+			core.Keys[keyIndex] = false
+			break
+		}
+	}
+	if !keyPressed {
+		return
+	} else {
+		core.IncrementPC(2)
+	}
+}
+
+func (instruction *WaitForKeyPress) String() string {
+	return fmt.Sprintf("LD V%X, K", instruction.x())
+}
+
+type SetDelayTimer struct {
+	GenericInstruction
+}
+
+func (instruction *SetDelayTimer) Execute(core *Chip8Core) {
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	registerValue := core.GetRegister(registerIndex)
+	core.DelayTimer = registerValue
+	core.IncrementPC(2)
+}
+
+func (instruction *SetDelayTimer) String() string {
+	return fmt.Sprintf("LD DT, V%X", instruction.x())
+}
+
+type SetSoundTimer struct {
+	GenericInstruction
+}
+
+func (instruction *SetSoundTimer) Execute(core *Chip8Core) {
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	registerValue := core.GetRegister(registerIndex)
+	core.SoundTimer = registerValue
+	core.IncrementPC(2)
+}
+
+func (instruction *SetSoundTimer) String() string {
+	return fmt.Sprintf("LD ST, V%X", instruction.x())
+}
+
+type SetIPlusVx struct {
+	GenericInstruction
+}
+
+func (instruction *SetIPlusVx) Execute(core *Chip8Core) {
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	registerValue := core.GetRegister(registerIndex)
+	core.SetI(core.GetI() + uint32(registerValue))
+	core.IncrementPC(2)
+}
+
+func (instruction *SetIPlusVx) String() string {
+	return fmt.Sprintf("ADD I, V%X", instruction.x())
+}
+
+type SetISprite struct {
+	GenericInstruction
+}
+
+func (instruction *SetISprite) Execute(core *Chip8Core) {
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	registerValue := core.GetRegister(registerIndex)
+	core.SetI(uint32(registerValue * 5))
+	core.IncrementPC(2)
+}
+
+func (instruction *SetISprite) String() string {
+	return fmt.Sprintf("LD F, V%X", instruction.x())
+}
+
+type StoreBCD struct {
+	GenericInstruction
+}
+
+func (instruction *StoreBCD) Execute(core *Chip8Core) {
+	registerIndex := uint8((instruction.opcode & 0x0F00) >> 8)
+	registerValue := core.GetRegister(registerIndex)
+	iRegisterValue := core.GetI()
+	core.WriteMemory(iRegisterValue, registerValue/100)
+	core.WriteMemory(iRegisterValue+1, (registerValue/10)%10)
+	core.WriteMemory(iRegisterValue+2, registerValue%10)
+	core.IncrementPC(2)
+}
+
+func (instruction *StoreBCD) String() string {
+	return fmt.Sprintf("LD B, V%X", instruction.x())
+}
+
+type Storeregisters struct {
+	GenericInstruction
+}
+
+func (instruction *Storeregisters) Execute(core *Chip8Core) {
+	registersNumber := uint32(instruction.opcode&0x0F00) >> 8
+	iRegisterValue := core.GetI()
+
+	for registerIndex := uint32(0); registerIndex <= registersNumber; registerIndex++ {
+		core.WriteMemory(iRegisterValue+registerIndex, core.GetRegister(uint8(registerIndex)))
+	}
+	if !core.Quirks.LoadStore {
+		core.SetI(iRegisterValue + registersNumber + 1)
+	}
+	core.IncrementPC(2)
+}
+
+func (instruction *Storeregisters) String() string {
+	return fmt.Sprintf("LD [I], V%X", instruction.x())
+}
+
+type Fillregisters struct {
+	GenericInstruction
+}
+
+func (instruction *Fillregisters) Execute(core *Chip8Core) {
+	registersNumber := uint32(instruction.opcode&0x0F00) >> 8
+	iRegisterValue := core.GetI()
+
+	for registerIndex := uint32(0); registerIndex <= registersNumber; registerIndex++ {
+		core.SetRegister(uint8(registerIndex), core.ReadMemory(iRegisterValue+registerIndex))
+	}
+	if !core.Quirks.LoadStore {
+		core.SetI(iRegisterValue + registersNumber + 1)
+	}
+	core.IncrementPC(2)
+}
+
+func (instruction *Fillregisters) String() string {
+	return fmt.Sprintf("LD V%X, [I]", instruction.x())
+}
+
+// CallMachineCode is 0NNN on the COSMAC VIP: it calls the RCA 1802 machine code subroutine at NNN.
+type CallMachineCode struct {
+	GenericInstruction
+}
+
+func (instruction *CallMachineCode) Execute(core *Chip8Core) {
+	core.RunMachineCode(instruction.address(), instruction.x(), instruction.y())
+}
+
+func (instruction *CallMachineCode) String() string {
+	return fmt.Sprintf("SYS #%03X", instruction.address())
+}
+
+// DisableMegaChip is 0010 on MegaChip-8: it goes back to the 64x32 CHIP-8 display.
+type DisableMegaChip struct {
+	GenericInstruction
+}
+
+func (instruction *DisableMegaChip) Execute(core *Chip8Core) {
+	core.SetMegaChipMode(false)
+	core.IncrementPC(2)
+}
+
+func (instruction *DisableMegaChip) String() string {
+	return "MEGAOFF"
+}
+
+// EnableMegaChip is 0011 on MegaChip-8: it switches to the 256x192 display in colors.
+type EnableMegaChip struct {
+	GenericInstruction
+}
+
+func (instruction *EnableMegaChip) Execute(core *Chip8Core) {
+	core.SetMegaChipMode(true)
+	core.IncrementPC(2)
+}
+
+func (instruction *EnableMegaChip) String() string {
+	return "MEGAON"
+}
+
+// SetLongI is 01NN NNNN on MegaChip-8: it loads I with the 24-bit address made of NN and the next 2 bytes.
+type SetLongI struct {
+	GenericInstruction
+}
+
+func (instruction *SetLongI) Execute(core *Chip8Core) {
+	low := uint32(core.ReadMemory(uint32(core.PC)+2))<<8 | uint32(core.ReadMemory(uint32(core.PC)+3))
+	core.SetI(uint32(instruction.value())<<16 | low)
+	core.IncrementPC(4)
+}
+
+func (instruction *SetLongI) String() string {
+	return fmt.Sprintf("LDHI I, #%02X....", instruction.value())
+}
+
+// LoadPalette is 02NN on MegaChip-8: it loads NN colors from I into the palette.
+type LoadPalette struct {
+	GenericInstruction
+}
+
+func (instruction *LoadPalette) Execute(core *Chip8Core) {
+	core.LoadMegaChipPalette(int(instruction.value()))
+	core.IncrementPC(2)
+}
+
+func (instruction *LoadPalette) String() string {
+	return fmt.Sprintf("LDPAL %d", instruction.value())
+}
+
+// SetSpriteWidth is 03NN on MegaChip-8: it sets the width of the sprites, 0 standing for 256.
+type SetSpriteWidth struct {
+	GenericInstruction
+}
+
+func (instruction *SetSpriteWidth) Execute(core *Chip8Core) {
+	core.megaChipState().SpriteWidth = megaChipSpriteSize(instruction.value())
+	core.IncrementPC(2)
+}
+
+func (instruction *SetSpriteWidth) String() string {
+	return fmt.Sprintf("SPRW %d", instruction.value())
+}
+
+// SetSpriteHeight is 04NN on MegaChip-8: it sets the height of the sprites, 0 standing for 256.
+type SetSpriteHeight struct {
+	GenericInstruction
+}
+
+func (instruction *SetSpriteHeight) Execute(core *Chip8Core) {
+	core.megaChipState().SpriteHeight = megaChipSpriteSize(instruction.value())
+	core.IncrementPC(2)
+}
+
+func (instruction *SetSpriteHeight) String() string {
+	return fmt.Sprintf("SPRH %d", instruction.value())
+}
+
+func megaChipSpriteSize(value uint8) int {
+	if value == 0 {
+		return 256
+	}
+	return int(value)
+}
+
+// SetScreenAlpha is 05NN on MegaChip-8: it sets the opacity of the whole display, to fade it in or out.
+type SetScreenAlpha struct {
+	GenericInstruction
+}
+
+func (instruction *SetScreenAlpha) Execute(core *Chip8Core) {
+	core.megaChipState().Alpha = instruction.value()
+	core.IncrementPC(2)
+}
+
+func (instruction *SetScreenAlpha) String() string {
+	return fmt.Sprintf("ALPHA %d", instruction.value())
+}
+
+// PlaySound is 060N on MegaChip-8: it plays the digitized sound at I, once when N is not 0, in a loop otherwise.
+type PlaySound struct {
+	GenericInstruction
+}
+
+func (instruction *PlaySound) Execute(core *Chip8Core) {
+	core.PlayMegaChipSound(instruction.nibble() == 0)
+	core.IncrementPC(2)
+}
+
+func (instruction *PlaySound) String() string {
+	return fmt.Sprintf("DIGISND %d", instruction.nibble())
+}
+
+// StopSound is 0700 on MegaChip-8: it stops the digitized sound.
+type StopSound struct {
+	GenericInstruction
+}
+
+func (instruction *StopSound) Execute(core *Chip8Core) {
+	core.megaChipState().Sound = nil
+	core.IncrementPC(2)
+}
+
+func (instruction *StopSound) String() string {
+	return "STOPSND"
+}
+
+// SetBlendMode is 080N on MegaChip-8: it selects how the sprites are blended with the display.
+type SetBlendMode struct {
+	GenericInstruction
+}
+
+func (instruction *SetBlendMode) Execute(core *Chip8Core) {
+	core.megaChipState().Blend = BlendMode(instruction.nibble())
+	core.IncrementPC(2)
+}
+
+func (instruction *SetBlendMode) String() string {
+	return fmt.Sprintf("BMODE %d", instruction.nibble())
+}
+
+// SetCollisionColor is 09NN on MegaChip-8: it sets the palette index sprites collide with.
+type SetCollisionColor struct {
+	GenericInstruction
+}
+
+func (instruction *SetCollisionColor) Execute(core *Chip8Core) {
+	core.megaChipState().CollisionColor = instruction.value()
+	core.IncrementPC(2)
+}
+
+func (instruction *SetCollisionColor) String() string {
+	return fmt.Sprintf("CCOL %d", instruction.value())
+}
+
+// CycleBackground is 02A0 on CHIP-8X: it moves the background to the next of its 4 colors.
+type CycleBackground struct {
+	GenericInstruction
+}
+
+func (instruction *CycleBackground) Execute(core *Chip8Core) {
+	core.chip8XState().CycleBackground()
+	core.IncrementPC(2)
+}
+
+func (instruction *CycleBackground) String() string {
+	return "BGCOL"
+}
+
+// AddVyToVxNibbles is 5XY1 on CHIP-8X: it adds VY to VX nibble by nibble, each keeping its 3 low bits.
+type AddVyToVxNibbles struct {
+	GenericInstruction
+}
+
+func (instruction *AddVyToVxNibbles) Execute(core *Chip8Core) {
+	xRegisterValue := core.GetRegister(instruction.x())
+	yRegisterValue := core.GetRegister(instruction.y())
+	core.SetRegister(instruction.x(), (xRegisterValue&0x77+yRegisterValue&0x77)&0x77)
+	core.IncrementPC(2)
+}
+
+func (instruction *AddVyToVxNibbles) String() string {
+	return fmt.Sprintf("ADDN V%X, V%X", instruction.x(), instruction.y())
+}
+
+// SetColorZones is BXY0 on CHIP-8X: it colors the zones VX and VX+1 select, horizontally and vertically, with VY.
+type SetColorZones struct {
+	GenericInstruction
+}
+
+func (instruction *SetColorZones) Execute(core *Chip8Core) {
+	horizontal := core.GetRegister(instruction.x())
+	vertical := core.GetRegister((instruction.x() + 1) & 0xF)
+	core.chip8XState().ColorZones(horizontal, vertical, core.GetRegister(instruction.y()))
+	core.IncrementPC(2)
+}
+
+func (instruction *SetColorZones) String() string {
+	return fmt.Sprintf("COL V%X, V%X", instruction.x(), instruction.y())
+}
+
+// SetColorRows is BXYN on CHIP-8X: it colors N rows from the row in VX+1 with VY, in the zone of the column in
+// VX.
+type SetColorRows struct {
+	GenericInstruction
+}
+
+func (instruction *SetColorRows) Execute(core *Chip8Core) {
+	positionX := core.GetRegister(instruction.x())
+	row := core.GetRegister((instruction.x() + 1) & 0xF)
+	core.chip8XState().ColorRows(positionX, row, instruction.nibble(), core.GetRegister(instruction.y()))
+	core.IncrementPC(2)
+}
+
+func (instruction *SetColorRows) String() string {
+	return fmt.Sprintf("COL V%X, V%X, %d", instruction.x(), instruction.y(), instruction.nibble())
+}
+
+// SkipIfSecondKeyPressed is EXF2 on CHIP-8X: it skips the next instruction when the key VX of the second keypad
+// is pressed.
+type SkipIfSecondKeyPressed struct {
+	GenericInstruction
+}
+
+func (instruction *SkipIfSecondKeyPressed) Execute(core *Chip8Core) {
+	if core.GetSecondKey(core.GetRegister(instruction.x())) {
+		core.IncrementPC(4)
+	} else {
+		core.IncrementPC(2)
+	}
+}
+
+func (instruction *SkipIfSecondKeyPressed) String() string {
+	return fmt.Sprintf("SKP2 V%X", instruction.x())
+}
+
+// SkipIfSecondKeyNotPressed is EXF5 on CHIP-8X: it skips the next instruction unless the key VX of the second
+// keypad is pressed.
+type SkipIfSecondKeyNotPressed struct {
+	GenericInstruction
+}
+
+func (instruction *SkipIfSecondKeyNotPressed) Execute(core *Chip8Core) {
+	if !core.GetSecondKey(core.GetRegister(instruction.x())) {
+		core.IncrementPC(4)
+	} else {
+		core.IncrementPC(2)
+	}
+}
+
+func (instruction *SkipIfSecondKeyNotPressed) String() string {
+	return fmt.Sprintf("SKNP2 V%X", instruction.x())
+}
+
+// OutputToPort is FXF8 on CHIP-8X: it sends VX to the I/O port, setting the pitch of the VP-595 sound board.
+type OutputToPort struct {
+	GenericInstruction
+}
+
+func (instruction *OutputToPort) Execute(core *Chip8Core) {
+	core.chip8XState().OutputPort = core.GetRegister(instruction.x())
+	core.IncrementPC(2)
+}
+
+func (instruction *OutputToPort) String() string {
+	return fmt.Sprintf("OUT V%X", instruction.x())
+}
+
+// InputFromPort is FXFB on CHIP-8X: it loads VX from the I/O port. The emulated port always has a byte ready,
+// InputPort, so the instruction does not wait.
+type InputFromPort struct {
+	GenericInstruction
+}
+
+func (instruction *InputFromPort) Execute(core *Chip8Core) {
+	core.SetRegister(instruction.x(), core.chip8XState().InputPort)
+	core.IncrementPC(2)
+}
+
+func (instruction *InputFromPort) String() string {
+	return fmt.Sprintf("INP V%X", instruction.x())
+}
+
+// ScrollDown is 00CN on SUPER-CHIP: it scrolls the display down by N pixels.
+type ScrollDown struct {
+	GenericInstruction
+}
+
+func (instruction *ScrollDown) Execute(core *Chip8Core) {
+	core.Scroll(0, int(instruction.nibble()))
+	core.IncrementPC(2)
+}
+
+func (instruction *ScrollDown) String() string {
+	return fmt.Sprintf("SCD %d", instruction.nibble())
+}
+
+// ScrollUp is 00BN on MegaChip-8: it scrolls the display up by N pixels.
+type ScrollUp struct {
+	GenericInstruction
+}
+
+func (instruction *ScrollUp) Execute(core *Chip8Core) {
+	core.Scroll(0, -int(instruction.nibble()))
+	core.IncrementPC(2)
+}
+
+func (instruction *ScrollUp) String() string {
+	return fmt.Sprintf("SCU %d", instruction.nibble())
+}
+
+// ScrollRight is 00FB on SUPER-CHIP: it scrolls the display right by 4 pixels.
+type ScrollRight struct {
+	GenericInstruction
+}
+
+func (instruction *ScrollRight) Execute(core *Chip8Core) {
+	core.Scroll(4, 0)
+	core.IncrementPC(2)
+}
+
+func (instruction *ScrollRight) String() string {
+	return "SCR"
+}
+
+// ScrollLeft is 00FC on SUPER-CHIP: it scrolls the display left by 4 pixels.
+type ScrollLeft struct {
+	GenericInstruction
+}
+
+func (instruction *ScrollLeft) Execute(core *Chip8Core) {
+	core.Scroll(-4, 0)
+	core.IncrementPC(2)
+}
+
+func (instruction *ScrollLeft) String() string {
+	return "SCL"
+}
+
+// ExitInterpreter is 00FD on SUPER-CHIP: it ends the program, which halts the machine. PC stays on it.
+type ExitInterpreter struct {
+	GenericInstruction
+}
+
+func (instruction *ExitInterpreter) Execute(core *Chip8Core) {
+	core.exited = true
+}
+
+func (instruction *ExitInterpreter) String() string {
+	return "EXIT"
+}
+
+// SetLowResolution is 00FE on SUPER-CHIP: it switches to the 64x32 display. The MegaChip display keeps its size.
+type SetLowResolution struct {
+	GenericInstruction
+}
+
+func (instruction *SetLowResolution) Execute(core *Chip8Core) {
+	if !core.MegaChipEnabled() {
+		core.SetResolution(64, 32)
+	}
+	core.IncrementPC(2)
+}
+
+func (instruction *SetLowResolution) String() string {
+	return "LOW"
+}
+
+// SetHighResolution is 00FF on SUPER-CHIP: it switches to the 128x64 display. The MegaChip display keeps its size.
+type SetHighResolution struct {
+	GenericInstruction
+}
+
+func (instruction *SetHighResolution) Execute(core *Chip8Core) {
+	if !core.MegaChipEnabled() {
+		core.SetResolution(SuperChipWidth, SuperChipHeight)
+	}
+	core.IncrementPC(2)
+}
+
+func (instruction *SetHighResolution) String() string {
+	return "HIGH"
+}
+
+// DrawLargeSprite is DXY0 on SUPER-CHIP: it draws the 16x16 sprite at I, 2 bytes per row. In MegaChip mode it
+// draws the sprite as DXYN does.
+type DrawLargeSprite struct {
+	GenericInstruction
+}
+
+func (instruction *DrawLargeSprite) Execute(core *Chip8Core) {
+	if core.MegaChipEnabled() {
+		(&DrawSprite{instruction.GenericInstruction}).Execute(core)
+		return
+	}
+	xRegisterValue := core.GetRegister(instruction.x())
+	yRegisterValue := core.GetRegister(instruction.y())
+	drawSprite(core, xRegisterValue, yRegisterValue, 16, 16)
+	core.IncrementPC(2)
+}
+
+func (instruction *DrawLargeSprite) String() string {
+	return fmt.Sprintf("DRW V%X, V%X, 0", instruction.x(), instruction.y())
+}
+
+// SetIBigSprite is FX30 on SUPER-CHIP: it points I at the 8x10 digit of the low nibble of VX.
+type SetIBigSprite struct {
+	GenericInstruction
+}
+
+func (instruction *SetIBigSprite) Execute(core *Chip8Core) {
+	digit := core.GetRegister(instruction.x()) & 0xF
+	core.SetI(bigFontAddress + uint32(digit)*10)
+	core.IncrementPC(2)
+}
+
+func (instruction *SetIBigSprite) String() string {
+	return fmt.Sprintf("LD HF, V%X", instruction.x())
+}
+
+// StoreFlags is FX75 on SUPER-CHIP: it saves V0 to VX in the user flags, X being at most 7.
+type StoreFlags struct {
+	GenericInstruction
+}
+
+func (instruction *StoreFlags) Execute(core *Chip8Core) {
+	for registerIndex := uint8(0); registerIndex <= min(instruction.x(), 7); registerIndex++ {
+		core.Flags[registerIndex] = core.GetRegister(registerIndex)
+	}
+	core.IncrementPC(2)
+}
+
+func (instruction *StoreFlags) String() string {
+	return fmt.Sprintf("LD R, V%X", instruction.x())
+}
+
+// LoadFlags is FX85 on SUPER-CHIP: it loads V0 to VX from the user flags, X being at most 7.
+type LoadFlags struct {
+	GenericInstruction
+}
+
+func (instruction *LoadFlags) Execute(core *Chip8Core) {
+	for registerIndex := uint8(0); registerIndex <= min(instruction.x(), 7); registerIndex++ {
+		core.SetRegister(registerIndex, core.Flags[registerIndex])
+	}
+	core.IncrementPC(2)
+}
+
+func (instruction *LoadFlags) String() string {
+	return fmt.Sprintf("LD V%X, R", instruction.x())
+}
+
+type UnknownInstruction struct {
+	GenericInstruction
+}
+
+func (instruction *UnknownInstruction) Execute(core *Chip8Core) {
+	core.IncrementPC(2)
+}
+
+func (instruction *UnknownInstruction) String() string {
+	return fmt.Sprintf("DW #%04X", instruction.opcode)
+}
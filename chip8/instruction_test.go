@@ -0,0 +1,59 @@
+package chip8
+
+import "testing"
+
+func TestTimerAndMemoryInstructions(t *testing.T) {
+	t.Run("FX07", func(t *testing.T) {
+		machine := loadPlatformProgram(t, "chip8", []byte{0xF3, 0x07})
+		machine.Core.DelayTimer = 42
+		machine.Step()
+		if machine.Core.V[3] != 42 || machine.Core.DelayTimer != 42 || machine.Core.PC != 0x202 {
+			t.Errorf("F307 left V3 at %d, DT at %d and PC at %04X, want 42, 42 and 0202",
+				machine.Core.V[3], machine.Core.DelayTimer, machine.Core.PC)
+		}
+	})
+
+	for _, addCase := range []struct {
+		Name     string
+		I        uint32
+		V2       byte
+		Expected uint32
+	}{
+		{"FX1E", 0x300, 0x10, 0x310},
+		{"FX1E by zero", 0x300, 0, 0x300},
+		// I keeps the carry out of 12 bits, as on the COSMAC VIP.
+		{"FX1E past 0FFF", 0xFFF, 2, 0x1001},
+	} {
+		t.Run(addCase.Name, func(t *testing.T) {
+			machine := loadPlatformProgram(t, "chip8", []byte{0xF2, 0x1E})
+			machine.Core.I = addCase.I
+			machine.Core.V[2] = addCase.V2
+			machine.Step()
+			if machine.Core.I != addCase.Expected || machine.Core.V[2] != addCase.V2 || machine.Core.PC != 0x202 {
+				t.Errorf("F21E with I %03X and V2 %02X left I at %03X, V2 at %02X and PC at %04X, want %03X, %02X and 0202",
+					addCase.I, addCase.V2, machine.Core.I, machine.Core.V[2], machine.Core.PC, addCase.Expected, addCase.V2)
+			}
+		})
+	}
+
+	for _, bcdCase := range []struct {
+		Value    byte
+		Expected [3]byte
+	}{
+		{0, [3]byte{0, 0, 0}},
+		{7, [3]byte{0, 0, 7}},
+		{42, [3]byte{0, 4, 2}},
+		{100, [3]byte{1, 0, 0}},
+		{255, [3]byte{2, 5, 5}},
+	} {
+		machine := loadPlatformProgram(t, "chip8", []byte{0xF5, 0x33})
+		machine.Core.I = 0x300
+		machine.Core.V[5] = bcdCase.Value
+		machine.Step()
+		digits := [3]byte(machine.Core.Memory[0x300:0x303])
+		if digits != bcdCase.Expected || machine.Core.I != 0x300 || machine.Core.PC != 0x202 {
+			t.Errorf("F533 of %d stored %v with I at %03X and PC at %04X, want %v with I at 300 and PC at 0202",
+				bcdCase.Value, digits, machine.Core.I, machine.Core.PC, bcdCase.Expected)
+		}
+	}
+}
@@ -0,0 +1,569 @@
+// Package chip8 emulates CHIP-8 and its variants: the core with its instructions and decoder, the platforms and
+// their quirks, the ROM formats and database, and the Machine running a program frame after frame with a Clock.
+// It knows nothing of windows, terminals or debuggers, which register callbacks on the Machine.
+package chip8
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultCyclesPerFrame is the number of instructions executed between two 60 Hz timer
+// updates when nothing else has been configured, which gives the usual 600 instructions per second.
+const DefaultCyclesPerFrame = 10
+
+// TraceLength is the number of executed instructions remembered by a Machine for the debuggers.
+const TraceLength = 64
+
+// MachineSettings are the settings a Machine runs every program with, before what is known of the program
+// overrides them.
+type MachineSettings struct {
+	CyclesPerFrame int  // CyclesPerFrame is the number of instructions executed per 60 Hz frame.
+	VIPTiming      bool // VIPTiming budgets the frames in COSMAC VIP machine cycles instead of running CyclesPerFrame instructions.
+	VIPMemory      bool // VIPMemory mirrors the registers, the stack and the screen in memory at the COSMAC VIP addresses.
+}
+
+// TraceEntry is an executed instruction together with the address it was fetched from.
+type TraceEntry struct {
+	Address     uint16
+	Instruction Instruction
+}
+
+// Machine is the embeddable face of the emulator.
+// It owns the Chip8Core together with the InstructionDecoder and the Clock driving it, runs the
+// fetch/decode/execute loop in 60 Hz frames and notifies the registered callbacks when a frame
+// is ready, when the sound turns on or off and when the machine halts.
+//
+// Debuggers and servers touching the machine from other goroutines hold its lock (Lock and Unlock)
+// while they do; RunFrame holds it for the whole frame, callbacks included.
+type Machine struct {
+	Core           *Chip8Core         // Core is the emulated CHIP-8 system.
+	Decoder        InstructionDecoder // Decoder turns the fetched opcodes into instructions, the one of the platform.
+	Clock          Clock              // Clock paces the frames when the machine is driven by Run.
+	Defaults       MachineSettings    // Defaults are the settings chosen by the user, which LoadROMImage starts again from for every program.
+	CyclesPerFrame int                // CyclesPerFrame is the number of instructions executed per 60 Hz frame, set by LoadROMImage.
+	ROMDatabase    *ROMDatabase       // ROMDatabase, when set, is consulted by LoadROM for the settings of the program.
+	ROMInfo        *ROMInfo           // ROMInfo holds the known settings of the loaded program, nil when it is unknown.
+	Platform       *Platform          // Platform is the variant the loaded program runs on.
+	Program        *OctoProgram       // Program holds the labels and lines of the loaded program when it was assembled from source.
+	Overlay        *Overlay           // Overlay holds what scripts draw over the screen.
+	HaltOnLoop     bool               // HaltOnLoop halts the machine once the program is caught in an endless loop, to end headless runs.
+	VIPTiming      bool               // VIPTiming budgets the frames in COSMAC VIP machine cycles, each instruction costing its VIPCycles, instead of running CyclesPerFrame instructions; set by LoadROMImage.
+	VIPMemory      bool               // VIPMemory mirrors the registers, the stack and the screen in memory at the addresses the COSMAC VIP interpreter kept them, for programs reaching them there; set by LoadROMImage.
+
+	rom        []byte
+	running    bool
+	paused     bool
+	advance    bool
+	speed      float64
+	seed       *int64
+	halted     bool
+	haltReason string
+	idle       IdleState
+	vipCycles  int
+	vipShadow  []byte
+	idleBefore idleSnapshot
+	idleAfter  idleSnapshot
+	soundOn    bool
+	sample     *DigitizedSound
+	trace      [TraceLength]TraceEntry
+	traceNext  int
+	traceCount int
+	mutex      sync.Mutex
+
+	breakpoints    map[uint16]bool
+	skipBreakpoint bool
+
+	frameCallbacks  []func(core *Chip8Core)
+	soundCallbacks  []func(on bool)
+	sampleCallbacks []func(sound *DigitizedSound)
+	haltCallbacks   []func(reason string)
+	breakCallbacks  []func(address uint16)
+	stepCallbacks   []func(address uint16)
+	writeCallbacks  []func(address uint32, value byte)
+	readCallbacks   []func(address uint32)
+	loadCallbacks   []func()
+}
+
+func NewMachine(clock Clock) *Machine {
+	return &Machine{
+		Core:           NewChip8Core(),
+		Decoder:        DefaultPlatform.Decoder,
+		Clock:          clock,
+		Defaults:       MachineSettings{CyclesPerFrame: DefaultCyclesPerFrame},
+		CyclesPerFrame: DefaultCyclesPerFrame,
+		Platform:       DefaultPlatform,
+		Overlay:        &Overlay{},
+		speed:          1,
+		breakpoints:    map[uint16]bool{},
+	}
+}
+
+// LoadROM resets the machine and loads a raw program, which is kept so Reset can reload it.
+func (machine *Machine) LoadROM(data []byte) error {
+	return machine.LoadROMImage(&ROMImage{Data: data})
+}
+
+// LoadROMImage resets the machine and loads the program for the platform picked from, in order, the
+// image itself, the ROM database and the default platform. The quirks come from the platform and the speed, timing and
+// memory layout from Defaults, overridden by the ROM database entry, overridden in turn by the settings found in the image; nothing
+// is kept from the previous program.
+// The program is rejected when it does not fit in the memory map of the platform.
+func (machine *Machine) LoadROMImage(romImage *ROMImage) error {
+	var romInfo ROMInfo
+	known := false
+	if machine.ROMDatabase != nil {
+		romInfo, known = machine.ROMDatabase.Lookup(romImage.Data)
+	}
+	if romImage.Info != nil {
+		romInfo.Merge(*romImage.Info)
+		known = true
+	}
+
+	platform := romImage.Platform
+	if platform == nil && romInfo.Platform != "" {
+		var err error
+		if platform, err = LookupPlatform(romInfo.Platform); err != nil {
+			return err
+		}
+	}
+	if platform == nil {
+		platform = DefaultPlatform
+	}
+	if err := platform.Validate(len(romImage.Data)); err != nil {
+		return err
+	}
+
+	machine.Platform = platform
+	machine.rom = append([]byte(nil), romImage.Data...)
+	machine.Program = romImage.Program
+	machine.ROMInfo = nil
+	if known {
+		machine.ROMInfo = &romInfo
+	}
+	machine.Decoder = platform.Decoder
+	machine.Core.Quirks = platform.Quirks
+	if romInfo.Quirks != nil {
+		machine.Core.Quirks = *romInfo.Quirks
+	}
+	machine.CyclesPerFrame = max(machine.Defaults.CyclesPerFrame, 1)
+	if romInfo.IPS > 0 {
+		machine.CyclesPerFrame = max(romInfo.IPS/60, 1)
+	}
+	machine.VIPTiming = machine.Defaults.VIPTiming || romInfo.Timing == "vip"
+	machine.VIPMemory = machine.Defaults.VIPMemory || romInfo.Memory == "vip"
+	machine.Reset()
+	for _, callback := range machine.loadCallbacks {
+		callback()
+	}
+	return nil
+}
+
+// ROM returns the loaded program.
+func (machine *Machine) ROM() []byte {
+	return machine.rom
+}
+
+// Reset puts the machine back in its power-on state and reloads the current ROM.
+// The platform and the quirks selected for the program are kept.
+func (machine *Machine) Reset() {
+	quirks := machine.Core.Quirks
+	*machine.Core = *NewChip8Core()
+	machine.Core.Quirks = quirks
+	machine.Core.SetMemorySize(machine.Platform.MemorySize)
+	machine.Core.SetPC(machine.Platform.LoadAddress)
+	// The program was validated against the platform when it was loaded.
+	_ = machine.Core.LoadROM(machine.rom)
+	if machine.Platform.EntryPoint != 0 {
+		machine.Core.SetPC(machine.Platform.EntryPoint)
+	}
+	machine.Core.SetResolution(machine.Platform.Resolution())
+	if machine.Platform.Chip8X {
+		machine.Core.Chip8X = NewChip8X()
+	}
+	if machine.seed != nil {
+		machine.Core.Seed(*machine.seed)
+	}
+	machine.installHooks()
+	machine.halted = false
+	machine.haltReason = ""
+	machine.idle = NotIdle
+	machine.vipCycles = 0
+	machine.vipShadow = nil
+	machine.traceCount = 0
+	machine.setSound(false)
+	machine.setDigitizedSound(nil)
+}
+
+// Step executes a single instruction and returns it, or nil when the machine is halted.
+func (machine *Machine) Step() Instruction {
+	if machine.halted {
+		return nil
+	}
+	for _, callback := range machine.stepCallbacks {
+		callback(machine.Core.PC)
+	}
+	opcode := machine.Core.FetchOpcode()
+	instruction := machine.Decoder.Decode(opcode)
+	machine.trace[machine.traceNext] = TraceEntry{Address: machine.Core.PC, Instruction: instruction}
+	machine.traceNext = (machine.traceNext + 1) % TraceLength
+	machine.traceCount = min(machine.traceCount+1, TraceLength)
+	if machine.VIPTiming {
+		machine.vipCycles -= instruction.VIPCycles(machine.Core)
+	}
+	// The memory copies are synchronized on both sides of the instruction, so what the debuggers and scripts
+	// change between two steps is seen by the next one.
+	if machine.VIPMemory {
+		machine.syncVIPMemory()
+	}
+	instruction.Execute(machine.Core)
+	if machine.VIPMemory {
+		machine.syncVIPMemory()
+	}
+	if machine.VIPTiming {
+		machine.vipCycles -= machine.Core.takeMachineCodeCycles()
+	}
+	if machine.Core.exited {
+		machine.Core.exited = false
+		machine.Halt("program exited")
+	}
+	return instruction
+}
+
+func (machine *Machine) syncVIPMemory() {
+	layout := NewVIPLayout(len(machine.Core.Memory))
+	machine.vipShadow = machine.Core.syncVIPMemory(layout, machine.vipShadow)
+}
+
+// Trace returns the last instructions executed since the last Reset, oldest first, at most TraceLength of them.
+func (machine *Machine) Trace() []TraceEntry {
+	entries := make([]TraceEntry, 0, machine.traceCount)
+	for index := machine.traceCount; index > 0; index-- {
+		entries = append(entries, machine.trace[(machine.traceNext-index+TraceLength)%TraceLength])
+	}
+	return entries
+}
+
+// RunCycles executes up to count instructions without touching the timers.
+// It stops early when the machine halts.
+func (machine *Machine) RunCycles(count int) {
+	for cycle := 0; cycle < count && !machine.halted; cycle++ {
+		machine.Step()
+	}
+}
+
+// RunFrame executes one 60 Hz frame: CyclesPerFrame instructions, or the instructions fitting in a frame of the
+// COSMAC VIP with VIPTiming, followed by a timer update.
+// Nothing is executed while the machine is paused or halted, but the frame callbacks are
+// still notified so frontends keep presenting and polling their input.
+// Reaching a breakpoint pauses the machine before the instruction and notifies the break callbacks.
+func (machine *Machine) RunFrame() {
+	machine.Lock()
+	defer machine.Unlock()
+	if (!machine.paused || machine.advance) && !machine.halted {
+		tracksIdle := len(machine.Core.Memory) <= idleMemoryLimit
+		if tracksIdle {
+			machine.idleBefore.capture(machine.Core)
+		}
+		delayTimer := machine.Core.DelayTimer
+		// A frame starting in the state an idle frame ended in would only repeat it, so it is skipped, unless
+		// breakpoints or step callbacks need to see the instructions.
+		repeated := tracksIdle && (machine.idle == IdleWaitingForKey || machine.idle == IdleForever) && machine.idleBefore.equal(&machine.idleAfter) &&
+			len(machine.breakpoints) == 0 && len(machine.stepCallbacks) == 0
+		if !repeated {
+			watch := idleWatch{}
+			complete := true
+			if machine.VIPTiming {
+				machine.vipCycles += VIPFrameCycles - vipDisplayCycles
+			}
+			for cycle := 0; machine.frameHasTime(cycle) && !machine.halted; cycle++ {
+				if machine.breakpoints[machine.Core.PC] && !machine.skipBreakpoint {
+					machine.Pause()
+					for _, callback := range machine.breakCallbacks {
+						callback(machine.Core.PC)
+					}
+					complete = false
+					break
+				}
+				machine.skipBreakpoint = false
+				instruction := machine.Step()
+				watch.observe(instruction)
+				if _, drawn := instruction.(*DrawSprite); drawn && machine.Core.Quirks.VBlank {
+					// The rest of the frame is spent waiting for the interrupt.
+					machine.vipCycles = min(machine.vipCycles, 0)
+					break
+				}
+			}
+			machine.idle = NotIdle
+			if complete && !machine.halted && tracksIdle {
+				machine.idleAfter.capture(machine.Core)
+				machine.idle = watch.classify(&machine.idleBefore, &machine.idleAfter, delayTimer)
+			}
+		}
+		// The sound still plays out before the machine halts.
+		if machine.idle == IdleForever && machine.HaltOnLoop && machine.Core.SoundTimer == 0 {
+			machine.Halt(fmt.Sprintf("endless loop at %04X", machine.Core.PC))
+		}
+		machine.Core.UpdateTimers()
+		machine.setSound(machine.Core.SoundTimer > 0 && !machine.paused)
+		machine.setDigitizedSound(machine.playingDigitizedSound())
+	}
+	machine.advance = false
+	for _, callback := range machine.frameCallbacks {
+		callback(machine.Core)
+	}
+}
+
+// frameHasTime tells whether the current frame, having run cycle instructions, can run another one. With
+// VIPTiming it can while machine cycles are left, an instruction overrunning the frame taking them from the next.
+func (machine *Machine) frameHasTime(cycle int) bool {
+	if machine.VIPTiming {
+		return machine.vipCycles > 0
+	}
+	return cycle < machine.CyclesPerFrame
+}
+
+// Run drives the machine with its Clock, one frame each time the clock lets one through, until Stop is
+// called, the machine halts or the clock is stopped.
+func (machine *Machine) Run() {
+	machine.Lock()
+	machine.running = true
+	machine.Unlock()
+	machine.Clock.Start()
+	defer machine.Clock.Stop()
+	for machine.Clock.WaitFrame() {
+		machine.RunFrame()
+		machine.Lock()
+		running := machine.running && !machine.halted
+		// Running uncapped, an idle program would only spin the host; it is paced at the normal speed instead.
+		spinning := machine.speed == 0 && (machine.idle == IdleWaitingForKey || machine.idle == IdleForever)
+		machine.Unlock()
+		if !running {
+			return
+		}
+		if spinning {
+			time.Sleep(FramePeriod)
+		}
+	}
+}
+
+// Stop makes Run return after the current frame, or at once when it is waiting for the clock. It is called with
+// the machine locked.
+func (machine *Machine) Stop() {
+	machine.running = false
+	if machine.Clock != nil {
+		machine.Clock.Stop()
+	}
+}
+
+func (machine *Machine) Pause() {
+	machine.paused = true
+	machine.setSound(false)
+	machine.setDigitizedSound(nil)
+}
+
+// Resume restarts a paused machine. A breakpoint at PC does not stop it again before the instruction runs.
+func (machine *Machine) Resume() {
+	machine.paused = false
+	machine.skipBreakpoint = true
+}
+
+func (machine *Machine) Paused() bool {
+	return machine.paused
+}
+
+// Seed makes the random numbers reproducible: the sequence starts over from the seed now and at every Reset.
+func (machine *Machine) Seed(seed int64) {
+	machine.seed = &seed
+	machine.Core.Seed(seed)
+}
+
+// AdvanceFrame pauses the machine and lets it run the next frame only, breakpoints included.
+func (machine *Machine) AdvanceFrame() {
+	machine.Pause()
+	machine.advance = true
+}
+
+// SetSpeed changes the pace of Run when its Clock is a SpeedClock, 0 meaning as fast as possible. The timers still
+// tick once per frame, so a program sees the same time at every speed.
+func (machine *Machine) SetSpeed(speed float64) {
+	machine.speed = speed
+	if speedClock, ok := machine.Clock.(SpeedClock); ok {
+		speedClock.SetSpeed(speed)
+	}
+}
+
+func (machine *Machine) Speed() float64 {
+	return machine.speed
+}
+
+// SpeedStatus describes the pace of the machine for titles and status lines: "paused", "x4", "x1/2",
+// "uncapped", or an empty string at normal speed.
+func (machine *Machine) SpeedStatus() string {
+	switch {
+	case machine.halted:
+		return "halted"
+	case machine.paused:
+		return "paused"
+	case machine.speed == 0:
+		return "uncapped"
+	case machine.speed == 1:
+		return ""
+	case machine.speed < 1:
+		return fmt.Sprintf("x1/%g", 1/machine.speed)
+	}
+	return fmt.Sprintf("x%g", machine.speed)
+}
+
+// Halt stops the execution for good, until the next Reset, and notifies the halt callbacks.
+func (machine *Machine) Halt(reason string) {
+	if machine.halted {
+		return
+	}
+	machine.halted = true
+	machine.haltReason = reason
+	machine.setSound(false)
+	machine.setDigitizedSound(nil)
+	for _, callback := range machine.haltCallbacks {
+		callback(reason)
+	}
+}
+
+func (machine *Machine) Halted() bool {
+	return machine.halted
+}
+
+func (machine *Machine) HaltReason() string {
+	return machine.haltReason
+}
+
+// Idle tells whether the last frame found the program spinning in a loop that changes nothing, and what it waits for.
+func (machine *Machine) Idle() IdleState {
+	return machine.idle
+}
+
+func (machine *Machine) Lock() {
+	machine.mutex.Lock()
+}
+
+func (machine *Machine) Unlock() {
+	machine.mutex.Unlock()
+}
+
+func (machine *Machine) SetBreakpoint(address uint16) {
+	machine.breakpoints[address] = true
+}
+
+func (machine *Machine) ClearBreakpoint(address uint16) {
+	delete(machine.breakpoints, address)
+}
+
+// Breakpoints returns the addresses of the breakpoints in increasing order.
+func (machine *Machine) Breakpoints() []uint16 {
+	var addresses []uint16
+	for address := range machine.breakpoints {
+		addresses = append(addresses, address)
+	}
+	slices.Sort(addresses)
+	return addresses
+}
+
+// OnFrame registers a callback notified at the end of every frame.
+func (machine *Machine) OnFrame(callback func(core *Chip8Core)) {
+	machine.frameCallbacks = append(machine.frameCallbacks, callback)
+}
+
+// OnSound registers a callback notified when the sound turns on or off.
+func (machine *Machine) OnSound(callback func(on bool)) {
+	machine.soundCallbacks = append(machine.soundCallbacks, callback)
+}
+
+// OnDigitizedSound registers a callback notified when a MegaChip program starts a digitized sound, with nil
+// when the sound stops.
+func (machine *Machine) OnDigitizedSound(callback func(sound *DigitizedSound)) {
+	machine.sampleCallbacks = append(machine.sampleCallbacks, callback)
+}
+
+// OnHalt registers a callback notified when the machine halts.
+func (machine *Machine) OnHalt(callback func(reason string)) {
+	machine.haltCallbacks = append(machine.haltCallbacks, callback)
+}
+
+// OnStep registers a callback notified with PC before every instruction is fetched; it may change the machine.
+func (machine *Machine) OnStep(callback func(address uint16)) {
+	machine.stepCallbacks = append(machine.stepCallbacks, callback)
+}
+
+// OnMemoryWrite registers a callback notified after the program writes a byte to Memory.
+func (machine *Machine) OnMemoryWrite(callback func(address uint32, value byte)) {
+	machine.writeCallbacks = append(machine.writeCallbacks, callback)
+	machine.installHooks()
+}
+
+// OnMemoryRead registers a callback notified before the program reads a byte of Memory, to draw a sprite or
+// to load registers; fetching instructions is left to OnStep.
+func (machine *Machine) OnMemoryRead(callback func(address uint32)) {
+	machine.readCallbacks = append(machine.readCallbacks, callback)
+	machine.installHooks()
+}
+
+// installHooks connects the core to the memory callbacks; cores without callbacks pay nothing for them.
+func (machine *Machine) installHooks() {
+	machine.Core.onWrite = nil
+	if len(machine.writeCallbacks) > 0 {
+		machine.Core.onWrite = func(address uint32, value byte) {
+			for _, callback := range machine.writeCallbacks {
+				callback(address, value)
+			}
+		}
+	}
+	machine.Core.onRead = nil
+	if len(machine.readCallbacks) > 0 {
+		machine.Core.onRead = func(address uint32) {
+			for _, callback := range machine.readCallbacks {
+				callback(address)
+			}
+		}
+	}
+}
+
+// OnLoad registers a callback notified after a program is loaded, by LoadROMImage or by LoadState
+// restoring a state of another program.
+func (machine *Machine) OnLoad(callback func()) {
+	machine.loadCallbacks = append(machine.loadCallbacks, callback)
+}
+
+// OnBreak registers a callback notified when the machine pauses at a breakpoint.
+func (machine *Machine) OnBreak(callback func(address uint16)) {
+	machine.breakCallbacks = append(machine.breakCallbacks, callback)
+}
+
+// playingDigitizedSound returns the digitized sound the program plays, nil when it plays none or is paused.
+func (machine *Machine) playingDigitizedSound() *DigitizedSound {
+	if machine.Core.MegaChip == nil || machine.paused {
+		return nil
+	}
+	return machine.Core.MegaChip.Sound
+}
+
+func (machine *Machine) setDigitizedSound(sound *DigitizedSound) {
+	if machine.sample == sound {
+		return
+	}
+	machine.sample = sound
+	for _, callback := range machine.sampleCallbacks {
+		callback(sound)
+	}
+}
+
+func (machine *Machine) setSound(on bool) {
+	if machine.soundOn == on {
+		return
+	}
+	machine.soundOn = on
+	for _, callback := range machine.soundCallbacks {
+		callback(on)
+	}
+}
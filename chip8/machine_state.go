@@ -0,0 +1,135 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// MachineState is a snapshot of a Machine: the core, the platform, speed, timing and memory layout it runs with, and the loaded
+// program so Reset keeps working after a restore. It is saved and loaded as JSON.
+type MachineState struct {
+	Platform       string     `json:"platform"`
+	CyclesPerFrame int        `json:"cyclesPerFrame"`
+	VIPTiming      bool       `json:"vipTiming,omitempty"`
+	VIPMemory      bool       `json:"vipMemory,omitempty"`
+	ROM            []byte     `json:"rom"`
+	Core           *Chip8Core `json:"core"`
+}
+
+// SaveState takes a snapshot of the machine; the snapshot shares nothing with it.
+func (machine *Machine) SaveState() *MachineState {
+	core := *machine.Core
+	core.onWrite = nil
+	core.onRead = nil
+	core.random = nil
+	core.vip = nil
+	core.Memory = append([]byte(nil), core.Memory...)
+	if core.MegaChip != nil {
+		core.MegaChip = core.MegaChip.clone()
+	}
+	if core.Chip8X != nil {
+		chip8X := *core.Chip8X
+		core.Chip8X = &chip8X
+	}
+	core.Screen = make([][]bool, len(machine.Core.Screen))
+	for row, pixels := range machine.Core.Screen {
+		core.Screen[row] = append([]bool(nil), pixels...)
+	}
+	return &MachineState{
+		Platform:       machine.Platform.Name,
+		CyclesPerFrame: machine.CyclesPerFrame,
+		VIPTiming:      machine.VIPTiming,
+		VIPMemory:      machine.VIPMemory,
+		ROM:            append([]byte(nil), machine.rom...),
+		Core:           &core,
+	}
+}
+
+// LoadState restores a snapshot taken by SaveState, after checking it describes a machine this emulator can run.
+// A halted machine runs again; breakpoints and callbacks are kept.
+func (machine *Machine) LoadState(state *MachineState) error {
+	platform, err := LookupPlatform(state.Platform)
+	if err != nil {
+		return err
+	}
+	core := state.Core
+	switch {
+	case core == nil:
+		return fmt.Errorf("state has no core")
+	case len(core.Memory) != platform.MemorySize:
+		return fmt.Errorf("state has %d bytes of memory, the %s platform has %d", len(core.Memory), platform.Name, platform.MemorySize)
+	case int(core.PC) >= len(core.Memory) || int(core.I) >= len(core.Memory):
+		return fmt.Errorf("state has PC or I outside of memory")
+	case int(core.SP) > len(core.Stack):
+		return fmt.Errorf("state has a stack pointer of %d", core.SP)
+	case len(core.Screen) == 0 || len(core.Screen) > 256 || len(core.Screen[0]) == 0 || len(core.Screen[0]) > 256:
+		return fmt.Errorf("state has an invalid screen")
+	case core.MegaChip != nil && !core.MegaChip.valid():
+		return fmt.Errorf("state has an invalid MegaChip display")
+	case core.MegaChip != nil && core.MegaChip.Enabled && (len(core.Screen) != MegaChipHeight || len(core.Screen[0]) != MegaChipWidth):
+		return fmt.Errorf("state has an invalid screen")
+	case core.Chip8X != nil && (core.Chip8X.Background < 0 || core.Chip8X.Background >= len(Chip8XBackgrounds)):
+		return fmt.Errorf("state has an invalid CHIP-8X background")
+	case state.CyclesPerFrame < 1:
+		return fmt.Errorf("state has %d cycles per frame", state.CyclesPerFrame)
+	}
+	for _, pixels := range core.Screen {
+		if len(pixels) != len(core.Screen[0]) {
+			return fmt.Errorf("state has an invalid screen")
+		}
+	}
+
+	restored := *state.Core
+	// The random sequence is not part of the state; the machine keeps its own.
+	restored.random = machine.Core.random
+	restored.Memory = append([]byte(nil), core.Memory...)
+	if core.MegaChip != nil {
+		restored.MegaChip = core.MegaChip.clone()
+	}
+	if core.Chip8X != nil {
+		chip8X := *core.Chip8X
+		restored.Chip8X = &chip8X
+	}
+	restored.Screen = make([][]bool, len(core.Screen))
+	for row, pixels := range core.Screen {
+		restored.Screen[row] = append([]bool(nil), pixels...)
+	}
+	*machine.Core = restored
+	machine.installHooks()
+	machine.Platform = platform
+	machine.Decoder = platform.Decoder
+	machine.vipShadow = nil
+	machine.CyclesPerFrame = state.CyclesPerFrame
+	machine.VIPTiming = state.VIPTiming
+	machine.VIPMemory = state.VIPMemory
+	machine.vipCycles = 0
+	loaded := !bytes.Equal(machine.rom, state.ROM)
+	machine.rom = append([]byte(nil), state.ROM...)
+	machine.halted = false
+	machine.haltReason = ""
+	machine.traceCount = 0
+	machine.setSound(machine.Core.SoundTimer > 0 && !machine.paused)
+	machine.setDigitizedSound(machine.playingDigitizedSound())
+	if loaded {
+		for _, callback := range machine.loadCallbacks {
+			callback()
+		}
+	}
+	return nil
+}
+
+// WriteState saves the state of the machine as JSON.
+func (machine *Machine) WriteState(writer io.Writer) error {
+	return json.NewEncoder(writer).Encode(machine.SaveState())
+}
+
+// ReadState loads a state saved by WriteState.
+func (machine *Machine) ReadState(reader io.Reader) error {
+	var state MachineState
+	if err := json.NewDecoder(reader).Decode(&state); err != nil {
+		return err
+	}
+	return machine.LoadState(&state)
+}
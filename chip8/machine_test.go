@@ -0,0 +1,99 @@
+package chip8
+
+import "testing"
+
+// TestLoadROMImageSettings checks that the settings of a program do not stay with the machine once another
+// program is loaded.
+func TestLoadROMImageSettings(t *testing.T) {
+	machine := NewMachine(NewManualClock())
+	machine.Defaults.CyclesPerFrame = 20
+	tuned := &ROMImage{Data: []byte{0x12, 0x00}, Info: &ROMInfo{IPS: 1800, Timing: "vip", Memory: "vip"}}
+	plain := &ROMImage{Data: []byte{0x12, 0x02}}
+
+	if err := machine.LoadROMImage(tuned); err != nil {
+		t.Fatal(err)
+	}
+	if machine.CyclesPerFrame != 30 || !machine.VIPTiming || !machine.VIPMemory {
+		t.Errorf("tuned program runs %d cycles per frame with VIPTiming %t and VIPMemory %t, want 30 with both",
+			machine.CyclesPerFrame, machine.VIPTiming, machine.VIPMemory)
+	}
+	if err := machine.LoadROMImage(plain); err != nil {
+		t.Fatal(err)
+	}
+	if machine.CyclesPerFrame != 20 || machine.VIPTiming || machine.VIPMemory {
+		t.Errorf("next program runs %d cycles per frame with VIPTiming %t and VIPMemory %t, want the default 20 with neither",
+			machine.CyclesPerFrame, machine.VIPTiming, machine.VIPMemory)
+	}
+}
+
+// TestSetSpeed checks that the speed reaches a clock whose pace can change, and only the pace: the timers still
+// tick once per frame.
+func TestSetSpeed(t *testing.T) {
+	clock := NewRealTimeClock()
+	machine := NewMachine(clock)
+	if err := machine.LoadROM([]byte{0x12, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	machine.SetSpeed(4)
+	if machine.Speed() != 4 || clock.speed != 4 {
+		t.Errorf("the machine runs at %g and its clock at %g, want 4", machine.Speed(), clock.speed)
+	}
+	machine.Core.DelayTimer = 10
+	machine.RunFrame()
+	if machine.Core.DelayTimer != 9 {
+		t.Errorf("a frame at x4 left the delay timer at %d, want 9", machine.Core.DelayTimer)
+	}
+	machine.SetSpeed(1)
+	if machine.Speed() != 1 || clock.speed != 1 {
+		t.Errorf("back to normal, the machine runs at %g and its clock at %g, want 1", machine.Speed(), clock.speed)
+	}
+}
+
+func TestAdvanceFrame(t *testing.T) {
+	// 0200: v0 += 1, 0202: jump 0200
+	machine := loadPlatformProgram(t, "chip8", []byte{0x70, 0x01, 0x12, 0x00})
+	machine.Core.DelayTimer = 10
+	machine.Pause()
+	machine.RunFrame()
+	if machine.Core.V[0] != 0 || machine.Core.DelayTimer != 10 {
+		t.Fatalf("a paused frame left V0 at %d and the delay timer at %d, want 0 and 10", machine.Core.V[0], machine.Core.DelayTimer)
+	}
+	machine.AdvanceFrame()
+	machine.RunFrame()
+	machine.RunFrame()
+	if machine.Core.V[0] != 5 || machine.Core.DelayTimer != 9 || !machine.Paused() {
+		t.Errorf("advancing a frame left V0 at %d, the delay timer at %d and paused %t, want 5, 9 and paused",
+			machine.Core.V[0], machine.Core.DelayTimer, machine.Paused())
+	}
+}
+
+func TestSpeedStatus(t *testing.T) {
+	cases := []struct {
+		Speed    float64
+		Paused   bool
+		Halted   bool
+		Expected string
+	}{
+		{1, false, false, ""},
+		{4, false, false, "x4"},
+		{0.5, false, false, "x1/2"},
+		{0.125, false, false, "x1/8"},
+		{0, false, false, "uncapped"},
+		{4, true, false, "paused"},
+		{4, true, true, "halted"},
+	}
+	for _, statusCase := range cases {
+		machine := NewMachine(NewManualClock())
+		machine.SetSpeed(statusCase.Speed)
+		if statusCase.Paused {
+			machine.Pause()
+		}
+		if statusCase.Halted {
+			machine.Halt("test")
+		}
+		if status := machine.SpeedStatus(); status != statusCase.Expected {
+			t.Errorf("at %g, paused %t and halted %t, the status is %q, want %q", statusCase.Speed, statusCase.Paused,
+				statusCase.Halted, status, statusCase.Expected)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package chip8
+
+import (
+	"image"
+	"image/color"
+)
+
+// MegaChip-8 extends SUPER-CHIP with a 256x192 display whose pixels are picked from a palette of 256 colors,
+// sprites of any size made of one palette index per byte, a 24-bit I reaching 32 MiB of memory and the playback
+// of digitized sound. Its instructions are only decoded on the megachip platform; the display only works in
+// colors once 0011 turned the MegaChip mode on.
+const (
+	MegaChipWidth      = 256
+	MegaChipHeight     = 192
+	MegaChipMemorySize = 32 << 20
+)
+
+// BlendMode selects how 080N mixes the pixels of a sprite with the display.
+type BlendMode uint8
+
+const (
+	BlendNormal   BlendMode = iota // BlendNormal draws the sprite over the display, with the alpha of its colors.
+	Blend25                        // Blend25 draws the sprite at 25% opacity.
+	Blend50                        // Blend50 draws the sprite at 50% opacity.
+	Blend75                        // Blend75 draws the sprite at 75% opacity.
+	BlendAdd                       // BlendAdd adds the sprite to the display.
+	BlendMultiply                  // BlendMultiply multiplies the display by the sprite.
+)
+
+// MegaChip holds the state MegaChip-8 adds to the core. The sprites are drawn into Buffer, which 00E0 shows,
+// copying it to Frame and to the Screen of the core, before clearing it for the next frame.
+type MegaChip struct {
+	Enabled        bool            // Enabled is set by 0011 and cleared by 0010; the core works as CHIP-8 while it is off.
+	Palette        [256]color.RGBA // Palette holds the colors loaded by 02NN; index 0 is transparent.
+	SpriteWidth    int             // SpriteWidth is the width of the sprites in pixels, set by 03NN.
+	SpriteHeight   int             // SpriteHeight is the height of the sprites in pixels, set by 04NN.
+	Alpha          uint8           // Alpha is the opacity of the whole display set by 05NN, fading it to black.
+	Blend          BlendMode       // Blend is the blend mode of the sprites set by 080N.
+	CollisionColor uint8           // CollisionColor is the palette index whose pixels a sprite collides with, set by 09NN.
+	Buffer         []byte          // Buffer is the display being drawn, RGBA, 4 bytes per pixel.
+	Indexes        []byte          // Indexes are the palette indexes of the pixels of Buffer, for the collisions.
+	Frame          []byte          // Frame is the display last shown by 00E0, RGBA.
+
+	Sound *DigitizedSound `json:"-"` // Sound is the digitized sound playing, nil when none is.
+}
+
+// DigitizedSound is a sound 060N plays from memory: unsigned 8-bit samples at a given rate.
+type DigitizedSound struct {
+	Rate    int    // Rate is the number of samples per second.
+	Samples []byte // Samples are the unsigned 8-bit samples, 0x80 being silence.
+	Loop    bool   // Loop plays the sound again and again until 0700 stops it.
+}
+
+// SamplePlayer is implemented by the audio sinks able to play digitized sound; the others only play the buzzer.
+type SamplePlayer interface {
+	PlaySamples(sound *DigitizedSound) // PlaySamples replaces the playing sound, nil stopping it.
+}
+
+// NewMegaChip returns the state of a core just switched to the MegaChip platform: a black palette but for a
+// white index 255, which the font is drawn with, and 1x1 opaque sprites.
+func NewMegaChip() *MegaChip {
+	megaChip := &MegaChip{SpriteWidth: 1, SpriteHeight: 1, Alpha: 0xFF}
+	for index := range megaChip.Palette {
+		megaChip.Palette[index] = color.RGBA{A: 0xFF}
+	}
+	megaChip.Palette[0] = color.RGBA{}
+	megaChip.Palette[0xFF] = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	megaChip.Buffer = make([]byte, MegaChipWidth*MegaChipHeight*4)
+	megaChip.Indexes = make([]byte, MegaChipWidth*MegaChipHeight)
+	megaChip.Frame = make([]byte, MegaChipWidth*MegaChipHeight*4)
+	return megaChip
+}
+
+// clone returns a copy sharing nothing with megaChip, for the saved states.
+func (megaChip *MegaChip) clone() *MegaChip {
+	copied := *megaChip
+	copied.Buffer = append([]byte(nil), megaChip.Buffer...)
+	copied.Indexes = append([]byte(nil), megaChip.Indexes...)
+	copied.Frame = append([]byte(nil), megaChip.Frame...)
+	return &copied
+}
+
+// valid tells whether the buffers have the size of the display, as a restored state must.
+func (megaChip *MegaChip) valid() bool {
+	return len(megaChip.Buffer) == MegaChipWidth*MegaChipHeight*4 && len(megaChip.Indexes) == MegaChipWidth*MegaChipHeight &&
+		len(megaChip.Frame) == MegaChipWidth*MegaChipHeight*4
+}
+
+// megaChipState returns the MegaChip state of the core, creating it on the first MegaChip instruction.
+func (chip8Core *Chip8Core) megaChipState() *MegaChip {
+	if chip8Core.MegaChip == nil {
+		chip8Core.MegaChip = NewMegaChip()
+	}
+	return chip8Core.MegaChip
+}
+
+// MegaChipEnabled tells whether the display and the sprites work the MegaChip way.
+func (chip8Core *Chip8Core) MegaChipEnabled() bool {
+	return chip8Core.MegaChip != nil && chip8Core.MegaChip.Enabled
+}
+
+// SetMegaChipMode turns the MegaChip mode on, with a 256x192 display, or back off to a 64x32 display.
+func (chip8Core *Chip8Core) SetMegaChipMode(enabled bool) {
+	megaChip := chip8Core.megaChipState()
+	megaChip.Enabled = enabled
+	clear(megaChip.Buffer)
+	clear(megaChip.Indexes)
+	clear(megaChip.Frame)
+	if enabled {
+		chip8Core.SetResolution(MegaChipWidth, MegaChipHeight)
+	} else {
+		chip8Core.SetResolution(64, 32)
+	}
+}
+
+// presentMegaChipFrame shows the display drawn since the last 00E0 and starts a blank one.
+func (chip8Core *Chip8Core) presentMegaChipFrame() {
+	megaChip := chip8Core.MegaChip
+	copy(megaChip.Frame, megaChip.Buffer)
+	for positionY := 0; positionY < MegaChipHeight; positionY++ {
+		for positionX := 0; positionX < MegaChipWidth; positionX++ {
+			chip8Core.Screen[positionY][positionX] = megaChip.Indexes[positionY*MegaChipWidth+positionX] != 0
+		}
+	}
+	clear(megaChip.Buffer)
+	clear(megaChip.Indexes)
+}
+
+// LoadMegaChipPalette loads count colors from memory at I into the palette from index 1 on, 4 bytes each in
+// the order alpha, red, green, blue.
+func (chip8Core *Chip8Core) LoadMegaChipPalette(count int) {
+	megaChip := chip8Core.megaChipState()
+	for index := 0; index < count && index < len(megaChip.Palette)-1; index++ {
+		address := chip8Core.I + uint32(index*4)
+		megaChip.Palette[index+1] = color.RGBA{
+			A: chip8Core.ReadMemory(address),
+			R: chip8Core.ReadMemory(address + 1),
+			G: chip8Core.ReadMemory(address + 2),
+			B: chip8Core.ReadMemory(address + 3),
+		}
+	}
+}
+
+// PlayMegaChipSound starts the digitized sound at I: a 16-bit sample rate, a 24-bit length, a byte left at
+// zero and the samples.
+func (chip8Core *Chip8Core) PlayMegaChipSound(loop bool) {
+	header := make([]byte, 6)
+	for index := range header {
+		header[index] = chip8Core.ReadMemory(chip8Core.I + uint32(index))
+	}
+	length := int(header[2])<<16 | int(header[3])<<8 | int(header[4])
+	start := min(int(chip8Core.I)+len(header), len(chip8Core.Memory))
+	length = max(0, min(length, len(chip8Core.Memory)-start))
+	chip8Core.megaChipState().Sound = &DigitizedSound{
+		Rate:    int(header[0])<<8 | int(header[1]),
+		Samples: append([]byte(nil), chip8Core.Memory[start:start+length]...),
+		Loop:    loop,
+	}
+}
+
+// DrawMegaChipSprite draws the sprite at I at (positionX, positionY) in MegaChip mode and reports whether it
+// covered a pixel drawn in the collision color. The font, below 0x100, is still drawn from its 8-pixel rows, in
+// the color of index 255, height rows of it; any other sprite is SpriteWidth x SpriteHeight palette indexes, row
+// after row, index 0 leaving the display as it is. The pixels falling off the display are clipped.
+func (chip8Core *Chip8Core) DrawMegaChipSprite(positionX uint8, positionY uint8, height uint8) bool {
+	megaChip := chip8Core.MegaChip
+	collision := false
+	plot := func(column int, row int, index byte) {
+		pixelX, pixelY := int(positionX)+column, int(positionY)+row
+		if index == 0 || pixelX >= MegaChipWidth || pixelY >= MegaChipHeight {
+			return
+		}
+		pixel := pixelY*MegaChipWidth + pixelX
+		if megaChip.Indexes[pixel] != 0 && megaChip.Indexes[pixel] == megaChip.CollisionColor {
+			collision = true
+		}
+		megaChip.Indexes[pixel] = index
+		megaChip.blend(megaChip.Buffer[pixel*4:pixel*4+4], megaChip.Palette[index])
+	}
+
+	if chip8Core.I < 0x100 {
+		for row := 0; row < int(height); row++ {
+			bits := chip8Core.ReadMemory(chip8Core.I + uint32(row))
+			for column := 0; column < 8; column++ {
+				if bits&(0x80>>column) != 0 {
+					plot(column, row, 0xFF)
+				}
+			}
+		}
+		return collision
+	}
+	for row := 0; row < megaChip.SpriteHeight; row++ {
+		for column := 0; column < megaChip.SpriteWidth; column++ {
+			plot(column, row, chip8Core.ReadMemory(chip8Core.I+uint32(row*megaChip.SpriteWidth+column)))
+		}
+	}
+	return collision
+}
+
+// scroll moves Buffer and Indexes by columns to the right and rows down, the pixels scrolled in being blank.
+func (megaChip *MegaChip) scroll(columns int, rows int) {
+	buffer := make([]byte, len(megaChip.Buffer))
+	indexes := make([]byte, len(megaChip.Indexes))
+	for positionY := max(0, rows); positionY < min(MegaChipHeight, MegaChipHeight+rows); positionY++ {
+		for positionX := max(0, columns); positionX < min(MegaChipWidth, MegaChipWidth+columns); positionX++ {
+			pixel, source := positionY*MegaChipWidth+positionX, (positionY-rows)*MegaChipWidth+positionX-columns
+			indexes[pixel] = megaChip.Indexes[source]
+			copy(buffer[pixel*4:pixel*4+4], megaChip.Buffer[source*4:source*4+4])
+		}
+	}
+	megaChip.Buffer, megaChip.Indexes = buffer, indexes
+}
+
+// MegaChipDecoder decodes the instructions of MegaChip-8, which adds its own to the 0NNN opcodes and 00BN to
+// the SUPER-CHIP ones.
+type MegaChipDecoder struct {
+	SuperChipDecoder
+}
+
+func (megaChipDecoder *MegaChipDecoder) Decode(opcode uint16) Instruction {
+	switch {
+	case opcode == 0x0010:
+		return &DisableMegaChip{GenericInstruction{opcode}}
+	case opcode == 0x0011:
+		return &EnableMegaChip{GenericInstruction{opcode}}
+	case opcode&0xFF00 == 0x0100:
+		return &SetLongI{GenericInstruction{opcode}}
+	case opcode&0xFF00 == 0x0200:
+		return &LoadPalette{GenericInstruction{opcode}}
+	case opcode&0xFF00 == 0x0300:
+		return &SetSpriteWidth{GenericInstruction{opcode}}
+	case opcode&0xFF00 == 0x0400:
+		return &SetSpriteHeight{GenericInstruction{opcode}}
+	case opcode&0xFF00 == 0x0500:
+		return &SetScreenAlpha{GenericInstruction{opcode}}
+	case opcode&0xFFF0 == 0x0600:
+		return &PlaySound{GenericInstruction{opcode}}
+	case opcode == 0x0700:
+		return &StopSound{GenericInstruction{opcode}}
+	case opcode&0xFFF0 == 0x0800:
+		return &SetBlendMode{GenericInstruction{opcode}}
+	case opcode&0xFF00 == 0x0900:
+		return &SetCollisionColor{GenericInstruction{opcode}}
+	case opcode&0xFFF0 == 0x00B0:
+		return &ScrollUp{GenericInstruction{opcode}}
+	}
+	return megaChipDecoder.SuperChipDecoder.Decode(opcode)
+}
+
+// blend mixes a color into the RGBA pixel with the blend mode, weighted by the alpha of the color.
+func (megaChip *MegaChip) blend(pixel []byte, source color.RGBA) {
+	opacity := int(source.A)
+	switch megaChip.Blend {
+	case Blend25:
+		opacity /= 4
+	case Blend50:
+		opacity /= 2
+	case Blend75:
+		opacity = opacity * 3 / 4
+	}
+	for channel, value := range [3]byte{source.R, source.G, source.B} {
+		destination := int(pixel[channel])
+		var mixed int
+		switch megaChip.Blend {
+		case BlendAdd:
+			mixed = min(0xFF, destination+int(value))
+		case BlendMultiply:
+			mixed = destination * int(value) / 0xFF
+		default:
+			mixed = int(value)
+		}
+		pixel[channel] = byte(destination + (mixed-destination)*opacity/0xFF)
+	}
+	pixel[3] = 0xFF
+}
+
+// MegaChipImage renders the frame last shown in MegaChip mode, faded by the alpha of the display, each pixel
+// becoming a scale x scale square.
+func MegaChipImage(megaChip *MegaChip, scale int) *image.RGBA {
+	frameImage := image.NewRGBA(image.Rect(0, 0, MegaChipWidth*scale, MegaChipHeight*scale))
+	for positionY := 0; positionY < MegaChipHeight*scale; positionY++ {
+		for positionX := 0; positionX < MegaChipWidth*scale; positionX++ {
+			pixel := megaChip.Frame[((positionY/scale)*MegaChipWidth+positionX/scale)*4:]
+			offset := frameImage.PixOffset(positionX, positionY)
+			for channel := 0; channel < 3; channel++ {
+				frameImage.Pix[offset+channel] = byte(int(pixel[channel]) * int(megaChip.Alpha) / 0xFF)
+			}
+			frameImage.Pix[offset+3] = 0xFF
+		}
+	}
+	return frameImage
+}
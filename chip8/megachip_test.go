@@ -0,0 +1,219 @@
+package chip8
+
+import (
+	"image/color"
+	"testing"
+)
+
+// loadMegaChipProgram loads a MegaChip program with data copied to 0x300, where 01000300 points I.
+func loadMegaChipProgram(t *testing.T, program []byte, data []byte) *Machine {
+	t.Helper()
+	machine := loadPlatformProgram(t, "megachip", program)
+	copy(machine.Core.Memory[0x300:], data)
+	return machine
+}
+
+// stepAll runs steps instructions.
+func stepAll(machine *Machine, steps int) {
+	for step := 0; step < steps; step++ {
+		machine.Step()
+	}
+}
+
+func TestMegaChipInstructions(t *testing.T) {
+	t.Run("0011 0010", func(t *testing.T) {
+		machine := loadMegaChipProgram(t, []byte{0x00, 0x11, 0x00, 0x10}, nil)
+		machine.Step()
+		if !machine.Core.MegaChipEnabled() || machine.Core.ScreenWidth() != MegaChipWidth || machine.Core.ScreenHeight() != MegaChipHeight {
+			t.Errorf("0011 left the MegaChip mode %t with a %dx%d display, want on with 256x192",
+				machine.Core.MegaChipEnabled(), machine.Core.ScreenWidth(), machine.Core.ScreenHeight())
+		}
+		machine.Step()
+		if machine.Core.MegaChipEnabled() || machine.Core.ScreenWidth() != 64 || machine.Core.ScreenHeight() != 32 {
+			t.Errorf("0010 left the MegaChip mode %t with a %dx%d display, want off with 64x32",
+				machine.Core.MegaChipEnabled(), machine.Core.ScreenWidth(), machine.Core.ScreenHeight())
+		}
+	})
+
+	t.Run("01NN NNNN", func(t *testing.T) {
+		machine := loadMegaChipProgram(t, []byte{0x01, 0x12, 0x34, 0x56}, nil)
+		machine.Step()
+		if machine.Core.I != 0x123456 || machine.Core.PC != 0x204 {
+			t.Errorf("01123456 left I at %06X and PC at %04X, want 123456 and 0204", machine.Core.I, machine.Core.PC)
+		}
+	})
+
+	t.Run("02NN", func(t *testing.T) {
+		machine := loadMegaChipProgram(t, []byte{0x01, 0x00, 0x03, 0x00, 0x02, 0x02},
+			[]byte{0xFF, 0x10, 0x20, 0x30, 0x80, 0x40, 0x50, 0x60})
+		stepAll(machine, 2)
+		palette := machine.Core.MegaChip.Palette
+		expected := [4]color.RGBA{{}, {R: 0x10, G: 0x20, B: 0x30, A: 0xFF}, {R: 0x40, G: 0x50, B: 0x60, A: 0x80}, {A: 0xFF}}
+		if [4]color.RGBA(palette[:4]) != expected {
+			t.Errorf("0202 loaded the palette %v, want %v", palette[:4], expected)
+		}
+	})
+
+	for _, settingCase := range []struct {
+		Name     string
+		Program  []byte
+		Expected MegaChip
+	}{
+		{"03NN 04NN", []byte{0x03, 0x10, 0x04, 0x08}, MegaChip{SpriteWidth: 16, SpriteHeight: 8, Alpha: 0xFF}},
+		{"03NN 04NN of 256", []byte{0x03, 0x00, 0x04, 0x00}, MegaChip{SpriteWidth: 256, SpriteHeight: 256, Alpha: 0xFF}},
+		{"05NN", []byte{0x05, 0x80, 0x00, 0xE0}, MegaChip{SpriteWidth: 1, SpriteHeight: 1, Alpha: 0x80}},
+		{"080N 09NN", []byte{0x08, 0x04, 0x09, 0x07}, MegaChip{SpriteWidth: 1, SpriteHeight: 1, Alpha: 0xFF, Blend: BlendAdd, CollisionColor: 7}},
+	} {
+		t.Run(settingCase.Name, func(t *testing.T) {
+			machine := loadMegaChipProgram(t, settingCase.Program, nil)
+			stepAll(machine, 2)
+			megaChip, expected := machine.Core.MegaChip, settingCase.Expected
+			if megaChip.SpriteWidth != expected.SpriteWidth || megaChip.SpriteHeight != expected.SpriteHeight || megaChip.Alpha != expected.Alpha ||
+				megaChip.Blend != expected.Blend || megaChip.CollisionColor != expected.CollisionColor {
+				t.Errorf("got sprites of %dx%d, alpha %02X, blend %d and collision color %d, want %dx%d, %02X, %d and %d",
+					megaChip.SpriteWidth, megaChip.SpriteHeight, megaChip.Alpha, megaChip.Blend, megaChip.CollisionColor,
+					expected.SpriteWidth, expected.SpriteHeight, expected.Alpha, expected.Blend, expected.CollisionColor)
+			}
+		})
+	}
+
+	t.Run("DXYN", func(t *testing.T) {
+		// Index 1 is red; the sprite is 2x1, a red pixel and a transparent one, drawn twice over itself.
+		machine := loadMegaChipProgram(t, []byte{
+			0x00, 0x11, 0x01, 0x00, 0x03, 0x00, 0x02, 0x01, 0x03, 0x02, 0x09, 0x01,
+			0x01, 0x00, 0x03, 0x04, 0xD0, 0x11, 0xD0, 0x11, 0x00, 0xE0,
+		}, []byte{0xFF, 0xFF, 0x00, 0x00, 0x01, 0x00})
+		stepAll(machine, 8)
+		megaChip := machine.Core.MegaChip
+		if megaChip.Indexes[0] != 1 || megaChip.Indexes[1] != 0 || [4]byte(megaChip.Buffer[:4]) != [4]byte{0xFF, 0, 0, 0xFF} {
+			t.Errorf("DXYN left the indexes %v and the pixel %v, want 1, 0 and red", megaChip.Indexes[:2], megaChip.Buffer[:4])
+		}
+		if machine.Core.V[0xF] != 1 {
+			t.Errorf("drawing over the collision color left VF at %d, want 1", machine.Core.V[0xF])
+		}
+		machine.Step()
+		if [4]byte(megaChip.Frame[:4]) != [4]byte{0xFF, 0, 0, 0xFF} || megaChip.Indexes[0] != 0 || !machine.Core.GetPixel(0, 0) || machine.Core.GetPixel(1, 0) {
+			t.Errorf("00E0 showed %v with the index %d left, want red and the buffer cleared", megaChip.Frame[:4], megaChip.Indexes[0])
+		}
+	})
+
+	t.Run("DXYN font", func(t *testing.T) {
+		// I below 0x100 draws the 8-pixel rows of the font in the color of index 255.
+		machine := loadMegaChipProgram(t, []byte{0x00, 0x11, 0xA0, 0x00, 0xD0, 0x01}, nil)
+		stepAll(machine, 3)
+		megaChip := machine.Core.MegaChip
+		for column := 0; column < 8; column++ {
+			lit := machine.Core.Memory[0]&(0x80>>column) != 0
+			if (megaChip.Indexes[column] == 0xFF) != lit {
+				t.Errorf("pixel %d of the first row of 0 has the index %d, want it lit %t", column, megaChip.Indexes[column], lit)
+			}
+		}
+	})
+
+	t.Run("060N 0700", func(t *testing.T) {
+		machine := loadMegaChipProgram(t, []byte{0x01, 0x00, 0x03, 0x00, 0x06, 0x01, 0x06, 0x00, 0x07, 0x00},
+			[]byte{0x1F, 0x40, 0x00, 0x00, 0x04, 0x00, 0x80, 0x90, 0xA0, 0xB0, 0xC0})
+		stepAll(machine, 2)
+		sound := machine.Core.MegaChip.Sound
+		if sound == nil || sound.Rate != 8000 || string(sound.Samples) != "\x80\x90\xA0\xB0" || sound.Loop {
+			t.Fatalf("0601 plays %+v, want 4 samples at 8000 Hz once", sound)
+		}
+		machine.Step()
+		if !machine.Core.MegaChip.Sound.Loop {
+			t.Error("0600 does not loop")
+		}
+		machine.Step()
+		if machine.Core.MegaChip.Sound != nil {
+			t.Error("0700 did not stop the sound")
+		}
+	})
+
+	t.Run("060N past the end of memory", func(t *testing.T) {
+		machine := loadMegaChipProgram(t, []byte{0x06, 0x01}, nil)
+		machine.Core.I = MegaChipMemorySize - 8
+		copy(machine.Core.Memory[machine.Core.I:], []byte{0x1F, 0x40, 0xFF, 0xFF, 0xFF, 0x00, 0x80, 0x81})
+		machine.Step()
+		if sound := machine.Core.MegaChip.Sound; sound == nil || string(sound.Samples) != "\x80\x81" {
+			t.Errorf("a sound longer than memory plays %+v, want the 2 samples left", sound)
+		}
+	})
+}
+
+func TestMegaChipBlend(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Blend       BlendMode
+		Destination byte
+		Source      color.RGBA
+		Expected    byte
+	}{
+		{"normal", BlendNormal, 10, color.RGBA{R: 200, A: 0xFF}, 200},
+		{"normal half transparent", BlendNormal, 0, color.RGBA{R: 200, A: 0x80}, 100},
+		{"25%", Blend25, 0, color.RGBA{R: 200, A: 0xFF}, 49},
+		{"50%", Blend50, 0, color.RGBA{R: 200, A: 0xFF}, 99},
+		{"75%", Blend75, 0, color.RGBA{R: 200, A: 0xFF}, 149},
+		{"add", BlendAdd, 100, color.RGBA{R: 100, A: 0xFF}, 200},
+		{"add saturated", BlendAdd, 100, color.RGBA{R: 200, A: 0xFF}, 0xFF},
+		{"multiply", BlendMultiply, 200, color.RGBA{R: 128, A: 0xFF}, 100},
+	}
+	for _, blendCase := range cases {
+		megaChip := NewMegaChip()
+		megaChip.Blend = blendCase.Blend
+		pixel := []byte{blendCase.Destination, 0, 0, 0}
+		megaChip.blend(pixel, blendCase.Source)
+		if pixel[0] != blendCase.Expected || pixel[3] != 0xFF {
+			t.Errorf("%s blended %d with %v into %v, want %d opaque", blendCase.Name, blendCase.Destination, blendCase.Source,
+				pixel, blendCase.Expected)
+		}
+	}
+}
+
+// TestMegaChipSuperChipInstructions checks the SUPER-CHIP instructions MegaChip-8 keeps.
+func TestMegaChipSuperChipInstructions(t *testing.T) {
+	t.Run("scroll", func(t *testing.T) {
+		machine := loadMegaChipProgram(t, []byte{0x00, 0x11, 0x00, 0xC2, 0x00, 0xFB, 0x00, 0xFC, 0x00, 0xB1}, nil)
+		machine.Step()
+		megaChip := machine.Core.MegaChip
+		megaChip.Indexes[10*MegaChipWidth+10] = 5
+		for _, scrollCase := range []struct {
+			Name      string
+			ExpectedX int
+			ExpectedY int
+		}{
+			{"00C2", 10, 12},
+			{"00FB", 14, 12},
+			{"00FC", 10, 12},
+			{"00B1", 10, 11},
+		} {
+			machine.Step()
+			if megaChip.Indexes[scrollCase.ExpectedY*MegaChipWidth+scrollCase.ExpectedX] != 5 {
+				t.Fatalf("%s did not move the pixel to %d,%d", scrollCase.Name, scrollCase.ExpectedX, scrollCase.ExpectedY)
+			}
+		}
+	})
+
+	t.Run("00FF", func(t *testing.T) {
+		machine := loadMegaChipProgram(t, []byte{0x00, 0x11, 0x00, 0xFF}, nil)
+		stepAll(machine, 2)
+		if machine.Core.ScreenWidth() != MegaChipWidth || machine.Core.ScreenHeight() != MegaChipHeight {
+			t.Errorf("00FF switched the MegaChip display to %dx%d", machine.Core.ScreenWidth(), machine.Core.ScreenHeight())
+		}
+	})
+
+	t.Run("FX75 FX85", func(t *testing.T) {
+		machine := loadMegaChipProgram(t, []byte{0xF1, 0x75, 0x60, 0x00, 0x61, 0x00, 0xF1, 0x85}, nil)
+		machine.Core.V[0], machine.Core.V[1] = 7, 9
+		stepAll(machine, 4)
+		if machine.Core.V[0] != 7 || machine.Core.V[1] != 9 {
+			t.Errorf("FX85 restored V0 %d and V1 %d, want 7 and 9", machine.Core.V[0], machine.Core.V[1])
+		}
+	})
+
+	t.Run("00FD", func(t *testing.T) {
+		machine := loadMegaChipProgram(t, []byte{0x00, 0xFD}, nil)
+		machine.Step()
+		if !machine.Halted() {
+			t.Error("00FD did not halt the machine")
+		}
+	})
+}
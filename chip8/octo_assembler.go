@@ -0,0 +1,956 @@
+package chip8
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// OctoProgram is the result of assembling Octo source code.
+type OctoProgram struct {
+	Data   []byte            // Data is the assembled program, starting at 0x200.
+	Labels map[string]uint16 // Labels are the addresses of the labels of the source.
+	Lines  map[uint16]int    // Lines maps the address of every instruction to its line in the source, starting at 1.
+}
+
+type octoToken struct {
+	text string
+	line int
+}
+
+type octoFixupKind int
+
+const (
+	octoFixupAddress  octoFixupKind = iota // octoFixupAddress fills the NNN of the opcode at the address.
+	octoFixupWord                          // octoFixupWord fills the 16-bit word at the address.
+	octoFixupHighByte                      // octoFixupHighByte fills the low nibble of the byte at the address with bits 8-11 of the label.
+	octoFixupLowByte                       // octoFixupLowByte fills the byte at the address with the low byte of the label.
+)
+
+type octoFixup struct {
+	address int
+	label   string
+	kind    octoFixupKind
+	line    int
+}
+
+type octoMacro struct {
+	arguments []string
+	body      []octoToken
+	calls     int
+}
+
+type octoAssembler struct {
+	tokens   []octoToken
+	position int
+	line     int
+
+	memory  []byte
+	here    int
+	highest int
+
+	labels    map[string]int
+	constants map[string]float64
+	aliases   map[string]uint16
+	macros    map[string]*octoMacro
+	fixups    []octoFixup
+	lines     map[uint16]int
+
+	branches []int   // branches holds the jumps of the open if ... begin blocks, to patch at else and end.
+	loops    []int   // loops holds the start addresses of the open loops.
+	whiles   [][]int // whiles holds, per open loop, the jumps out of it to patch at again.
+}
+
+// AssembleOcto assembles a program written in the Octo assembly language.
+// The whole language is supported except :stringmode; XO-CHIP and SUPER-CHIP statements are
+// assembled even though not every platform runs them.
+func AssembleOcto(source string) (*OctoProgram, error) {
+	octo := &octoAssembler{
+		tokens:    tokenizeOcto(source),
+		memory:    make([]byte, 65536),
+		here:      0x200,
+		highest:   0x200,
+		labels:    map[string]int{},
+		constants: map[string]float64{},
+		aliases:   map[string]uint16{},
+		macros:    map[string]*octoMacro{},
+		lines:     map[uint16]int{},
+	}
+	for octo.position < len(octo.tokens) {
+		if err := octo.statement(octo.next()); err != nil {
+			return nil, fmt.Errorf("line %d: %w", octo.line, err)
+		}
+	}
+	switch {
+	case len(octo.branches) > 0:
+		return nil, fmt.Errorf("missing end for an if ... begin")
+	case len(octo.loops) > 0:
+		return nil, fmt.Errorf("missing again for a loop")
+	}
+	for _, fixup := range octo.fixups {
+		address, exists := octo.labels[fixup.label]
+		if !exists {
+			return nil, fmt.Errorf("line %d: undefined name %q", fixup.line, fixup.label)
+		}
+		octo.patch(fixup, address)
+	}
+
+	program := &OctoProgram{
+		Data:   append([]byte(nil), octo.memory[0x200:octo.highest]...),
+		Labels: map[string]uint16{},
+		Lines:  octo.lines,
+	}
+	for name, address := range octo.labels {
+		program.Labels[name] = uint16(address)
+	}
+	return program, nil
+}
+
+// Symbol names an address after the closest label at or before it, such as "main" or "main+4".
+// It returns an empty string when no label precedes the address.
+func (program *OctoProgram) Symbol(address uint16) string {
+	return LabelSymbol(program.Labels, address)
+}
+
+// LineAddress returns the address of the first instruction of a line of the source, or of the closest
+// following line holding an instruction, together with the line actually used.
+func (program *OctoProgram) LineAddress(line int) (uint16, int, bool) {
+	address, addressLine, found := uint16(0), 0, false
+	for current, currentLine := range program.Lines {
+		if currentLine < line || (found && (currentLine > addressLine || currentLine == addressLine && current > address)) {
+			continue
+		}
+		address, addressLine, found = current, currentLine, true
+	}
+	return address, addressLine, found
+}
+
+func tokenizeOcto(source string) []octoToken {
+	var tokens []octoToken
+	for lineIndex, line := range strings.Split(source, "\n") {
+		if comment := strings.IndexByte(line, '#'); comment >= 0 {
+			line = line[:comment]
+		}
+		line = strings.NewReplacer("{", " { ", "}", " } ").Replace(line)
+		for _, text := range strings.Fields(line) {
+			tokens = append(tokens, octoToken{text: text, line: lineIndex + 1})
+		}
+	}
+	return tokens
+}
+
+func (octo *octoAssembler) next() string {
+	if octo.position >= len(octo.tokens) {
+		return ""
+	}
+	token := octo.tokens[octo.position]
+	octo.position++
+	octo.line = token.line
+	return token.text
+}
+
+func (octo *octoAssembler) peek() string {
+	if octo.position >= len(octo.tokens) {
+		return ""
+	}
+	return octo.tokens[octo.position].text
+}
+
+func (octo *octoAssembler) expect(text string) error {
+	if token := octo.next(); token != text {
+		return fmt.Errorf("expected %q, found %q", text, token)
+	}
+	return nil
+}
+
+func (octo *octoAssembler) emitByte(value byte) {
+	if octo.here >= len(octo.memory) {
+		octo.here++
+		return
+	}
+	octo.memory[octo.here] = value
+	octo.here++
+	octo.highest = max(octo.highest, octo.here)
+}
+
+func (octo *octoAssembler) emit(opcode uint16) {
+	if octo.here < len(octo.memory) {
+		octo.lines[uint16(octo.here)] = octo.line
+	}
+	octo.emitByte(byte(opcode >> 8))
+	octo.emitByte(byte(opcode))
+}
+
+func (octo *octoAssembler) patch(fixup octoFixup, address int) {
+	if fixup.address+1 >= len(octo.memory) {
+		return
+	}
+	switch fixup.kind {
+	case octoFixupAddress:
+		octo.memory[fixup.address] = octo.memory[fixup.address]&0xF0 | byte(address>>8)&0x0F
+		octo.memory[fixup.address+1] = byte(address)
+	case octoFixupWord:
+		octo.memory[fixup.address] = byte(address >> 8)
+		octo.memory[fixup.address+1] = byte(address)
+	case octoFixupHighByte:
+		octo.memory[fixup.address] |= byte(address>>8) & 0x0F
+	case octoFixupLowByte:
+		octo.memory[fixup.address] = byte(address)
+	}
+}
+
+// emitAddress emits the opcode with the address named by the next token, patched later for forward references.
+func (octo *octoAssembler) emitAddress(opcode uint16) error {
+	token := octo.next()
+	if value, known, err := octo.value(token); err != nil {
+		return err
+	} else if known {
+		octo.emit(opcode | uint16(value)&0x0FFF)
+		return nil
+	}
+	octo.fixups = append(octo.fixups, octoFixup{address: octo.here, label: token, kind: octoFixupAddress, line: octo.line})
+	octo.emit(opcode)
+	return nil
+}
+
+func isOctoName(token string) bool {
+	if token == "" {
+		return false
+	}
+	first := token[0]
+	if !(first == '_' || first >= 'a' && first <= 'z' || first >= 'A' && first <= 'Z') {
+		return false
+	}
+	return !octoKeywords[token]
+}
+
+var octoKeywords = map[string]bool{
+	"return": true, "clear": true, "bcd": true, "save": true, "load": true, "sprite": true, "jump": true,
+	"jump0": true, "native": true, "delay": true, "buzzer": true, "pitch": true, "if": true, "then": true,
+	"begin": true, "else": true, "end": true, "loop": true, "while": true, "again": true, "key": true,
+	"-key": true, "hex": true, "bighex": true, "random": true, "long": true, "i": true, "hires": true,
+	"lores": true, "exit": true, "scroll-up": true, "scroll-down": true, "scroll-left": true,
+	"scroll-right": true, "plane": true, "audio": true, "saveflags": true, "loadflags": true,
+}
+
+// value resolves a number, a constant, a known label or a { calc } expression.
+// known is false for a name which may be a label defined further down.
+func (octo *octoAssembler) value(token string) (value int, known bool, err error) {
+	if token == "{" {
+		result, err := octo.calc()
+		return int(result), err == nil, err
+	}
+	if number, ok := parseOctoNumber(token); ok {
+		return number, true, nil
+	}
+	if constant, exists := octo.constants[token]; exists {
+		return int(constant), true, nil
+	}
+	if address, exists := octo.labels[token]; exists {
+		return address, true, nil
+	}
+	if !isOctoName(token) {
+		return 0, false, fmt.Errorf("expected a value, found %q", token)
+	}
+	return 0, false, nil
+}
+
+// knownValue resolves a value which cannot be a forward reference.
+func (octo *octoAssembler) knownValue() (int, error) {
+	token := octo.next()
+	value, known, err := octo.value(token)
+	if err == nil && !known {
+		err = fmt.Errorf("undefined name %q", token)
+	}
+	return value, err
+}
+
+func parseOctoNumber(token string) (int, bool) {
+	negative := strings.HasPrefix(token, "-")
+	digits := strings.TrimPrefix(token, "-")
+	var number int64
+	var err error
+	switch {
+	case strings.HasPrefix(digits, "0x"):
+		number, err = strconv.ParseInt(digits[2:], 16, 64)
+	case strings.HasPrefix(digits, "0b"):
+		number, err = strconv.ParseInt(digits[2:], 2, 64)
+	default:
+		number, err = strconv.ParseInt(digits, 10, 64)
+	}
+	if err != nil {
+		return 0, false
+	}
+	if negative {
+		number = -number
+	}
+	return int(number), true
+}
+
+func (octo *octoAssembler) register(token string) (uint16, bool) {
+	if register, exists := octo.aliases[token]; exists {
+		return register, true
+	}
+	lower := strings.ToLower(token)
+	if len(lower) == 2 && lower[0] == 'v' {
+		if register, err := strconv.ParseUint(lower[1:], 16, 8); err == nil {
+			return uint16(register), true
+		}
+	}
+	return 0, false
+}
+
+func (octo *octoAssembler) expectRegister() (uint16, error) {
+	token := octo.next()
+	register, ok := octo.register(token)
+	if !ok {
+		return 0, fmt.Errorf("expected a register, found %q", token)
+	}
+	return register, nil
+}
+
+func (octo *octoAssembler) statement(token string) error {
+	if macro, exists := octo.macros[token]; exists {
+		return octo.expand(macro)
+	}
+	if register, ok := octo.register(token); ok {
+		return octo.registerStatement(register)
+	}
+	switch token {
+	case ":":
+		name := octo.next()
+		if !isOctoName(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+		if _, exists := octo.labels[name]; exists {
+			return fmt.Errorf("label %q is defined twice", name)
+		}
+		octo.labels[name] = octo.here
+	case ":next":
+		name := octo.next()
+		if !isOctoName(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+		octo.labels[name] = octo.here + 1
+	case ":alias":
+		name := octo.next()
+		if octo.peek() == "{" {
+			octo.next()
+			value, err := octo.calc()
+			if err != nil {
+				return err
+			}
+			octo.aliases[name] = uint16(value) & 0xF
+			return nil
+		}
+		register, err := octo.expectRegister()
+		if err != nil {
+			return err
+		}
+		octo.aliases[name] = register
+	case ":const":
+		name := octo.next()
+		value, err := octo.knownValue()
+		if err != nil {
+			return err
+		}
+		octo.constants[name] = float64(value)
+	case ":calc":
+		name := octo.next()
+		if err := octo.expect("{"); err != nil {
+			return err
+		}
+		value, err := octo.calc()
+		if err != nil {
+			return err
+		}
+		octo.constants[name] = value
+	case ":byte":
+		valueToken := octo.next()
+		value, known, err := octo.value(valueToken)
+		if err != nil {
+			return err
+		}
+		if !known {
+			octo.fixups = append(octo.fixups, octoFixup{address: octo.here, label: valueToken, kind: octoFixupLowByte, line: octo.line})
+		}
+		octo.emitByte(byte(value))
+	case ":pointer":
+		valueToken := octo.next()
+		value, known, err := octo.value(valueToken)
+		if err != nil {
+			return err
+		}
+		if !known {
+			octo.fixups = append(octo.fixups, octoFixup{address: octo.here, label: valueToken, kind: octoFixupWord, line: octo.line})
+		}
+		octo.emitByte(byte(value >> 8))
+		octo.emitByte(byte(value))
+	case ":org":
+		value, err := octo.knownValue()
+		if err != nil {
+			return err
+		}
+		if value < 0x200 || value >= len(octo.memory) {
+			return fmt.Errorf("address %#x is outside of the program", value)
+		}
+		octo.here = value
+	case ":unpack":
+		nybble, err := octo.knownValue()
+		if err != nil {
+			return err
+		}
+		labelToken := octo.next()
+		address, known, err := octo.value(labelToken)
+		if err != nil {
+			return err
+		}
+		if !known {
+			octo.fixups = append(octo.fixups,
+				octoFixup{address: octo.here + 1, label: labelToken, kind: octoFixupHighByte, line: octo.line},
+				octoFixup{address: octo.here + 3, label: labelToken, kind: octoFixupLowByte, line: octo.line})
+		}
+		octo.emit(0x6000 | uint16(nybble&0xF)<<4 | uint16(address>>8)&0x0F)
+		octo.emit(0x6100 | uint16(address)&0xFF)
+	case ":macro":
+		return octo.defineMacro()
+	case ":call":
+		return octo.emitAddress(0x2000)
+	case ":assert":
+		octo.next()
+		if err := octo.expect("{"); err != nil {
+			return err
+		}
+		value, err := octo.calc()
+		if err != nil {
+			return err
+		}
+		if value == 0 {
+			return fmt.Errorf("assertion failed")
+		}
+	case ":breakpoint", ":proto":
+		octo.next()
+	case ":monitor":
+		octo.next()
+		octo.next()
+	case ":stringmode":
+		return fmt.Errorf(":stringmode is not supported")
+	case ";", "return":
+		octo.emit(0x00EE)
+	case "clear":
+		octo.emit(0x00E0)
+	case "hires":
+		octo.emit(0x00FF)
+	case "lores":
+		octo.emit(0x00FE)
+	case "exit":
+		octo.emit(0x00FD)
+	case "scroll-left":
+		octo.emit(0x00FC)
+	case "scroll-right":
+		octo.emit(0x00FB)
+	case "scroll-down", "scroll-up":
+		rows, err := octo.knownValue()
+		if err != nil {
+			return err
+		}
+		opcode := uint16(0x00C0)
+		if token == "scroll-up" {
+			opcode = 0x00D0
+		}
+		octo.emit(opcode | uint16(rows)&0xF)
+	case "audio":
+		octo.emit(0xF002)
+	case "plane":
+		planes, err := octo.knownValue()
+		if err != nil {
+			return err
+		}
+		octo.emit(0xF001 | uint16(planes)&0xF<<8)
+	case "bcd", "saveflags", "loadflags":
+		register, err := octo.expectRegister()
+		if err != nil {
+			return err
+		}
+		opcode := map[string]uint16{"bcd": 0xF033, "saveflags": 0xF075, "loadflags": 0xF085}[token]
+		octo.emit(opcode | register<<8)
+	case "save", "load":
+		register, err := octo.expectRegister()
+		if err != nil {
+			return err
+		}
+		if octo.peek() == "-" {
+			octo.next()
+			last, err := octo.expectRegister()
+			if err != nil {
+				return err
+			}
+			opcode := uint16(0x5002)
+			if token == "load" {
+				opcode = 0x5003
+			}
+			octo.emit(opcode | register<<8 | last<<4)
+			return nil
+		}
+		opcode := uint16(0xF055)
+		if token == "load" {
+			opcode = 0xF065
+		}
+		octo.emit(opcode | register<<8)
+	case "delay", "buzzer", "pitch":
+		if err := octo.expect(":="); err != nil {
+			return err
+		}
+		register, err := octo.expectRegister()
+		if err != nil {
+			return err
+		}
+		opcode := map[string]uint16{"delay": 0xF015, "buzzer": 0xF018, "pitch": 0xF03A}[token]
+		octo.emit(opcode | register<<8)
+	case "sprite":
+		xRegister, err := octo.expectRegister()
+		if err != nil {
+			return err
+		}
+		yRegister, err := octo.expectRegister()
+		if err != nil {
+			return err
+		}
+		height, err := octo.knownValue()
+		if err != nil {
+			return err
+		}
+		octo.emit(0xD000 | xRegister<<8 | yRegister<<4 | uint16(height)&0xF)
+	case "jump":
+		return octo.emitAddress(0x1000)
+	case "jump0":
+		return octo.emitAddress(0xB000)
+	case "native":
+		return octo.emitAddress(0x0000)
+	case "i":
+		return octo.indexStatement()
+	case "if":
+		return octo.ifStatement()
+	case "else":
+		if len(octo.branches) == 0 {
+			return fmt.Errorf("else without if ... begin")
+		}
+		jump := octo.here
+		octo.emit(0x1000)
+		octo.patchJump(octo.branches[len(octo.branches)-1], octo.here)
+		octo.branches[len(octo.branches)-1] = jump
+	case "end":
+		if len(octo.branches) == 0 {
+			return fmt.Errorf("end without if ... begin")
+		}
+		octo.patchJump(octo.branches[len(octo.branches)-1], octo.here)
+		octo.branches = octo.branches[:len(octo.branches)-1]
+	case "loop":
+		octo.loops = append(octo.loops, octo.here)
+		octo.whiles = append(octo.whiles, nil)
+	case "while":
+		if len(octo.loops) == 0 {
+			return fmt.Errorf("while outside of a loop")
+		}
+		condition, err := octo.condition()
+		if err != nil {
+			return err
+		}
+		octo.emitCondition(condition, true)
+		octo.whiles[len(octo.whiles)-1] = append(octo.whiles[len(octo.whiles)-1], octo.here)
+		octo.emit(0x1000)
+	case "again":
+		if len(octo.loops) == 0 {
+			return fmt.Errorf("again without loop")
+		}
+		octo.emit(0x1000 | uint16(octo.loops[len(octo.loops)-1])&0x0FFF)
+		for _, jump := range octo.whiles[len(octo.whiles)-1] {
+			octo.patchJump(jump, octo.here)
+		}
+		octo.loops = octo.loops[:len(octo.loops)-1]
+		octo.whiles = octo.whiles[:len(octo.whiles)-1]
+	case "":
+		return fmt.Errorf("unexpected end of source")
+	default:
+		// Bare numbers are data bytes.
+		if number, ok := parseOctoNumber(token); ok {
+			octo.emitByte(byte(number))
+			return nil
+		}
+		// Any other name calls the subroutine at the label of that name.
+		if !isOctoName(token) {
+			return fmt.Errorf("unexpected %q", token)
+		}
+		octo.position--
+		return octo.emitAddress(0x2000)
+	}
+	return nil
+}
+
+func (octo *octoAssembler) patchJump(address int, target int) {
+	octo.patch(octoFixup{address: address, kind: octoFixupAddress}, target)
+}
+
+func (octo *octoAssembler) registerStatement(register uint16) error {
+	operator := octo.next()
+	operand := octo.next()
+	if source, ok := octo.register(operand); ok {
+		opcodes := map[string]uint16{":=": 0x8000, "|=": 0x8001, "&=": 0x8002, "^=": 0x8003, "+=": 0x8004, "-=": 0x8005, ">>=": 0x8006, "=-": 0x8007, "<<=": 0x800E}
+		opcode, exists := opcodes[operator]
+		if !exists {
+			return fmt.Errorf("unknown operator %q", operator)
+		}
+		octo.emit(opcode | register<<8 | source<<4)
+		return nil
+	}
+	switch {
+	case operator == ":=" && operand == "key":
+		octo.emit(0xF00A | register<<8)
+	case operator == ":=" && operand == "delay":
+		octo.emit(0xF007 | register<<8)
+	case operator == ":=" && operand == "random":
+		mask, err := octo.knownValue()
+		if err != nil {
+			return err
+		}
+		octo.emit(0xC000 | register<<8 | uint16(mask)&0xFF)
+	case operator == ":=" || operator == "+=" || operator == "-=":
+		value, known, err := octo.value(operand)
+		if err != nil {
+			return err
+		}
+		if !known {
+			return fmt.Errorf("undefined name %q", operand)
+		}
+		switch operator {
+		case ":=":
+			octo.emit(0x6000 | register<<8 | uint16(value)&0xFF)
+		case "+=":
+			octo.emit(0x7000 | register<<8 | uint16(value)&0xFF)
+		case "-=":
+			octo.emit(0x7000 | register<<8 | uint16(-value)&0xFF)
+		}
+	default:
+		return fmt.Errorf("operator %q needs a register, found %q", operator, operand)
+	}
+	return nil
+}
+
+func (octo *octoAssembler) indexStatement() error {
+	operator := octo.next()
+	switch operator {
+	case "+=":
+		register, err := octo.expectRegister()
+		if err != nil {
+			return err
+		}
+		octo.emit(0xF01E | register<<8)
+	case ":=":
+		switch octo.peek() {
+		case "hex", "bighex":
+			opcode := uint16(0xF029)
+			if octo.next() == "bighex" {
+				opcode = 0xF030
+			}
+			register, err := octo.expectRegister()
+			if err != nil {
+				return err
+			}
+			octo.emit(opcode | register<<8)
+		case "long":
+			octo.next()
+			token := octo.next()
+			value, known, err := octo.value(token)
+			if err != nil {
+				return err
+			}
+			octo.emit(0xF000)
+			if !known {
+				octo.fixups = append(octo.fixups, octoFixup{address: octo.here, label: token, kind: octoFixupWord, line: octo.line})
+			}
+			octo.emitByte(byte(value >> 8))
+			octo.emitByte(byte(value))
+		default:
+			return octo.emitAddress(0xA000)
+		}
+	default:
+		return fmt.Errorf("unknown operator %q for i", operator)
+	}
+	return nil
+}
+
+// octoCondition is the code of a condition: setup instructions followed by a skip which skips the next
+// instruction when the condition is false.
+type octoCondition []uint16
+
+func (octo *octoAssembler) condition() (octoCondition, error) {
+	register, err := octo.expectRegister()
+	if err != nil {
+		return nil, err
+	}
+	operator := octo.next()
+	switch operator {
+	case "key":
+		return octoCondition{0xE0A1 | register<<8}, nil
+	case "-key":
+		return octoCondition{0xE09E | register<<8}, nil
+	}
+	operand := octo.next()
+	source, isRegister := octo.register(operand)
+	var value int
+	if !isRegister {
+		var known bool
+		if value, known, err = octo.value(operand); err != nil {
+			return nil, err
+		} else if !known {
+			return nil, fmt.Errorf("undefined name %q", operand)
+		}
+	}
+	switch operator {
+	case "==", "!=":
+		var opcode uint16
+		switch {
+		case isRegister && operator == "==":
+			opcode = 0x9000 | register<<8 | source<<4
+		case isRegister:
+			opcode = 0x5000 | register<<8 | source<<4
+		case operator == "==":
+			opcode = 0x4000 | register<<8 | uint16(value)&0xFF
+		default:
+			opcode = 0x3000 | register<<8 | uint16(value)&0xFF
+		}
+		return octoCondition{opcode}, nil
+	case "<", ">", "<=", ">=":
+		// The comparison goes through vf: vf receives the right operand, then the register is subtracted
+		// so the borrow flag left in vf tells the result.
+		load := 0x6F00 | uint16(value)&0xFF
+		if isRegister {
+			load = 0x8F00 | source<<4
+		}
+		subtract := 0x8F05 | register<<4 // vf = right - register, vf = 1 when right >= register
+		if operator == "<" || operator == ">=" {
+			subtract = 0x8F07 | register<<4 // vf = register - right, vf = 1 when register >= right
+		}
+		skip := uint16(0x4F00) // the condition holds when vf is 0
+		if operator == "<=" || operator == ">=" {
+			skip = 0x4F01 // the condition holds when vf is 1
+		}
+		return octoCondition{load, subtract, skip}, nil
+	}
+	return nil, fmt.Errorf("unknown comparison %q", operator)
+}
+
+// emitCondition emits the condition, its skip inverted when the next instruction must be skipped when the condition is true.
+func (octo *octoAssembler) emitCondition(condition octoCondition, inverted bool) {
+	for index, opcode := range condition {
+		if inverted && index == len(condition)-1 {
+			switch opcode & 0xF000 {
+			case 0x3000:
+				opcode = opcode&0x0FFF | 0x4000
+			case 0x4000:
+				opcode = opcode&0x0FFF | 0x3000
+			case 0x5000:
+				opcode = opcode&0x0FFF | 0x9000
+			case 0x9000:
+				opcode = opcode&0x0FFF | 0x5000
+			case 0xE000:
+				opcode ^= 0x00A1 ^ 0x009E
+			}
+		}
+		octo.emit(opcode)
+	}
+}
+
+func (octo *octoAssembler) ifStatement() error {
+	condition, err := octo.condition()
+	if err != nil {
+		return err
+	}
+	switch keyword := octo.next(); keyword {
+	case "then":
+		octo.emitCondition(condition, false)
+	case "begin":
+		octo.emitCondition(condition, true)
+		octo.branches = append(octo.branches, octo.here)
+		octo.emit(0x1000)
+	default:
+		return fmt.Errorf("expected then or begin, found %q", keyword)
+	}
+	return nil
+}
+
+func (octo *octoAssembler) defineMacro() error {
+	name := octo.next()
+	macro := &octoMacro{}
+	for {
+		token := octo.next()
+		if token == "{" {
+			break
+		}
+		if token == "" {
+			return fmt.Errorf("unterminated macro %q", name)
+		}
+		macro.arguments = append(macro.arguments, token)
+	}
+	for depth := 1; ; {
+		if octo.position >= len(octo.tokens) {
+			return fmt.Errorf("unterminated macro %q", name)
+		}
+		token := octo.tokens[octo.position]
+		octo.position++
+		switch token.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		}
+		if depth == 0 {
+			break
+		}
+		macro.body = append(macro.body, token)
+	}
+	octo.macros[name] = macro
+	return nil
+}
+
+// expand replaces the macro invocation with the body of the macro, its arguments substituted.
+func (octo *octoAssembler) expand(macro *octoMacro) error {
+	substitutions := map[string]string{"CALLS": strconv.Itoa(macro.calls)}
+	macro.calls++
+	for _, argument := range macro.arguments {
+		value := octo.next()
+		if value == "" {
+			return fmt.Errorf("missing macro argument %q", argument)
+		}
+		substitutions[argument] = value
+	}
+	body := make([]octoToken, len(macro.body))
+	for index, token := range macro.body {
+		if substitution, exists := substitutions[token.text]; exists {
+			token.text = substitution
+		}
+		body[index] = token
+	}
+	octo.tokens = append(octo.tokens[:octo.position], append(body, octo.tokens[octo.position:]...)...)
+	return nil
+}
+
+// calc evaluates the expression up to the closing brace. Like in Octo, binary operators have no
+// precedence and are evaluated right to left.
+func (octo *octoAssembler) calc() (float64, error) {
+	value, err := octo.expression()
+	if err != nil {
+		return 0, err
+	}
+	return value, octo.expect("}")
+}
+
+func (octo *octoAssembler) expression() (float64, error) {
+	left, err := octo.term()
+	if err != nil {
+		return 0, err
+	}
+	operator := octo.peek()
+	binary, exists := octoBinaryOperators[operator]
+	if !exists {
+		return left, nil
+	}
+	octo.next()
+	right, err := octo.expression()
+	if err != nil {
+		return 0, err
+	}
+	return binary(left, right), nil
+}
+
+func (octo *octoAssembler) term() (float64, error) {
+	token := octo.next()
+	if unary, exists := octoUnaryOperators[token]; exists {
+		value, err := octo.term()
+		return unary(value), err
+	}
+	switch token {
+	case "(":
+		value, err := octo.expression()
+		if err != nil {
+			return 0, err
+		}
+		return value, octo.expect(")")
+	case "HERE":
+		return float64(octo.here), nil
+	case "PI":
+		return math.Pi, nil
+	case "E":
+		return math.E, nil
+	case "@":
+		address, err := octo.term()
+		if err != nil || int(address) < 0 || int(address) >= len(octo.memory) {
+			return 0, fmt.Errorf("invalid address in calc expression")
+		}
+		return float64(octo.memory[int(address)]), nil
+	}
+	if register, ok := octo.register(token); ok {
+		return float64(register), nil
+	}
+	if number, ok := parseOctoNumber(token); ok {
+		return float64(number), nil
+	}
+	if number, err := strconv.ParseFloat(token, 64); err == nil {
+		return number, nil
+	}
+	if constant, exists := octo.constants[token]; exists {
+		return constant, nil
+	}
+	if address, exists := octo.labels[token]; exists {
+		return float64(address), nil
+	}
+	return 0, fmt.Errorf("undefined name %q in calc expression", token)
+}
+
+func octoBool(condition bool) float64 {
+	if condition {
+		return 1
+	}
+	return 0
+}
+
+var octoBinaryOperators = map[string]func(left, right float64) float64{
+	"+":   func(left, right float64) float64 { return left + right },
+	"-":   func(left, right float64) float64 { return left - right },
+	"*":   func(left, right float64) float64 { return left * right },
+	"/":   func(left, right float64) float64 { return left / right },
+	"%":   func(left, right float64) float64 { return float64(int(left) % max(int(right), 1)) },
+	"&":   func(left, right float64) float64 { return float64(int(left) & int(right)) },
+	"|":   func(left, right float64) float64 { return float64(int(left) | int(right)) },
+	"^":   func(left, right float64) float64 { return float64(int(left) ^ int(right)) },
+	"<<":  func(left, right float64) float64 { return float64(int(left) << uint(right)) },
+	">>":  func(left, right float64) float64 { return float64(int(left) >> uint(right)) },
+	"pow": math.Pow,
+	"min": math.Min,
+	"max": math.Max,
+	"<":   func(left, right float64) float64 { return octoBool(left < right) },
+	">":   func(left, right float64) float64 { return octoBool(left > right) },
+	"<=":  func(left, right float64) float64 { return octoBool(left <= right) },
+	">=":  func(left, right float64) float64 { return octoBool(left >= right) },
+	"==":  func(left, right float64) float64 { return octoBool(left == right) },
+	"!=":  func(left, right float64) float64 { return octoBool(left != right) },
+}
+
+var octoUnaryOperators = map[string]func(value float64) float64{
+	"-":     func(value float64) float64 { return -value },
+	"~":     func(value float64) float64 { return float64(^int(value)) },
+	"!":     func(value float64) float64 { return octoBool(value == 0) },
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"exp":   math.Exp,
+	"log":   math.Log,
+	"abs":   math.Abs,
+	"sqrt":  math.Sqrt,
+	"ceil":  math.Ceil,
+	"floor": math.Floor,
+	"sign": func(value float64) float64 {
+		return octoBool(value > 0) - octoBool(value < 0)
+	},
+}
@@ -0,0 +1,99 @@
+package chip8
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAssembleOcto(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Source   string
+		Expected []byte
+	}{
+		{"registers", "v0 := 5 v1 += 2 v2 -= 1 v3 := v4 v5 ^= v6 v7 <<= v8",
+			[]byte{0x60, 0x05, 0x71, 0x02, 0x72, 0xFF, 0x83, 0x40, 0x85, 0x63, 0x87, 0x8E}},
+		{"keys and timers", "v0 := key v1 := delay delay := v2 buzzer := v3 v4 := random 0x0F",
+			[]byte{0xF0, 0x0A, 0xF1, 0x07, 0xF2, 0x15, 0xF3, 0x18, 0xC4, 0x0F}},
+		{"index", "i := 0x123 i += v1 i := hex v2 i := bighex v3 bcd v4 save v5 load v6",
+			[]byte{0xA1, 0x23, 0xF1, 0x1E, 0xF2, 0x29, 0xF3, 0x30, 0xF4, 0x33, 0xF5, 0x55, 0xF6, 0x65}},
+		{"labels", ": main jump main : sub return sub",
+			[]byte{0x12, 0x00, 0x00, 0xEE, 0x22, 0x02}},
+		{"forward reference", "jump later clear : later ;",
+			[]byte{0x12, 0x04, 0x00, 0xE0, 0x00, 0xEE}},
+		{"if then", "if v0 == 3 then clear if v1 != v2 then clear",
+			[]byte{0x40, 0x03, 0x00, 0xE0, 0x51, 0x20, 0x00, 0xE0}},
+		{"if begin else end", "if v0 key begin v1 := 1 else v1 := 2 end",
+			[]byte{0xE0, 0x9E, 0x12, 0x08, 0x61, 0x01, 0x12, 0x0A, 0x61, 0x02}},
+		{"comparison", "if v1 < 5 then clear",
+			[]byte{0x6F, 0x05, 0x8F, 0x17, 0x4F, 0x00, 0x00, 0xE0}},
+		{"loop while again", "loop v0 += 1 while v0 != 10 again",
+			[]byte{0x70, 0x01, 0x40, 0x0A, 0x12, 0x08, 0x12, 0x00}},
+		{"constants and calc", ":const SIZE 4 :calc DOUBLE { SIZE * 2 } v0 := SIZE v1 := DOUBLE",
+			[]byte{0x60, 0x04, 0x61, 0x08}},
+		{"aliases", ":alias x v3 x := 7", []byte{0x63, 0x07}},
+		{"macros", ":macro twice register { register += 1 register += 1 } twice v2",
+			[]byte{0x72, 0x01, 0x72, 0x01}},
+		{"data", "i := shape sprite v0 v1 2 : shape 0xF0 0b10010000",
+			[]byte{0xA2, 0x04, 0xD0, 0x12, 0xF0, 0x90}},
+		{"org and pointer", ":org 0x204 :pointer here : here", []byte{0x00, 0x00, 0x00, 0x00, 0x02, 0x06}},
+		{"unpack", ":unpack 0xA target : target", []byte{0x60, 0xA2, 0x61, 0x04}},
+		{"super-chip", "hires lores scroll-down 3 scroll-left scroll-right exit saveflags v7 loadflags v7 sprite v0 v1 0",
+			[]byte{0x00, 0xFF, 0x00, 0xFE, 0x00, 0xC3, 0x00, 0xFC, 0x00, 0xFB, 0x00, 0xFD, 0xF7, 0x75, 0xF7, 0x85, 0xD0, 0x10}},
+		{"comments", "# a comment\nclear # another\n", []byte{0x00, 0xE0}},
+	}
+	for _, assembleCase := range cases {
+		t.Run(assembleCase.Name, func(t *testing.T) {
+			program, err := AssembleOcto(assembleCase.Source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(program.Data, assembleCase.Expected) {
+				t.Errorf("assembled % X, want % X", program.Data, assembleCase.Expected)
+			}
+		})
+	}
+}
+
+func TestAssembleOctoErrors(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Source string
+		Error  string
+	}{
+		{"undefined label", "jump nowhere", `undefined name "nowhere"`},
+		{"label defined twice", ": a : a", `label "a" is defined twice`},
+		{"missing end", "if v0 == 1 begin clear", "missing end"},
+		{"missing again", "loop clear", "missing again"},
+		{"else without if", "else", "else without if"},
+		{"failed assertion", ":assert \"never\" { 1 == 2 }", "assertion failed"},
+		{"stringmode", ":stringmode", ":stringmode is not supported"},
+		{"unknown operator", "v0 ?= v1", `unknown operator "?="`},
+	}
+	for _, errorCase := range cases {
+		t.Run(errorCase.Name, func(t *testing.T) {
+			_, err := AssembleOcto(errorCase.Source)
+			if err == nil || !strings.Contains(err.Error(), errorCase.Error) {
+				t.Errorf("got error %v, want one containing %q", err, errorCase.Error)
+			}
+		})
+	}
+}
+
+func TestOctoProgramLines(t *testing.T) {
+	program, err := AssembleOcto(": main\n  clear\n\n  v0 := 1\n: forever\n  jump forever\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if program.Labels["main"] != 0x200 || program.Labels["forever"] != 0x204 {
+		t.Errorf("labels are %v, want main at 0200 and forever at 0204", program.Labels)
+	}
+	if symbol := program.Symbol(0x202); symbol != "main+2" {
+		t.Errorf("0202 is named %q, want main+2", symbol)
+	}
+	address, line, found := program.LineAddress(3)
+	if !found || address != 0x202 || line != 4 {
+		t.Errorf("line 3 is at %04X on line %d (found %t), want 0202 on line 4", address, line, found)
+	}
+}
@@ -1,8 +1,14 @@
-package main
+package chip8
 
-type OpcodeDecoder struct {
+// InstructionDecoder turns the opcodes of a platform into instructions. Every platform has its own: the variants
+// decode the opcodes they add or change and leave the others to the decoder of the platform they extend.
+type InstructionDecoder interface {
+	Decode(opcode uint16) Instruction
 }
 
+// OpcodeDecoder decodes the instructions of the original CHIP-8, which the other decoders fall back to.
+type OpcodeDecoder struct{}
+
 func NewOpcodeDecoder() *OpcodeDecoder {
 	return &OpcodeDecoder{}
 }
@@ -101,3 +107,15 @@ func (opcodeDecoder *OpcodeDecoder) Decode(opcode uint16) Instruction {
 		return &UnknownInstruction{GenericInstruction{opcode}}
 	}
 }
+
+// HiResDecoder decodes the instructions of the hi-res CHIP-8, where 0230 clears the 64x64 display.
+type HiResDecoder struct {
+	OpcodeDecoder
+}
+
+func (hiResDecoder *HiResDecoder) Decode(opcode uint16) Instruction {
+	if opcode == 0x0230 {
+		return &ClearScreen{GenericInstruction{opcode}}
+	}
+	return hiResDecoder.OpcodeDecoder.Decode(opcode)
+}
@@ -0,0 +1,63 @@
+package chip8
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestPlatformDecoders checks that every platform decodes the opcodes it changes its own way and leaves the
+// others to CHIP-8.
+func TestPlatformDecoders(t *testing.T) {
+	cases := []struct {
+		Platform string
+		Opcode   uint16
+		Expected Instruction
+	}{
+		{"chip8", 0x00E0, &ClearScreen{}},
+		{"chip8", 0x0230, &UnknownInstruction{}},
+		{"chip8", 0x02A0, &UnknownInstruction{}},
+		{"chip8", 0x5121, &SkipIfVxVyEqual{}},
+		{"chip8", 0xB120, &JumpToAddressPlusV0{}},
+		{"chip8", 0xE1F2, &UnknownInstruction{}},
+		{"chip8", 0xF1F8, &UnknownInstruction{}},
+
+		{"vip", 0x0123, &CallMachineCode{}},
+		{"vip", 0x00E0, &ClearScreen{}},
+		{"vip", 0x00EE, &ReturnFromSubroutine{}},
+
+		{"hires", 0x0230, &ClearScreen{}},
+		{"hires", 0x00E0, &ClearScreen{}},
+		{"hires", 0x0231, &UnknownInstruction{}},
+		{"hires", 0x1260, &JumpToAddress{}},
+
+		{"chip8x", 0x02A0, &CycleBackground{}},
+		{"chip8x", 0x5121, &AddVyToVxNibbles{}},
+		{"chip8x", 0x5120, &SkipIfVxVyEqual{}},
+		{"chip8x", 0xB120, &SetColorZones{}},
+		{"chip8x", 0xB123, &SetColorRows{}},
+		{"chip8x", 0xE1F2, &SkipIfSecondKeyPressed{}},
+		{"chip8x", 0xE1F5, &SkipIfSecondKeyNotPressed{}},
+		{"chip8x", 0xE19E, &SkipIfKeyPressed{}},
+		{"chip8x", 0xF1F8, &OutputToPort{}},
+		{"chip8x", 0xF1FB, &InputFromPort{}},
+		{"chip8x", 0x0230, &UnknownInstruction{}},
+
+		{"megachip", 0x0011, &EnableMegaChip{}},
+		{"megachip", 0x02A0, &LoadPalette{}},
+		{"megachip", 0xB120, &JumpToAddressPlusV0{}},
+		{"megachip", 0x00B2, &ScrollUp{}},
+		{"megachip", 0x00C2, &ScrollDown{}},
+		{"megachip", 0x00FD, &ExitInterpreter{}},
+		{"megachip", 0xD120, &DrawLargeSprite{}},
+		{"megachip", 0xF175, &StoreFlags{}},
+		{"megachip", 0xF185, &LoadFlags{}},
+		{"schip", 0x00B2, &UnknownInstruction{}},
+	}
+	for _, decodeCase := range cases {
+		platform := Platforms[decodeCase.Platform]
+		instruction := platform.Decoder.Decode(decodeCase.Opcode)
+		if fmt.Sprintf("%T", instruction) != fmt.Sprintf("%T", decodeCase.Expected) {
+			t.Errorf("%s decodes %04X to %T, want %T", decodeCase.Platform, decodeCase.Opcode, instruction, decodeCase.Expected)
+		}
+	}
+}
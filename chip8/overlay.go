@@ -0,0 +1,30 @@
+package chip8
+
+import "image/color"
+
+// OverlayItem is a filled rectangle, or a line of text when Text is set.
+type OverlayItem struct {
+	X, Y          int
+	Width, Height int
+	Text          string
+	Color         color.RGBA
+}
+
+// Overlay holds what scripts draw over the screen, until they clear it. Coordinates are in pixels of the
+// CHIP-8 screen; text is drawn with the bitmap font, two font pixels per screen pixel. Frontends draw
+// what they can of it.
+type Overlay struct {
+	Items []OverlayItem
+}
+
+func (overlay *Overlay) Clear() {
+	overlay.Items = overlay.Items[:0]
+}
+
+func (overlay *Overlay) AddRectangle(positionX int, positionY int, width int, height int, fill color.RGBA) {
+	overlay.Items = append(overlay.Items, OverlayItem{X: positionX, Y: positionY, Width: width, Height: height, Color: fill})
+}
+
+func (overlay *Overlay) AddText(positionX int, positionY int, text string, fill color.RGBA) {
+	overlay.Items = append(overlay.Items, OverlayItem{X: positionX, Y: positionY, Text: text, Color: fill})
+}
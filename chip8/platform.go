@@ -0,0 +1,153 @@
+package chip8
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Platform describes a CHIP-8 variant: its memory map and the quirks its programs expect.
+type Platform struct {
+	Name        string             // Name identifies the platform in the ROM database and on the command line.
+	Description string             // Description is the human readable name of the platform.
+	Extensions  []string           // Extensions are the file extensions of its programs.
+	MemorySize  int                // MemorySize is the size of the addressable Memory, in bytes.
+	LoadAddress uint16             // LoadAddress is where programs are loaded and start executing.
+	Quirks      Quirks             // Quirks are the behaviours its interpreter had.
+	Decoder     InstructionDecoder // Decoder decodes the instructions of its programs.
+	Chip8X      bool               // Chip8X gives the core the color board and the second keypad of CHIP-8X.
+	Unsupported bool               // Unsupported platforms are only recognized: their instructions are not emulated and their programs are refused.
+
+	EntryPoint   uint16 // EntryPoint is where the execution starts when it is not LoadAddress.
+	ScreenWidth  int    // ScreenWidth is the width of the display at power on, 64 when zero.
+	ScreenHeight int    // ScreenHeight is the height of the display at power on, 32 when zero.
+}
+
+// Platforms lists the supported platforms by name.
+var Platforms = map[string]*Platform{
+	"chip8": {
+		Name:        "chip8",
+		Description: "CHIP-8",
+		Extensions:  []string{".ch8", ".c8"},
+		MemorySize:  4096,
+		LoadAddress: 0x200,
+		Quirks:      DefaultQuirks,
+		Decoder:     &OpcodeDecoder{},
+	},
+	"vip": {
+		Name:        "vip",
+		Description: "CHIP-8 on the COSMAC VIP",
+		MemorySize:  4096,
+		LoadAddress: 0x200,
+		Quirks:      Quirks{Logic: true, Clip: true, VBlank: true},
+		Decoder:     &VIPDecoder{},
+	},
+	"hires": {
+		Name:         "hires",
+		Description:  "hi-res CHIP-8",
+		MemorySize:   4096,
+		LoadAddress:  0x200,
+		Quirks:       Quirks{Logic: true, Clip: true, VBlank: true},
+		Decoder:      &HiResDecoder{},
+		EntryPoint:   0x2C0,
+		ScreenWidth:  64,
+		ScreenHeight: 64,
+	},
+	"chip8x": {
+		Name:        "chip8x",
+		Description: "CHIP-8X",
+		Extensions:  []string{".c8x"},
+		MemorySize:  4096,
+		LoadAddress: 0x300,
+		Quirks:      Quirks{Logic: true, Clip: true, VBlank: true},
+		Decoder:     &Chip8XDecoder{},
+		Chip8X:      true,
+	},
+	"schip": {
+		Name:        "schip",
+		Description: "SUPER-CHIP",
+		Extensions:  []string{".sc8"},
+		MemorySize:  4096,
+		LoadAddress: 0x200,
+		Quirks:      Quirks{Shift: true, LoadStore: true, Jump: true, Clip: true},
+		Decoder:     &SuperChipDecoder{},
+	},
+	"xochip": {
+		Name:        "xochip",
+		Description: "XO-CHIP",
+		Extensions:  []string{".xo8"},
+		MemorySize:  65536,
+		LoadAddress: 0x200,
+		Quirks:      Quirks{},
+		Decoder:     &OpcodeDecoder{},
+		Unsupported: true,
+	},
+	"megachip": {
+		Name:        "megachip",
+		Description: "MegaChip-8",
+		Extensions:  []string{".mc8"},
+		MemorySize:  MegaChipMemorySize,
+		LoadAddress: 0x200,
+		Quirks:      Quirks{Shift: true, LoadStore: true, Jump: true, Clip: true},
+		Decoder:     &MegaChipDecoder{},
+	},
+}
+
+// DefaultPlatform is used when neither the file nor the ROM database tell which platform a program is for.
+var DefaultPlatform = Platforms["chip8"]
+
+// LookupPlatform returns the platform with the given name.
+func LookupPlatform(name string) (*Platform, error) {
+	platform, exists := Platforms[name]
+	if !exists {
+		names := make([]string, 0, len(Platforms))
+		for name := range Platforms {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown platform %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return platform, nil
+}
+
+// PlatformForFile picks the platform from the extension of the file name, nil when the extension is not specific.
+func PlatformForFile(name string) *Platform {
+	extension := strings.ToLower(filepath.Ext(name))
+	for _, platform := range Platforms {
+		for _, platformExtension := range platform.Extensions {
+			if extension == platformExtension {
+				return platform
+			}
+		}
+	}
+	return nil
+}
+
+// MaxROMSize is the largest program fitting between the load address and the end of Memory.
+func (platform *Platform) MaxROMSize() int {
+	return platform.MemorySize - int(platform.LoadAddress)
+}
+
+// Resolution returns the size of the display at power on.
+func (platform *Platform) Resolution() (int, int) {
+	width, height := platform.ScreenWidth, platform.ScreenHeight
+	if width == 0 || height == 0 {
+		width, height = 64, 32
+	}
+	return width, height
+}
+
+// Validate checks that the platform runs programs and that a program of the given size fits in its memory map.
+func (platform *Platform) Validate(size int) error {
+	if platform.Unsupported {
+		return fmt.Errorf("%s programs are not supported: its instructions are not emulated", platform.Description)
+	}
+	if size == 0 {
+		return fmt.Errorf("empty program")
+	}
+	if size > platform.MaxROMSize() {
+		return fmt.Errorf("program of %d bytes does not fit in the %d bytes available on %s", size, platform.MaxROMSize(), platform.Description)
+	}
+	return nil
+}
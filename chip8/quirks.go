@@ -0,0 +1,15 @@
+package chip8
+
+// Quirks selects between the behaviours that differ among CHIP-8 interpreters.
+// The JSON names are the ones Octo uses for its options.
+type Quirks struct {
+	Shift     bool `json:"shiftQuirks"`     // Shift makes 8XY6 and 8XYE shift VX in place instead of shifting VY into VX.
+	LoadStore bool `json:"loadStoreQuirks"` // LoadStore leaves I untouched by FX55 and FX65.
+	Jump      bool `json:"jumpQuirks"`      // Jump makes BNNN jump to NNN plus VX, X being the high nibble of NNN, instead of NNN plus V0.
+	Logic     bool `json:"logicQuirks"`     // Logic resets VF after 8XY1, 8XY2 and 8XY3.
+	Clip      bool `json:"clipQuirks"`      // Clip cuts sprites at the screen edges instead of wrapping them around.
+	VBlank    bool `json:"vBlankQuirks"`    // VBlank makes DXYN end the frame, limiting drawing to 60 sprites per second.
+}
+
+// DefaultQuirks is the behaviour of this emulator when nothing else is known about the program.
+var DefaultQuirks = Quirks{Shift: true}
@@ -0,0 +1,278 @@
+package chip8
+
+// RCA1802 emulates the CPU of the COSMAC VIP, to run the machine code subroutines CHIP-8 programs call with
+// 0NNN. It has sixteen 16-bit registers, any of which can be the program counter (selected by P) or the index
+// register (selected by X), an 8-bit accumulator D with its carry DF, and the Q output. The memory and the
+// I/O lines are provided by the host through the callbacks.
+//
+// Interrupts are not raised by the emulation: machine code only runs between two CHIP-8 instructions, while
+// the interpreter serves the interrupts of the VIP itself.
+type RCA1802 struct {
+	R      [16]uint16 // R are the scratchpad registers R0 to RF.
+	P      uint8      // P selects the register used as program counter.
+	X      uint8      // X selects the register used as memory index.
+	D      uint8      // D is the accumulator.
+	DF     uint8      // DF is the carry of D, 0 or 1; subtractions set it when there is no borrow.
+	T      uint8      // T holds X and P saved by MARK or an interrupt.
+	IE     bool       // IE enables the interrupts.
+	Q      bool       // Q is the output flip-flop, driving the tone on the VIP.
+	Cycles uint64     // Cycles counts the machine cycles executed, of 8 clock cycles each.
+
+	Read   func(address uint16) byte        // Read loads a byte from memory.
+	Write  func(address uint16, value byte) // Write stores a byte to memory.
+	Input  func(port uint8) byte            // Input reads the data bus for INP 1 to INP 7.
+	Output func(port uint8, value byte)     // Output drives the data bus for OUT 1 to OUT 7.
+	Flag   func(flag uint8) bool            // Flag reads the external flag lines EF1 to EF4.
+
+	idleUntil uint64
+}
+
+// IdleCycles is the period IDL waits for: the next DMA request, which the VIP display raises every frame.
+const IdleCycles = VIPFrameCycles
+
+// Step executes one instruction and returns the machine cycles it took: 2, or 3 for the long branches and skips.
+func (cpu *RCA1802) Step() int {
+	if cpu.Cycles < cpu.idleUntil {
+		cpu.Cycles += 2
+		return 2
+	}
+	opcode := cpu.immediate()
+	low := opcode & 0x0F
+	cycles := 2
+	switch opcode >> 4 {
+	case 0x0:
+		if low == 0 {
+			// IDL waits for an interrupt or a DMA cycle, at the latest the display DMA of the next frame.
+			cpu.idleUntil = (cpu.Cycles/IdleCycles + 1) * IdleCycles
+		} else {
+			cpu.D = cpu.Read(cpu.R[low])
+		}
+	case 0x1:
+		cpu.R[low]++
+	case 0x2:
+		cpu.R[low]--
+	case 0x3:
+		cpu.shortBranch(cpu.condition(low))
+	case 0x4:
+		cpu.D = cpu.Read(cpu.R[low])
+		cpu.R[low]++
+	case 0x5:
+		cpu.Write(cpu.R[low], cpu.D)
+	case 0x6:
+		cpu.inputOutput(low)
+	case 0x7:
+		cpu.execute7(low)
+	case 0x8:
+		cpu.D = uint8(cpu.R[low])
+	case 0x9:
+		cpu.D = uint8(cpu.R[low] >> 8)
+	case 0xA:
+		cpu.R[low] = cpu.R[low]&0xFF00 | uint16(cpu.D)
+	case 0xB:
+		cpu.R[low] = cpu.R[low]&0x00FF | uint16(cpu.D)<<8
+	case 0xC:
+		cpu.long(low)
+		cycles = 3
+	case 0xD:
+		cpu.P = low
+	case 0xE:
+		cpu.X = low
+	case 0xF:
+		cpu.executeF(low)
+	}
+	cpu.Cycles += uint64(cycles)
+	return cycles
+}
+
+// immediate reads the byte at the program counter and moves past it.
+func (cpu *RCA1802) immediate() uint8 {
+	value := cpu.Read(cpu.R[cpu.P])
+	cpu.R[cpu.P]++
+	return value
+}
+
+// condition evaluates the condition of a short branch: always, Q, D zero, DF and EF1 to EF4, negated from 8 on.
+func (cpu *RCA1802) condition(code uint8) bool {
+	var result bool
+	switch code & 0x7 {
+	case 0:
+		result = true
+	case 1:
+		result = cpu.Q
+	case 2:
+		result = cpu.D == 0
+	case 3:
+		result = cpu.DF == 1
+	default:
+		result = cpu.Flag != nil && cpu.Flag(code&0x7-3)
+	}
+	return result != (code >= 8)
+}
+
+// shortBranch replaces the low byte of the program counter with the byte that follows when taken, and skips it otherwise.
+func (cpu *RCA1802) shortBranch(taken bool) {
+	if taken {
+		cpu.R[cpu.P] = cpu.R[cpu.P]&0xFF00 | uint16(cpu.Read(cpu.R[cpu.P]))
+	} else {
+		cpu.R[cpu.P]++
+	}
+}
+
+// long executes the CN long branches, which load the program counter from the two bytes that follow when
+// taken, and the long skips, which jump over these two bytes when taken.
+func (cpu *RCA1802) long(code uint8) {
+	var taken bool
+	switch code {
+	case 0x0, 0x1, 0x2, 0x3:
+		taken = cpu.condition(code)
+	case 0x9, 0xA, 0xB:
+		taken = !cpu.condition(code & 0x3)
+	case 0x4:
+		// NOP
+		return
+	default:
+		var condition bool
+		switch code {
+		case 0x5, 0xD:
+			condition = cpu.Q
+		case 0x6, 0xE:
+			condition = cpu.D == 0
+		case 0x7, 0xF:
+			condition = cpu.DF == 1
+		case 0xC:
+			condition = cpu.IE
+		}
+		// C5 to C8 (LSNQ, LSNZ, LSNF and LSKP) skip when their condition is false, CC to CF when it is true.
+		if condition != (code < 0xC) {
+			cpu.R[cpu.P] += 2
+		}
+		return
+	}
+	if taken {
+		cpu.R[cpu.P] = uint16(cpu.Read(cpu.R[cpu.P]))<<8 | uint16(cpu.Read(cpu.R[cpu.P]+1))
+	} else {
+		cpu.R[cpu.P] += 2
+	}
+}
+
+// inputOutput executes 60 IRX, 61 to 67 OUT N and 69 to 6F INP N.
+func (cpu *RCA1802) inputOutput(code uint8) {
+	switch {
+	case code == 0:
+		cpu.R[cpu.X]++
+	case code < 8:
+		value := cpu.Read(cpu.R[cpu.X])
+		cpu.R[cpu.X]++
+		if cpu.Output != nil {
+			cpu.Output(code, value)
+		}
+	case code > 8:
+		cpu.D = 0
+		if cpu.Input != nil {
+			cpu.D = cpu.Input(code - 8)
+		}
+		cpu.Write(cpu.R[cpu.X], cpu.D)
+	}
+}
+
+func (cpu *RCA1802) execute7(code uint8) {
+	switch code {
+	case 0x0, 0x1:
+		// RET and DIS restore X and P from memory and enable or disable the interrupts.
+		value := cpu.Read(cpu.R[cpu.X])
+		cpu.R[cpu.X]++
+		cpu.X, cpu.P = value>>4, value&0x0F
+		cpu.IE = code == 0x0
+	case 0x2:
+		cpu.D = cpu.Read(cpu.R[cpu.X])
+		cpu.R[cpu.X]++
+	case 0x3:
+		cpu.Write(cpu.R[cpu.X], cpu.D)
+		cpu.R[cpu.X]--
+	case 0x4:
+		cpu.add(cpu.Read(cpu.R[cpu.X]), cpu.DF)
+	case 0x5:
+		cpu.subtract(cpu.Read(cpu.R[cpu.X]), cpu.D, cpu.DF)
+	case 0x6:
+		carry := cpu.D & 1
+		cpu.D = cpu.D>>1 | cpu.DF<<7
+		cpu.DF = carry
+	case 0x7:
+		cpu.subtract(cpu.D, cpu.Read(cpu.R[cpu.X]), cpu.DF)
+	case 0x8:
+		cpu.Write(cpu.R[cpu.X], cpu.T)
+	case 0x9:
+		// MARK saves X and P on the stack pointed by R2 and makes the program counter the index register.
+		cpu.T = cpu.X<<4 | cpu.P
+		cpu.Write(cpu.R[2], cpu.T)
+		cpu.X = cpu.P
+		cpu.R[2]--
+	case 0xA:
+		cpu.Q = false
+	case 0xB:
+		cpu.Q = true
+	case 0xC:
+		cpu.add(cpu.immediate(), cpu.DF)
+	case 0xD:
+		cpu.subtract(cpu.immediate(), cpu.D, cpu.DF)
+	case 0xE:
+		carry := cpu.D >> 7
+		cpu.D = cpu.D<<1 | cpu.DF
+		cpu.DF = carry
+	case 0xF:
+		cpu.subtract(cpu.D, cpu.immediate(), cpu.DF)
+	}
+}
+
+func (cpu *RCA1802) executeF(code uint8) {
+	switch code {
+	case 0x6:
+		cpu.DF = cpu.D & 1
+		cpu.D >>= 1
+		return
+	case 0xE:
+		cpu.DF = cpu.D >> 7
+		cpu.D <<= 1
+		return
+	}
+	// F0 to F7 operate on the byte at RX, F8 to FF on the byte following the instruction.
+	var operand uint8
+	if code >= 8 {
+		operand = cpu.immediate()
+	} else {
+		operand = cpu.Read(cpu.R[cpu.X])
+	}
+	switch code & 0x7 {
+	case 0x0:
+		cpu.D = operand
+	case 0x1:
+		cpu.D |= operand
+	case 0x2:
+		cpu.D &= operand
+	case 0x3:
+		cpu.D ^= operand
+	case 0x4:
+		cpu.add(operand, 0)
+	case 0x5:
+		cpu.subtract(operand, cpu.D, 1)
+	case 0x7:
+		cpu.subtract(cpu.D, operand, 1)
+	}
+}
+
+// add sets D to D plus value plus carry, and DF to the carry out.
+func (cpu *RCA1802) add(value uint8, carry uint8) {
+	sum := int(cpu.D) + int(value) + int(carry)
+	cpu.D = uint8(sum)
+	cpu.DF = uint8(sum >> 8)
+}
+
+// subtract sets D to minuend minus subtrahend, minus 1 when notBorrow is 0, and DF to 1 when no borrow occurred.
+func (cpu *RCA1802) subtract(minuend uint8, subtrahend uint8, notBorrow uint8) {
+	difference := int(minuend) - int(subtrahend) - int(1-notBorrow)
+	cpu.D = uint8(difference)
+	cpu.DF = 0
+	if difference >= 0 {
+		cpu.DF = 1
+	}
+}
@@ -0,0 +1,184 @@
+package chip8
+
+import "testing"
+
+// newTestCPU returns a CPU with 64 KiB of memory holding program at 0x100, R3 its program counter and R2 its
+// index register pointing at 0x1F0, which holds operand.
+func newTestCPU(program []byte, operand byte) (*RCA1802, []byte) {
+	memory := make([]byte, 0x10000)
+	copy(memory[0x100:], program)
+	memory[0x1F0] = operand
+	cpu := &RCA1802{
+		Read:  func(address uint16) byte { return memory[address] },
+		Write: func(address uint16, value byte) { memory[address] = value },
+	}
+	cpu.P, cpu.X = 3, 2
+	cpu.R[3], cpu.R[2] = 0x100, 0x1F0
+	return cpu, memory
+}
+
+func TestRCA1802Arithmetic(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Program    []byte
+		D          uint8
+		DF         uint8
+		Operand    uint8 // Operand is the byte at RX.
+		ExpectedD  uint8
+		ExpectedDF uint8
+	}{
+		{"ADD", []byte{0xF4}, 0x01, 1, 0x02, 0x03, 0},
+		{"ADD carry", []byte{0xF4}, 0x80, 0, 0x90, 0x10, 1},
+		{"ADC", []byte{0x74}, 0xFF, 1, 0x00, 0x00, 1},
+		{"ADI", []byte{0xFC, 0x01}, 0xFF, 0, 0, 0x00, 1},
+		{"ADCI", []byte{0x7C, 0x01}, 0x01, 1, 0, 0x03, 0},
+		{"SD", []byte{0xF5}, 0x03, 0, 0x05, 0x02, 1},
+		{"SD borrow", []byte{0xF5}, 0x05, 1, 0x03, 0xFE, 0},
+		{"SDB", []byte{0x75}, 0x03, 0, 0x05, 0x01, 1},
+		{"SDI", []byte{0xFD, 0x10}, 0x01, 0, 0, 0x0F, 1},
+		{"SM", []byte{0xF7}, 0x03, 1, 0x05, 0xFE, 0},
+		{"SMB", []byte{0x77}, 0x05, 0, 0x05, 0xFF, 0},
+		{"SMI", []byte{0xFF, 0x01}, 0x00, 1, 0, 0xFF, 0},
+		{"SMBI", []byte{0x7F, 0x02}, 0x05, 0, 0, 0x02, 1},
+		{"SHR", []byte{0xF6}, 0x03, 0, 0, 0x01, 1},
+		{"SHL", []byte{0xFE}, 0x81, 0, 0, 0x02, 1},
+		{"RSHR", []byte{0x76}, 0x02, 1, 0, 0x81, 0},
+		{"RSHL", []byte{0x7E}, 0x80, 1, 0, 0x01, 1},
+		{"OR", []byte{0xF1}, 0x0C, 1, 0x0A, 0x0E, 1},
+		{"AND", []byte{0xF2}, 0x0C, 1, 0x0A, 0x08, 1},
+		{"XOR", []byte{0xF3}, 0x0C, 1, 0x0A, 0x06, 1},
+		{"LDI", []byte{0xF8, 0x42}, 0x00, 0, 0, 0x42, 0},
+		{"LDX", []byte{0xF0}, 0x00, 0, 0x42, 0x42, 0},
+	}
+	for _, arithmeticCase := range cases {
+		cpu, _ := newTestCPU(arithmeticCase.Program, arithmeticCase.Operand)
+		cpu.D, cpu.DF = arithmeticCase.D, arithmeticCase.DF
+		cpu.Step()
+		if cpu.D != arithmeticCase.ExpectedD || cpu.DF != arithmeticCase.ExpectedDF || cpu.R[3] != 0x100+uint16(len(arithmeticCase.Program)) {
+			t.Errorf("%s left D at %02X, DF at %d and R3 at %04X, want %02X, %d and %04X", arithmeticCase.Name, cpu.D, cpu.DF,
+				cpu.R[3], arithmeticCase.ExpectedD, arithmeticCase.ExpectedDF, 0x100+len(arithmeticCase.Program))
+		}
+	}
+}
+
+func TestRCA1802Branches(t *testing.T) {
+	cases := []struct {
+		Name           string
+		Program        []byte
+		Q              bool
+		D              uint8
+		DF             uint8
+		IE             bool
+		ExpectedPC     uint16
+		ExpectedCycles int
+	}{
+		{"BR", []byte{0x30, 0x20}, false, 1, 0, false, 0x120, 2},
+		{"BZ taken", []byte{0x32, 0x20}, false, 0, 0, false, 0x120, 2},
+		{"BZ", []byte{0x32, 0x20}, false, 1, 0, false, 0x102, 2},
+		{"BNZ", []byte{0x3A, 0x20}, false, 1, 0, false, 0x120, 2},
+		{"BDF", []byte{0x33, 0x20}, false, 1, 1, false, 0x120, 2},
+		{"BNF", []byte{0x3B, 0x20}, false, 1, 1, false, 0x102, 2},
+		{"BQ", []byte{0x31, 0x20}, true, 1, 0, false, 0x120, 2},
+		{"BNQ", []byte{0x39, 0x20}, true, 1, 0, false, 0x102, 2},
+		{"B1", []byte{0x34, 0x20}, false, 1, 0, false, 0x120, 2},
+		{"B2", []byte{0x35, 0x20}, false, 1, 0, false, 0x102, 2},
+		{"SKP", []byte{0x38, 0x20}, false, 1, 0, false, 0x102, 2},
+		{"LBR", []byte{0xC0, 0x12, 0x34}, false, 1, 0, false, 0x1234, 3},
+		{"LBQ", []byte{0xC1, 0x12, 0x34}, true, 1, 0, false, 0x1234, 3},
+		{"LBZ", []byte{0xC2, 0x12, 0x34}, false, 1, 0, false, 0x103, 3},
+		{"LBNF", []byte{0xCB, 0x12, 0x34}, false, 1, 0, false, 0x1234, 3},
+		{"LBNZ", []byte{0xCA, 0x12, 0x34}, false, 0, 0, false, 0x103, 3},
+		{"NOP", []byte{0xC4}, false, 1, 0, false, 0x101, 3},
+		{"LSNQ", []byte{0xC5}, false, 1, 0, false, 0x103, 3},
+		{"LSNQ not taken", []byte{0xC5}, true, 1, 0, false, 0x101, 3},
+		{"LSNZ", []byte{0xC6}, false, 1, 0, false, 0x103, 3},
+		{"LSNF", []byte{0xC7}, false, 1, 1, false, 0x101, 3},
+		{"LSKP", []byte{0xC8}, false, 1, 0, false, 0x103, 3},
+		{"LSIE", []byte{0xCC}, false, 1, 0, true, 0x103, 3},
+		{"LSQ", []byte{0xCD}, false, 1, 0, false, 0x101, 3},
+		{"LSZ", []byte{0xCE}, false, 0, 0, false, 0x103, 3},
+		{"LSDF", []byte{0xCF}, false, 1, 1, false, 0x103, 3},
+	}
+	for _, branchCase := range cases {
+		cpu, _ := newTestCPU(branchCase.Program, 0)
+		cpu.Q, cpu.D, cpu.DF, cpu.IE = branchCase.Q, branchCase.D, branchCase.DF, branchCase.IE
+		// EF1 is the only external flag raised.
+		cpu.Flag = func(flag uint8) bool { return flag == 1 }
+		cycles := cpu.Step()
+		if cpu.R[3] != branchCase.ExpectedPC || cycles != branchCase.ExpectedCycles {
+			t.Errorf("%s went to %04X in %d cycles, want %04X in %d", branchCase.Name, cpu.R[3], cycles,
+				branchCase.ExpectedPC, branchCase.ExpectedCycles)
+		}
+	}
+}
+
+// TestRCA1802MarkAndReturn calls a subroutine the way 1802 programs do: MARK saves X and P on the R2 stack before
+// SEP switches the program counter, and RET restores them.
+func TestRCA1802MarkAndReturn(t *testing.T) {
+	// 0100: MARK, SEP R4, SEQ; 0200: SEX R2, INC R2, RET
+	cpu, memory := newTestCPU([]byte{0x79, 0xD4, 0x7B}, 0)
+	copy(memory[0x200:], []byte{0xE2, 0x12, 0x70})
+	cpu.X, cpu.R[4] = 5, 0x200
+
+	cpu.Step()
+	if cpu.T != 0x53 || memory[0x1F0] != 0x53 || cpu.R[2] != 0x1EF || cpu.X != 3 {
+		t.Errorf("MARK left T at %02X, M(01F0) at %02X, R2 at %04X and X at %X, want 53, 53, 01EF and 3",
+			cpu.T, memory[0x1F0], cpu.R[2], cpu.X)
+	}
+	for step := 0; step < 5; step++ {
+		cpu.Step()
+	}
+	if cpu.P != 3 || cpu.X != 5 || cpu.R[2] != 0x1F1 || cpu.R[3] != 0x103 || !cpu.IE || !cpu.Q {
+		t.Errorf("after RET P is %X, X %X, R2 %04X and R3 %04X with IE %t and Q %t, want 3, 5, 01F1 and 0103 with both set",
+			cpu.P, cpu.X, cpu.R[2], cpu.R[3], cpu.IE, cpu.Q)
+	}
+}
+
+// TestRunMachineCode checks that a 0NNN subroutine finds VX through R6 and the CHIP-8 stack through R2, and that
+// PC comes back from R5: this one increments V3 and returns from the CHIP-8 subroutine that called it.
+func TestRunMachineCode(t *testing.T) {
+	// 0200: call 0206, 0202: V0 := 1, 0204: jump 0204, 0206: 0300
+	machine := loadPlatformProgram(t, "vip", []byte{0x22, 0x06, 0x60, 0x01, 0x12, 0x04, 0x03, 0x00})
+	copy(machine.Core.Memory[0x300:], []byte{
+		0x06, 0xFC, 0x01, 0x56, // LDN R6, ADI 1, STR R6: VX += 1
+		0x12, 0x42, 0xB5, 0x02, 0xA5, // INC R2, LDA R2, PHI R5, LDN R2, PLO R5: R5 = the return address
+		0xD4, // SEP R4
+	})
+	machine.Core.V[3] = 41
+
+	machine.Step()
+	machine.Step()
+	if machine.Core.V[3] != 42 || machine.Core.PC != 0x202 || machine.Core.SP != 0 {
+		t.Errorf("the subroutine left V3 at %d, PC at %04X and SP at %d, want 42, 0202 and 0", machine.Core.V[3], machine.Core.PC, machine.Core.SP)
+	}
+	machine.Step()
+	if machine.Core.V[0] != 1 {
+		t.Errorf("V0 is %d after the return, want 1", machine.Core.V[0])
+	}
+}
+
+// TestRunMachineCodeResumes runs a subroutine lasting several slices: PC stays on 0NNN until it returns, each
+// step carrying on where the last one stopped.
+func TestRunMachineCodeResumes(t *testing.T) {
+	machine := loadPlatformProgram(t, "vip", []byte{0x03, 0x00, 0x60, 0x01})
+	copy(machine.Core.Memory[0x300:], []byte{
+		0xF8, 0x00, 0xB9, 0xA9, // LDI 0, PHI R9, PLO R9
+		0x19, 0x99, 0xFB, 0x04, 0x3A, 0x04, // 0304: INC R9, GHI R9, XRI 4, BNZ 0304
+		0xD4, // SEP R4
+	})
+	// 0x400 turns of 4 instructions of 2 cycles.
+	const cycles = 0x400 * 4 * 2
+	steps := 0
+	for machine.Core.PC == 0x200 && steps < 10 {
+		if returned := machine.Core.RunMachineCode(0x300, 0, 0); returned != (machine.Core.PC == 0x202) {
+			t.Fatalf("RunMachineCode returned %t with PC at %04X", returned, machine.Core.PC)
+		}
+		steps++
+	}
+	if expected := cycles/vipMachineCodeSlice + 1; machine.Core.PC != 0x202 || steps != expected {
+		t.Errorf("the subroutine returned to %04X after %d steps, want 0202 after %d", machine.Core.PC, steps, expected)
+	}
+	if spent := machine.Core.takeMachineCodeCycles(); spent < cycles {
+		t.Errorf("the subroutine ran %d cycles, want at least %d", spent, cycles)
+	}
+}
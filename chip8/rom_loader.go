@@ -0,0 +1,205 @@
+package chip8
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/gif"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// ROMImage is a program ready to be loaded, with what its file told about it.
+type ROMImage struct {
+	Name     string       // Name is the file name the program was read from.
+	Data     []byte       // Data is the program itself.
+	Platform *Platform    // Platform is the platform the file asks for, nil when it does not say.
+	Info     *ROMInfo     // Info holds the settings carried by the file, such as the options of an Octo cartridge.
+	Program  *OctoProgram // Program is set when the image was assembled from Octo source, with its labels and lines.
+}
+
+// ReadROMFile reads and decodes a program file; see DecodeROM for the supported formats.
+func ReadROMFile(filePath string) (*ROMImage, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeROM(filepath.Base(filePath), data)
+}
+
+// DecodeROM recognizes the format of a program from its name and content:
+// zip archives holding a program, Octo cartridges (GIF images carrying the source and the options of a
+// program), Octo source files (.8o), and raw programs whose extension (.ch8, .c8, .sc8, .xo8) tells the platform.
+func DecodeROM(name string, data []byte) (*ROMImage, error) {
+	extension := strings.ToLower(filepath.Ext(name))
+	switch {
+	case isZipArchive(name, data):
+		return decodeZipROM(data)
+	case extension == ".gif" || bytes.HasPrefix(data, []byte("GIF8")):
+		return decodeOctoCartridge(name, data)
+	case extension == ".8o":
+		return assembleOctoROM(name, string(data), nil)
+	}
+	return &ROMImage{Name: name, Data: data, Platform: PlatformForFile(name)}, nil
+}
+
+// isZipArchive tells whether a file is a zip archive, from its extension or its signature.
+func isZipArchive(name string, data []byte) bool {
+	return strings.ToLower(filepath.Ext(name)) == ".zip" || bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// decodeZipROM loads the single program of the archive, or the one whose extension is known when there are several files.
+func decodeZipROM(data []byte) (*ROMImage, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var candidates []*zip.File
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		candidates = append(candidates, file)
+	}
+	if len(candidates) > 1 {
+		var known []*zip.File
+		for _, file := range candidates {
+			extension := strings.ToLower(path.Ext(file.Name))
+			if PlatformForFile(file.Name) != nil || extension == ".8o" || extension == ".gif" {
+				known = append(known, file)
+			}
+		}
+		candidates = known
+	}
+	if len(candidates) != 1 {
+		return nil, fmt.Errorf("zip archive must hold exactly one program, found %d", len(candidates))
+	}
+	// The size in the archive is only what the archive claims; the reading is limited too.
+	limit := largestROMSize()
+	if candidates[0].UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%s is larger than the %d bytes of the largest program", candidates[0].Name, limit)
+	}
+	file, err := candidates[0].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	content, err := io.ReadAll(io.LimitReader(file, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > limit {
+		return nil, fmt.Errorf("%s is larger than the %d bytes of the largest program", candidates[0].Name, limit)
+	}
+	// An archive nested in the archive would be decoded in turn, without end for one holding itself.
+	if isZipArchive(candidates[0].Name, content) {
+		return nil, fmt.Errorf("%s is a zip archive inside a zip archive", candidates[0].Name)
+	}
+	return DecodeROM(path.Base(candidates[0].Name), content)
+}
+
+// largestROMSize is the size of the largest program of any platform.
+func largestROMSize() int {
+	largest := 0
+	for _, platform := range Platforms {
+		largest = max(largest, platform.MaxROMSize())
+	}
+	return largest
+}
+
+// octoSuperChipMaxSize is the size of the programs of the SUPER-CHIP preset of Octo, the space the HP48
+// interpreter left them.
+const octoSuperChipMaxSize = 3583
+
+// octoCartridgeMaxPixels is the size of the largest cartridge image decoded, 1 MiB of payload per frame: the size
+// a GIF claims is checked first, as a small file can hold a huge image.
+const octoCartridgeMaxPixels = 1 << 22
+
+// octoCartridgeOptions are the options Octo saves in its cartridges.
+type octoCartridgeOptions struct {
+	Quirks
+	TickRate        int    `json:"tickrate"`
+	FillColor       string `json:"fillColor"`
+	BackgroundColor string `json:"backgroundColor"`
+	MaxSize         int    `json:"maxSize"`
+}
+
+// decodeOctoCartridge extracts the payload Octo hides in the low two bits of the color index of every pixel
+// of every frame, four pixels per byte with the most significant bits first. The payload starts with its
+// length as a 32-bit big-endian number, followed by the JSON of the options and the source of the program.
+func decodeOctoCartridge(name string, data []byte) (*ROMImage, error) {
+	config, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > octoCartridgeMaxPixels {
+		return nil, fmt.Errorf("%s is a %dx%d image, larger than any Octo cartridge", name, config.Width, config.Height)
+	}
+	image, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var payload []byte
+	var current byte
+	count := 0
+	for _, frame := range image.Image {
+		for _, index := range frame.Pix {
+			current = current<<2 | index&0x3
+			count++
+			if count%4 == 0 {
+				payload = append(payload, current)
+			}
+		}
+	}
+	if len(payload) < 4 {
+		return nil, fmt.Errorf("%s is not an Octo cartridge", name)
+	}
+	size := int(payload[0])<<24 | int(payload[1])<<16 | int(payload[2])<<8 | int(payload[3])
+	if size <= 0 || size > len(payload)-4 || !utf8.Valid(payload[4:4+size]) {
+		return nil, fmt.Errorf("%s is not an Octo cartridge", name)
+	}
+	var cartridge struct {
+		Options octoCartridgeOptions `json:"options"`
+		Program string               `json:"program"`
+	}
+	if err := json.Unmarshal(payload[4:4+size], &cartridge); err != nil {
+		return nil, fmt.Errorf("%s: invalid Octo cartridge: %w", name, err)
+	}
+
+	options := cartridge.Options
+	romInfo := &ROMInfo{Quirks: &options.Quirks}
+	if options.TickRate > 0 {
+		romInfo.IPS = options.TickRate * 60
+	}
+	if options.FillColor != "" || options.BackgroundColor != "" {
+		romInfo.Colors = &Colors{Foreground: options.FillColor, Background: options.BackgroundColor}
+	}
+	// Octo has no platform option; its presets tell them apart by the size of the programs they allow.
+	switch {
+	case options.MaxSize == 0:
+	case options.MaxSize == octoSuperChipMaxSize:
+		romInfo.Platform = "schip"
+	case options.MaxSize <= Platforms["chip8"].MaxROMSize():
+		romInfo.Platform = "chip8"
+	default:
+		romInfo.Platform = "xochip"
+	}
+	return assembleOctoROM(name, cartridge.Program, romInfo)
+}
+
+func assembleOctoROM(name string, source string, romInfo *ROMInfo) (*ROMImage, error) {
+	program, err := AssembleOcto(source)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	romImage := &ROMImage{Name: name, Data: program.Data, Info: romInfo, Program: program}
+	if romInfo != nil && romInfo.Platform != "" {
+		romImage.Platform = Platforms[romInfo.Platform]
+	}
+	return romImage, nil
+}
@@ -0,0 +1,151 @@
+package chip8
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/gif"
+	"strings"
+	"testing"
+)
+
+// octoCartridge hides the options and the source in a GIF the way Octo does, two bits per pixel.
+func octoCartridge(t *testing.T, options map[string]any, source string) []byte {
+	t.Helper()
+	cartridgeJSON, err := json.Marshal(map[string]any{"options": options, "program": source})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := binary.BigEndian.AppendUint32(nil, uint32(len(cartridgeJSON)))
+	payload = append(payload, cartridgeJSON...)
+	palette := color.Palette{color.Black, color.White, color.Gray{Y: 0x55}, color.Gray{Y: 0xAA}}
+	frame := image.NewPaletted(image.Rect(0, 0, 64, len(payload)/16+1), palette)
+	for index, value := range payload {
+		for shift := 0; shift < 4; shift++ {
+			frame.Pix[index*4+shift] = value >> (6 - 2*shift) & 0x3
+		}
+	}
+	var cartridge bytes.Buffer
+	if err := gif.EncodeAll(&cartridge, &gif.GIF{Image: []*image.Paletted{frame}, Delay: []int{0}}); err != nil {
+		t.Fatal(err)
+	}
+	return cartridge.Bytes()
+}
+
+func zipArchive(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	for name, content := range files {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archive.Bytes()
+}
+
+func TestDecodeOctoCartridge(t *testing.T) {
+	cases := []struct {
+		Name     string
+		MaxSize  int
+		Platform string
+	}{
+		{"chip8 preset", 3216, "chip8"},
+		{"super-chip preset", 3583, "schip"},
+		{"xo-chip preset", 65024, "xochip"},
+	}
+	for _, cartridgeCase := range cases {
+		t.Run(cartridgeCase.Name, func(t *testing.T) {
+			options := map[string]any{"tickrate": 20, "maxSize": cartridgeCase.MaxSize, "shiftQuirks": true, "fillColor": "#FF0000"}
+			romImage, err := DecodeROM("game.gif", octoCartridge(t, options, ": main\n  v0 := 5\n  jump main\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(romImage.Data, []byte{0x60, 0x05, 0x12, 0x00}) {
+				t.Errorf("assembled % X, want 60 05 12 00", romImage.Data)
+			}
+			if romImage.Platform == nil || romImage.Platform.Name != cartridgeCase.Platform {
+				t.Errorf("platform is %v, want %s", romImage.Platform, cartridgeCase.Platform)
+			}
+			if romImage.Info.IPS != 1200 || !romImage.Info.Quirks.Shift || romImage.Info.Colors.Foreground != "#FF0000" {
+				t.Errorf("options read as %+v", romImage.Info)
+			}
+		})
+	}
+
+	t.Run("plain gif", func(t *testing.T) {
+		frame := image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.Black, color.White})
+		var plain bytes.Buffer
+		if err := gif.Encode(&plain, frame, nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DecodeROM("picture.gif", plain.Bytes()); err == nil || !strings.Contains(err.Error(), "not an Octo cartridge") {
+			t.Errorf("got error %v, want not an Octo cartridge", err)
+		}
+	})
+
+	t.Run("huge image", func(t *testing.T) {
+		frame := image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black, color.White})
+		var huge bytes.Buffer
+		config := image.Config{ColorModel: frame.Palette, Width: 4096, Height: 4096}
+		if err := gif.EncodeAll(&huge, &gif.GIF{Image: []*image.Paletted{frame}, Delay: []int{0}, Config: config}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DecodeROM("huge.gif", huge.Bytes()); err == nil || !strings.Contains(err.Error(), "4096x4096") {
+			t.Errorf("got error %v, want the 4096x4096 image refused", err)
+		}
+	})
+}
+
+func TestDecodeZipROM(t *testing.T) {
+	program := []byte{0x12, 0x00}
+	cases := []struct {
+		Name     string
+		Files    map[string][]byte
+		Error    string
+		Platform string
+	}{
+		{"single file", map[string][]byte{"game": program}, "", ""},
+		{"known extension among others", map[string][]byte{"game.sc8": program, "README.txt": []byte("read me")}, "", "schip"},
+		{"several programs", map[string][]byte{"one.ch8": program, "two.ch8": program}, "exactly one program, found 2", ""},
+		{"too large", map[string][]byte{"game.ch8": make([]byte, largestROMSize()+1)}, "larger than", ""},
+		{"nested archive", map[string][]byte{"inner.zip": zipArchive(t, map[string][]byte{"game.ch8": program})}, "zip archive inside a zip archive", ""},
+	}
+	for _, zipCase := range cases {
+		t.Run(zipCase.Name, func(t *testing.T) {
+			romImage, err := DecodeROM("archive.zip", zipArchive(t, zipCase.Files))
+			if zipCase.Error != "" {
+				if err == nil || !strings.Contains(err.Error(), zipCase.Error) {
+					t.Errorf("got error %v, want one containing %q", err, zipCase.Error)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(romImage.Data, program) {
+				t.Errorf("loaded % X, want % X", romImage.Data, program)
+			}
+			if zipCase.Platform != "" && (romImage.Platform == nil || romImage.Platform.Name != zipCase.Platform) {
+				t.Errorf("platform is %v, want %s", romImage.Platform, zipCase.Platform)
+			}
+		})
+	}
+}
+
+func TestUnsupportedPlatform(t *testing.T) {
+	machine := NewMachine(NewManualClock())
+	if err := machine.LoadROMImage(&ROMImage{Data: []byte{0x00, 0xE0}, Platform: Platforms["xochip"]}); err == nil {
+		t.Error("an XO-CHIP program was loaded")
+	}
+}
@@ -0,0 +1,129 @@
+package chip8
+
+import (
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+//go:embed romdb.json
+var bundledROMDatabase []byte
+
+// ROMInfo describes a known program and the settings it needs to run properly.
+// Every setting is optional: a missing one keeps the emulator default.
+type ROMInfo struct {
+	Title    string           `json:"title"`
+	Author   string           `json:"author,omitempty"`
+	Platform string           `json:"platform,omitempty"`
+	IPS      int              `json:"ips,omitempty"`    // IPS is the recommended number of instructions per second.
+	Timing   string           `json:"timing,omitempty"` // Timing is "vip" for programs tuned to the instruction timings of the COSMAC VIP.
+	Memory   string           `json:"memory,omitempty"` // Memory is "vip" for programs reaching the registers, the stack or the display in memory, as on the COSMAC VIP.
+	Quirks   *Quirks          `json:"quirks,omitempty"` // Quirks replaces the default quirks as a whole.
+	Keys     map[string]uint8 `json:"keys,omitempty"`   // Keys maps host key names to hex keypad keys, on top of the default layout.
+	Colors   *Colors          `json:"colors,omitempty"`
+}
+
+// Colors holds the pixel colors as "#RRGGBB" strings.
+type Colors struct {
+	Foreground string `json:"foreground,omitempty"`
+	Background string `json:"background,omitempty"`
+}
+
+// ROMDatabase finds the settings of a program from the SHA-1 of its bytes.
+type ROMDatabase struct {
+	entries map[string]ROMInfo
+}
+
+// DefaultROMDatabasePath is where the user's own entries live, next to the other per-user configuration.
+func DefaultROMDatabasePath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "chip8", "romdb.json")
+}
+
+// LoadROMDatabase reads the bundled database, then the user's file at overridePath whose entries take
+// precedence field by field. A missing user file is not an error.
+func LoadROMDatabase(overridePath string) (*ROMDatabase, error) {
+	romDatabase := &ROMDatabase{entries: map[string]ROMInfo{}}
+	if err := romDatabase.merge(bundledROMDatabase); err != nil {
+		return nil, fmt.Errorf("bundled ROM database: %w", err)
+	}
+	if overridePath == "" {
+		return romDatabase, nil
+	}
+	data, err := os.ReadFile(overridePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return romDatabase, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := romDatabase.merge(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", overridePath, err)
+	}
+	return romDatabase, nil
+}
+
+func (romDatabase *ROMDatabase) merge(data []byte) error {
+	var entries map[string]ROMInfo
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	for hash, override := range entries {
+		romInfo := romDatabase.entries[hash]
+		romInfo.Merge(override)
+		romDatabase.entries[hash] = romInfo
+	}
+	return nil
+}
+
+// Merge overrides the settings of the entry with the ones set in override.
+func (romInfo *ROMInfo) Merge(override ROMInfo) {
+	if override.Title != "" {
+		romInfo.Title = override.Title
+	}
+	if override.Author != "" {
+		romInfo.Author = override.Author
+	}
+	if override.Platform != "" {
+		romInfo.Platform = override.Platform
+	}
+	if override.IPS != 0 {
+		romInfo.IPS = override.IPS
+	}
+	if override.Timing != "" {
+		romInfo.Timing = override.Timing
+	}
+	if override.Memory != "" {
+		romInfo.Memory = override.Memory
+	}
+	if override.Quirks != nil {
+		romInfo.Quirks = override.Quirks
+	}
+	if override.Keys != nil {
+		romInfo.Keys = override.Keys
+	}
+	if override.Colors != nil {
+		romInfo.Colors = override.Colors
+	}
+}
+
+// Lookup returns the entry of the program, if it is known.
+func (romDatabase *ROMDatabase) Lookup(data []byte) (ROMInfo, bool) {
+	romInfo, exists := romDatabase.entries[ROMHash(data)]
+	return romInfo, exists
+}
+
+// ROMHash is the key of a program in the database: the hex SHA-1 of its bytes.
+func ROMHash(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
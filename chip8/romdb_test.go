@@ -0,0 +1,36 @@
+package chip8
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadROMDatabaseOverride checks that a field of a user entry replaces the bundled one and leaves the
+// other fields of the bundled entry as they were.
+func TestLoadROMDatabaseOverride(t *testing.T) {
+	const pong = "b232ef880bd6060fb45fa6effed7edf0ae95670e"
+	path := filepath.Join(t.TempDir(), "romdb.json")
+	if err := os.WriteFile(path, []byte(`{"`+pong+`": {"ips": 900}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	romDatabase, err := LoadROMDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	romInfo := romDatabase.entries[pong]
+	if romInfo.IPS != 900 {
+		t.Errorf("the user entry left ips at %d, want 900", romInfo.IPS)
+	}
+	if romInfo.Title != "Pong" || romInfo.Platform != "chip8" || romInfo.Quirks == nil || !romInfo.Quirks.Logic {
+		t.Errorf("the user entry lost the bundled title, platform or quirks: %+v", romInfo)
+	}
+
+	missing, err := LoadROMDatabase(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("a missing user file gave the error %v", err)
+	}
+	if missing.entries[pong].IPS != 600 {
+		t.Errorf("without a user file ips is %d, want the bundled 600", missing.entries[pong].IPS)
+	}
+}
@@ -0,0 +1,88 @@
+package chip8
+
+// SUPER-CHIP 1.1 ran on the HP48 calculators. It adds a 128x64 display, which programs switch to and from with
+// 00FF and 00FE, scrolling, 16x16 sprites, a large font and the 8 user flags of the calculator. As in Octo, the
+// 64x32 display stays a display of its own rather than being drawn in double pixels on the large one.
+const (
+	SuperChipWidth  = 128
+	SuperChipHeight = 64
+)
+
+// bigFontAddress is where the 8x10 digits of FX30 sit in memory, after the small font.
+const bigFontAddress = 0x50
+
+// bigFont holds the 8x10 hexadecimal digits of SUPER-CHIP, 10 bytes each.
+var bigFont = []byte{
+	0x3C, 0x7E, 0xE7, 0xC3, 0xC3, 0xC3, 0xC3, 0xE7, 0x7E, 0x3C, // 0
+	0x18, 0x38, 0x58, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x3C, // 1
+	0x3E, 0x7F, 0xC3, 0x06, 0x0C, 0x18, 0x30, 0x60, 0xFF, 0xFF, // 2
+	0x3C, 0x7E, 0xC3, 0x03, 0x0E, 0x0E, 0x03, 0xC3, 0x7E, 0x3C, // 3
+	0x06, 0x0E, 0x1E, 0x36, 0x66, 0xC6, 0xFF, 0xFF, 0x06, 0x06, // 4
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFC, 0xFE, 0x03, 0xC3, 0x7E, 0x3C, // 5
+	0x3E, 0x7C, 0xE0, 0xC0, 0xFC, 0xFE, 0xC3, 0xC3, 0x7E, 0x3C, // 6
+	0xFF, 0xFF, 0x03, 0x06, 0x0C, 0x18, 0x30, 0x60, 0x60, 0x60, // 7
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7E, 0x7E, 0xC3, 0xC3, 0x7E, 0x3C, // 8
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7F, 0x3F, 0x03, 0x03, 0x3E, 0x7C, // 9
+	0x7E, 0xFF, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xC3, // A
+	0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, // B
+	0x3C, 0xFF, 0xC3, 0xC0, 0xC0, 0xC0, 0xC0, 0xC3, 0xFF, 0x3C, // C
+	0xFC, 0xFE, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFE, 0xFC, // D
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, // E
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xC0, 0xC0, // F
+}
+
+// SuperChipDecoder decodes the instructions of SUPER-CHIP 1.1: 00CN, 00FB to 00FF, DXY0, FX30, FX75 and FX85.
+type SuperChipDecoder struct {
+	OpcodeDecoder
+}
+
+func (superChipDecoder *SuperChipDecoder) Decode(opcode uint16) Instruction {
+	switch {
+	case opcode&0xFFF0 == 0x00C0:
+		return &ScrollDown{GenericInstruction{opcode}}
+	case opcode == 0x00FB:
+		return &ScrollRight{GenericInstruction{opcode}}
+	case opcode == 0x00FC:
+		return &ScrollLeft{GenericInstruction{opcode}}
+	case opcode == 0x00FD:
+		return &ExitInterpreter{GenericInstruction{opcode}}
+	case opcode == 0x00FE:
+		return &SetLowResolution{GenericInstruction{opcode}}
+	case opcode == 0x00FF:
+		return &SetHighResolution{GenericInstruction{opcode}}
+	case opcode&0xF00F == 0xD000:
+		return &DrawLargeSprite{GenericInstruction{opcode}}
+	case opcode&0xF0FF == 0xF030:
+		return &SetIBigSprite{GenericInstruction{opcode}}
+	case opcode&0xF0FF == 0xF075:
+		return &StoreFlags{GenericInstruction{opcode}}
+	case opcode&0xF0FF == 0xF085:
+		return &LoadFlags{GenericInstruction{opcode}}
+	}
+	return superChipDecoder.OpcodeDecoder.Decode(opcode)
+}
+
+// Scroll moves the display by columns to the right and rows down, negative values moving it left and up; the
+// pixels scrolled in are unlit. In MegaChip mode it moves the display being drawn, shown by the next 00E0.
+func (chip8Core *Chip8Core) Scroll(columns int, rows int) {
+	if chip8Core.MegaChipEnabled() {
+		chip8Core.MegaChip.scroll(columns, rows)
+		return
+	}
+	width, height := chip8Core.ScreenWidth(), chip8Core.ScreenHeight()
+	scrolled := make([][]bool, height)
+	for positionY := range scrolled {
+		scrolled[positionY] = make([]bool, width)
+		sourceY := positionY - rows
+		if sourceY < 0 || sourceY >= height {
+			continue
+		}
+		for positionX := range scrolled[positionY] {
+			sourceX := positionX - columns
+			if sourceX >= 0 && sourceX < width {
+				scrolled[positionY][positionX] = chip8Core.Screen[sourceY][sourceX]
+			}
+		}
+	}
+	chip8Core.Screen = scrolled
+}
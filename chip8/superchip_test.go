@@ -0,0 +1,123 @@
+package chip8
+
+import "testing"
+
+func TestSuperChipInstructions(t *testing.T) {
+	t.Run("00FF 00FE", func(t *testing.T) {
+		machine := loadPlatformProgram(t, "schip", []byte{0x00, 0xFF, 0x00, 0xFE})
+		machine.Step()
+		if machine.Core.ScreenWidth() != 128 || machine.Core.ScreenHeight() != 64 {
+			t.Errorf("00FF switched to %dx%d, want 128x64", machine.Core.ScreenWidth(), machine.Core.ScreenHeight())
+		}
+		machine.Step()
+		if machine.Core.ScreenWidth() != 64 || machine.Core.ScreenHeight() != 32 {
+			t.Errorf("00FE switched to %dx%d, want 64x32", machine.Core.ScreenWidth(), machine.Core.ScreenHeight())
+		}
+	})
+
+	for _, scrollCase := range []struct {
+		Name             string
+		Program          []byte
+		ExpectedX        uint8
+		ExpectedY        uint8
+		ExpectedScrolled bool
+	}{
+		{"00C3", []byte{0x00, 0xC3}, 10, 8, true},
+		{"00FB", []byte{0x00, 0xFB}, 14, 5, true},
+		{"00FC", []byte{0x00, 0xFC}, 6, 5, true},
+		{"00FC off the edge", []byte{0x00, 0xFC, 0x00, 0xFC, 0x00, 0xFC}, 0, 5, false},
+	} {
+		t.Run(scrollCase.Name, func(t *testing.T) {
+			machine := loadPlatformProgram(t, "schip", scrollCase.Program)
+			machine.Core.SetPixel(10, 5, true)
+			for step := 0; step < len(scrollCase.Program)/2; step++ {
+				machine.Step()
+			}
+			lit := 0
+			for positionY := 0; positionY < machine.Core.ScreenHeight(); positionY++ {
+				for positionX := 0; positionX < machine.Core.ScreenWidth(); positionX++ {
+					if machine.Core.GetPixel(uint8(positionX), uint8(positionY)) {
+						lit++
+					}
+				}
+			}
+			if !scrollCase.ExpectedScrolled {
+				if lit != 0 {
+					t.Errorf("%d pixels lit, want none", lit)
+				}
+				return
+			}
+			if lit != 1 || !machine.Core.GetPixel(scrollCase.ExpectedX, scrollCase.ExpectedY) {
+				t.Errorf("%d pixels lit, want only %d,%d", lit, scrollCase.ExpectedX, scrollCase.ExpectedY)
+			}
+		})
+	}
+
+	t.Run("DXY0", func(t *testing.T) {
+		program := []byte{0x00, 0xFF, 0xA2, 0x06, 0xD0, 0x10}
+		// A 16x16 sprite whose left half is lit on even rows and right half on odd rows.
+		for row := 0; row < 16; row++ {
+			if row%2 == 0 {
+				program = append(program, 0xFF, 0x00)
+			} else {
+				program = append(program, 0x00, 0xFF)
+			}
+		}
+		machine := loadPlatformProgram(t, "schip", program)
+		machine.Core.V[0], machine.Core.V[1] = 100, 40
+		for step := 0; step < 3; step++ {
+			machine.Step()
+		}
+		for row := 0; row < 16; row++ {
+			for column := 0; column < 16; column++ {
+				expected := (column < 8) == (row%2 == 0)
+				if machine.Core.GetPixel(uint8(100+column), uint8(40+row)) != expected {
+					t.Fatalf("pixel %d,%d of the sprite is %t, want %t", column, row, !expected, expected)
+				}
+			}
+		}
+	})
+
+	t.Run("FX30", func(t *testing.T) {
+		machine := loadPlatformProgram(t, "schip", []byte{0xF2, 0x30})
+		machine.Core.V[2] = 0x1A
+		machine.Step()
+		if machine.Core.GetI() != bigFontAddress+0xA*10 {
+			t.Fatalf("I is %04X, want %04X", machine.Core.GetI(), bigFontAddress+0xA*10)
+		}
+		if machine.Core.ReadMemory(machine.Core.GetI()) != 0x7E {
+			t.Errorf("the digit A starts with %02X, want 7E", machine.Core.ReadMemory(machine.Core.GetI()))
+		}
+	})
+
+	t.Run("FX75 FX85", func(t *testing.T) {
+		machine := loadPlatformProgram(t, "schip", []byte{0xFF, 0x75, 0x6A, 0x00, 0xFF, 0x85})
+		for register := range machine.Core.V {
+			machine.Core.V[register] = uint8(register + 1)
+		}
+		machine.Step()
+		if machine.Core.Flags != [8]byte{1, 2, 3, 4, 5, 6, 7, 8} {
+			t.Errorf("FF75 saved the flags %v, want V0 to V7", machine.Core.Flags)
+		}
+		machine.Core.V = [16]uint8{}
+		machine.Step()
+		machine.Step()
+		for register, value := range machine.Core.V {
+			expected := uint8(0)
+			if register < 8 {
+				expected = uint8(register + 1)
+			}
+			if value != expected {
+				t.Errorf("FF85 loaded V%X with %d, want %d", register, value, expected)
+			}
+		}
+	})
+
+	t.Run("00FD", func(t *testing.T) {
+		machine := loadPlatformProgram(t, "schip", []byte{0x00, 0xFD})
+		machine.Step()
+		if !machine.Halted() || machine.Core.PC != 0x200 {
+			t.Errorf("halted %t at %04X, want halted at 0200", machine.Halted(), machine.Core.PC)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package chip8
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadSymbolFile reads the labels of a program assembled elsewhere, one label per line as a hexadecimal
+// address followed by its name; lines starting with # are comments:
+//
+//	# pong.8o
+//	0200 main
+//	0246 draw-paddles
+func ReadSymbolFile(path string) (map[string]uint16, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	labels := map[string]uint16{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		address, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(fields[0]), "0x"), 16, 16)
+		if len(fields) != 2 || err != nil {
+			return nil, fmt.Errorf("%s:%d: expected ADDRESS NAME", path, lineNumber)
+		}
+		labels[fields[1]] = uint16(address)
+	}
+	return labels, scanner.Err()
+}
+
+// LabelSymbol names an address after the closest label at or before it, such as "main" or "main+4".
+// It returns an empty string when no label precedes the address.
+func LabelSymbol(labels map[string]uint16, address uint16) string {
+	name, labelAddress, found := "", uint16(0), false
+	for label, current := range labels {
+		if current > address || (found && (current < labelAddress || current == labelAddress && label > name)) {
+			continue
+		}
+		name, labelAddress, found = label, current, true
+	}
+	if !found || address == labelAddress {
+		return name
+	}
+	return fmt.Sprintf("%s+%d", name, address-labelAddress)
+}
@@ -0,0 +1,256 @@
+package chip8
+
+import "bytes"
+
+// The CHIP-8 interpreter of the COSMAC VIP kept its data in the last pages of memory: the call stack grows down
+// from 0xECF, V0 to VF are at 0xEF0 and the display, 8 bytes per row of 64 pixels with the leftmost pixel in the
+// most significant bit, fills 0xF00 to 0xFFF. The offsets are from the top of a 4 KiB memory; a VIP with less
+// memory had them as far from its own top.
+const (
+	vipStackOffset     = 0x1000 - 0xECF
+	vipRegistersOffset = 0x1000 - 0xEF0
+	vipDisplayOffset   = 0x1000 - 0xF00
+)
+
+// vipLineCycles is the duration of a scan line of the VIP display; the first of the 128 displayed lines is
+// vipFirstDisplayLine, and EF1 is active during the 4 lines before the display starts and before it ends.
+const (
+	vipLineCycles       = 14
+	vipFirstDisplayLine = 80
+)
+
+// VIPLayout holds the addresses of the data of the VIP interpreter in the memory of a core.
+type VIPLayout struct {
+	StackTop  uint16 // StackTop is where the first return address goes, low byte first, the stack growing down.
+	Registers uint16 // Registers is the address of V0.
+	Display   uint16 // Display is the address of the first row of the display.
+}
+
+// NewVIPLayout returns the layout for a memory of the given size, placed below the top of the first 4 KiB.
+func NewVIPLayout(memorySize int) VIPLayout {
+	top := min(memorySize, 0x1000)
+	return VIPLayout{
+		StackTop:  uint16(top - vipStackOffset),
+		Registers: uint16(top - vipRegistersOffset),
+		Display:   uint16(top - vipDisplayOffset),
+	}
+}
+
+// VIPDecoder decodes CHIP-8 as the COSMAC VIP ran it, the 0NNN instructions calling RCA 1802 machine code.
+type VIPDecoder struct {
+	OpcodeDecoder
+}
+
+func (vipDecoder *VIPDecoder) Decode(opcode uint16) Instruction {
+	if opcode&0xF000 == 0x0000 && opcode != 0x00E0 && opcode != 0x00EE {
+		return &CallMachineCode{GenericInstruction{opcode}}
+	}
+	return vipDecoder.OpcodeDecoder.Decode(opcode)
+}
+
+// vipHardware is the part of the COSMAC VIP the machine code subroutines use besides memory: the CPU, the keypad
+// latch set by OUT 2 and read through EF3, and the cycles run by the last call for the VIP timing.
+type vipHardware struct {
+	cpu         RCA1802
+	keyLatch    uint8
+	calling     bool
+	spentCycles int
+}
+
+// vipMachineCodeSlice is the number of machine cycles a subroutine runs before the interpreter gets back to the
+// rest of the emulation; a longer subroutine carries on at the next step, the 0NNN instruction being fetched again.
+const vipMachineCodeSlice = VIPFrameCycles - vipDisplayCycles
+
+// RunMachineCode runs the RCA 1802 subroutine at address called by a 0NNN instruction, with the registers set
+// as the VIP interpreter sets them: R3 the program counter, R2 the stack, R5 the CHIP-8 program counter, R6 and
+// R7 pointing at VX and VY, RA holding I and R8 the timers. The registers, the stack and the display are stored
+// in memory at their VIP addresses for the subroutine and read back from there. The subroutine returns to the
+// interpreter with D4 (SEP R4); PC then comes from R5. It reports whether the subroutine returned: when it runs
+// longer than vipMachineCodeSlice, PC stays on the 0NNN instruction, which resumes it when executed again.
+func (chip8Core *Chip8Core) RunMachineCode(address uint16, x uint8, y uint8) bool {
+	layout := NewVIPLayout(len(chip8Core.Memory))
+	if chip8Core.vip == nil {
+		chip8Core.vip = chip8Core.newVIPHardware()
+	}
+	vip := chip8Core.vip
+	cpu := &vip.cpu
+	chip8Core.storeVIPLayout(layout)
+	if !vip.calling {
+		vip.calling = true
+		cpu.R[2] = layout.StackTop - 2*chip8Core.SP
+		cpu.R[3] = address
+		cpu.R[5] = chip8Core.PC + 2
+		cpu.R[6] = layout.Registers + uint16(x)
+		cpu.R[7] = layout.Registers + uint16(y)
+		cpu.R[0xA] = uint16(chip8Core.I)
+		cpu.R[0xB] = layout.Display & 0xFF00
+		cpu.P, cpu.X = 3, 2
+	}
+	cpu.R[8] = uint16(chip8Core.DelayTimer)<<8 | uint16(chip8Core.SoundTimer)
+
+	start := cpu.Cycles
+	for cpu.P != 4 && cpu.Cycles-start < vipMachineCodeSlice {
+		cpu.Step()
+	}
+	vip.spentCycles += int(cpu.Cycles - start)
+	returned := cpu.P == 4
+	chip8Core.loadVIPLayout(layout, returned)
+	chip8Core.I = uint32(cpu.R[0xA])
+	chip8Core.DelayTimer, chip8Core.SoundTimer = uint8(cpu.R[8]>>8), uint8(cpu.R[8])
+	if returned {
+		vip.calling = false
+		chip8Core.SetPC(cpu.R[5])
+	}
+	return returned
+}
+
+func (chip8Core *Chip8Core) newVIPHardware() *vipHardware {
+	vip := &vipHardware{}
+	vip.cpu = RCA1802{
+		Read: func(address uint16) byte {
+			return chip8Core.ReadMemory(uint32(address))
+		},
+		Write: func(address uint16, value byte) {
+			chip8Core.WriteMemory(uint32(address), value)
+		},
+		Input: func(port uint8) byte {
+			// INP 1 turns the display on; the other ports read nothing.
+			return 0
+		},
+		Output: func(port uint8, value byte) {
+			if port == 2 {
+				vip.keyLatch = value & 0x0F
+			}
+		},
+		Flag: func(flag uint8) bool {
+			switch flag {
+			case 1:
+				line := int(vip.cpu.Cycles%VIPFrameCycles) / vipLineCycles
+				return line >= vipFirstDisplayLine-4 && line < vipFirstDisplayLine || line >= vipFirstDisplayLine+124 && line < vipFirstDisplayLine+128
+			case 3:
+				return chip8Core.GetKey(vip.keyLatch)
+			}
+			return false
+		},
+	}
+	return vip
+}
+
+// takeMachineCodeCycles returns the machine cycles run by subroutines since it was last called.
+func (chip8Core *Chip8Core) takeMachineCodeCycles() int {
+	if chip8Core.vip == nil {
+		return 0
+	}
+	cycles := chip8Core.vip.spentCycles
+	chip8Core.vip.spentCycles = 0
+	return cycles
+}
+
+// storeVIPLayout copies V0 to VF, the return addresses of the stack and a 64x32 display into memory.
+func (chip8Core *Chip8Core) storeVIPLayout(layout VIPLayout) {
+	chip8Core.storeVIPRegisters(layout)
+	chip8Core.storeVIPStack(layout)
+	chip8Core.storeVIPDisplay(layout)
+}
+
+// loadVIPLayout reads back what storeVIPLayout stored, the stack only when the subroutine has returned, its
+// stack pointer R2 being in use until then.
+func (chip8Core *Chip8Core) loadVIPLayout(layout VIPLayout, stack bool) {
+	chip8Core.loadVIPRegisters(layout)
+	if stack {
+		depth := (int(layout.StackTop) - int(chip8Core.vip.cpu.R[2])) / 2
+		chip8Core.loadVIPStack(layout, depth)
+	}
+	chip8Core.loadVIPDisplay(layout)
+}
+
+func (chip8Core *Chip8Core) storeVIPRegisters(layout VIPLayout) {
+	copy(chip8Core.Memory[layout.Registers:], chip8Core.V[:])
+}
+
+func (chip8Core *Chip8Core) loadVIPRegisters(layout VIPLayout) {
+	copy(chip8Core.V[:], chip8Core.Memory[layout.Registers:])
+}
+
+// storeVIPStack stores the return addresses, the address following each call, as the VIP interpreter pushed them.
+func (chip8Core *Chip8Core) storeVIPStack(layout VIPLayout) {
+	memory := chip8Core.Memory
+	for index := uint16(0); index < chip8Core.SP && index < uint16(len(chip8Core.Stack)); index++ {
+		returnAddress := chip8Core.Stack[index] + 2
+		memory[layout.StackTop-2*index] = uint8(returnAddress)
+		memory[layout.StackTop-2*index-1] = uint8(returnAddress >> 8)
+	}
+}
+
+// loadVIPStack reads depth return addresses back into the stack, which it leaves that deep.
+func (chip8Core *Chip8Core) loadVIPStack(layout VIPLayout, depth int) {
+	memory := chip8Core.Memory
+	chip8Core.SP = uint16(max(0, min(depth, len(chip8Core.Stack))))
+	for index := uint16(0); index < chip8Core.SP; index++ {
+		returnAddress := uint16(memory[layout.StackTop-2*index-1])<<8 | uint16(memory[layout.StackTop-2*index])
+		chip8Core.Stack[index] = returnAddress - 2
+	}
+}
+
+// storeVIPDisplay packs the screen into memory, 8 pixels per byte; only a 64x32 screen has a VIP display.
+func (chip8Core *Chip8Core) storeVIPDisplay(layout VIPLayout) {
+	if chip8Core.ScreenWidth() != 64 || chip8Core.ScreenHeight() != 32 {
+		return
+	}
+	for positionY := 0; positionY < 32; positionY++ {
+		for column := 0; column < 8; column++ {
+			var bits byte
+			for bit := 0; bit < 8; bit++ {
+				if chip8Core.Screen[positionY][column*8+bit] {
+					bits |= 0x80 >> bit
+				}
+			}
+			chip8Core.Memory[int(layout.Display)+positionY*8+column] = bits
+		}
+	}
+}
+
+func (chip8Core *Chip8Core) loadVIPDisplay(layout VIPLayout) {
+	if chip8Core.ScreenWidth() != 64 || chip8Core.ScreenHeight() != 32 {
+		return
+	}
+	for positionY := 0; positionY < 32; positionY++ {
+		for column := 0; column < 8; column++ {
+			bits := chip8Core.Memory[int(layout.Display)+positionY*8+column]
+			for bit := 0; bit < 8; bit++ {
+				chip8Core.Screen[positionY][column*8+bit] = bits&(0x80>>bit) != 0
+			}
+		}
+	}
+}
+
+// syncVIPMemory keeps V0 to VF, the stack and a 64x32 screen in step with their copies at the VIP addresses in
+// memory, so a program can reach them through either. shadow holds the bytes from the bottom of the stack to the
+// end of the display as they were after the last call, nil the first time: a part whose bytes have changed in
+// memory since is read from there, any other part is stored, the fields winning over an unchanged memory. It
+// returns the shadow for the next call.
+func (chip8Core *Chip8Core) syncVIPMemory(layout VIPLayout, shadow []byte) []byte {
+	memory := chip8Core.Memory
+	start := int(layout.StackTop) + 1 - 2*len(chip8Core.Stack)
+	end := int(layout.Display) + 32*8
+	changed := func(from int, to int) bool {
+		return shadow != nil && !bytes.Equal(memory[from:to], shadow[from-start:to-start])
+	}
+
+	if changed(int(layout.Registers), int(layout.Registers)+len(chip8Core.V)) {
+		chip8Core.loadVIPRegisters(layout)
+	} else {
+		chip8Core.storeVIPRegisters(layout)
+	}
+	if changed(int(layout.StackTop)+1-2*int(chip8Core.SP), int(layout.StackTop)+1) {
+		chip8Core.loadVIPStack(layout, int(chip8Core.SP))
+	} else {
+		chip8Core.storeVIPStack(layout)
+	}
+	if changed(int(layout.Display), end) {
+		chip8Core.loadVIPDisplay(layout)
+	} else {
+		chip8Core.storeVIPDisplay(layout)
+	}
+	return append(shadow[:0], memory[start:end]...)
+}
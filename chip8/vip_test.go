@@ -0,0 +1,75 @@
+package chip8
+
+import "testing"
+
+// loadVIPMemoryProgram loads a CHIP-8 program with the COSMAC VIP memory layout: the stack below 0xECF, V0 to VF
+// at 0xEF0 and the display at 0xF00.
+func loadVIPMemoryProgram(t *testing.T, program []byte) *Machine {
+	t.Helper()
+	machine := NewMachine(NewManualClock())
+	machine.Defaults.VIPMemory = true
+	if err := machine.LoadROM(program); err != nil {
+		t.Fatal(err)
+	}
+	return machine
+}
+
+func TestVIPMemoryStores(t *testing.T) {
+	// 0200: v3 := 7, 0202: call 0206, 0206: i := 0 (the digit 0), 0208: sprite v0 v0 5
+	machine := loadVIPMemoryProgram(t, []byte{0x63, 0x07, 0x22, 0x06, 0x12, 0x04, 0xA0, 0x00, 0xD0, 0x05})
+	stepAll(machine, 4)
+	memory := machine.Core.Memory
+	if memory[0xEF3] != 7 {
+		t.Errorf("V3 is %d in memory, want 7", memory[0xEF3])
+	}
+	if memory[0xECE] != 0x02 || memory[0xECF] != 0x04 {
+		t.Errorf("the return address is %02X%02X in memory, want 0204", memory[0xECE], memory[0xECF])
+	}
+	for row := 0; row < 5; row++ {
+		if memory[0xF00+row*8] != memory[row] {
+			t.Errorf("row %d of the display is %02X in memory, want %02X", row, memory[0xF00+row*8], memory[row])
+		}
+	}
+}
+
+func TestVIPMemoryLoads(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Program []byte
+		Steps   int
+		Check   func(core *Chip8Core) bool
+	}{
+		// v0 := 0x2A, i := 0xEF5, save v0
+		{"FX55 into V5", []byte{0x60, 0x2A, 0xAE, 0xF5, 0xF0, 0x55}, 3,
+			func(core *Chip8Core) bool { return core.V[5] == 0x2A && core.Memory[0xEF5] == 0x2A }},
+		// v0 := 0xFF, i := 0xF00, save v0: the write is not undone by the screen it changed
+		{"FX55 into the display", []byte{0x60, 0xFF, 0xAF, 0x00, 0xF0, 0x55}, 3,
+			func(core *Chip8Core) bool {
+				return core.GetPixel(0, 0) && core.GetPixel(7, 0) && core.Memory[0xF00] == 0xFF
+			}},
+		// v0 := 0x06, call 0206, 0206: i := 0xECE, save v0, return: the return address written in memory is taken
+		{"FX55 into the stack", []byte{0x60, 0x06, 0x22, 0x06, 0x00, 0x00, 0xAE, 0xCE, 0xF0, 0x55, 0x00, 0xEE}, 5,
+			func(core *Chip8Core) bool { return core.SP == 0 && core.PC == 0x0604 }},
+	}
+	for _, loadCase := range cases {
+		machine := loadVIPMemoryProgram(t, loadCase.Program)
+		stepAll(machine, loadCase.Steps)
+		if !loadCase.Check(machine.Core) {
+			t.Errorf("%s left V %X, SP %d, PC %04X and the first byte of the display %02X", loadCase.Name,
+				machine.Core.V, machine.Core.SP, machine.Core.PC, machine.Core.Memory[0xF00])
+		}
+	}
+}
+
+// TestVIPMemoryExternalWrite checks that what a debugger writes between two steps is seen by the next one.
+func TestVIPMemoryExternalWrite(t *testing.T) {
+	machine := loadVIPMemoryProgram(t, []byte{0x60, 0x01, 0x61, 0x02})
+	machine.Step()
+	machine.Core.Memory[0xF00] = 0x80
+	machine.Core.Memory[0xEF4] = 0x44
+	machine.Step()
+	if !machine.Core.GetPixel(0, 0) || machine.Core.V[4] != 0x44 || machine.Core.V[1] != 2 || machine.Core.Memory[0xEF1] != 2 {
+		t.Errorf("the writes left the pixel %t, V4 at %02X and V1 at %d, want lit, 44 and 2",
+			machine.Core.GetPixel(0, 0), machine.Core.V[4], machine.Core.V[1])
+	}
+}
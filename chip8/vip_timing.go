@@ -0,0 +1,145 @@
+package chip8
+
+// The COSMAC VIP ran its CHIP-8 interpreter on an RCA 1802 at 1.7609 MHz, 8 clock cycles per machine cycle,
+// which gives VIPFrameCycles machine cycles between two 60 Hz interrupts. The display DMA and the interrupt
+// routine take vipDisplayCycles of them, the interpreter gets the rest.
+const (
+	VIPFrameCycles   = 3668
+	vipDisplayCycles = 1024 + 46
+	vipFetchCycles   = 68 // vipFetchCycles is the cost of fetching and decoding an instruction, on top of executing it.
+)
+
+// The costs of the instructions in VIP machine cycles, fetching included, after the analysis of the original
+// interpreter's code. They are close to, not exactly, what the VIP took: the costs of DXYN, FX33, FX55 and FX65
+// depend on their operands, and skips taking the branch cost a little more.
+
+// VIPCycles is the cost of the instructions without one of their own: the 8XYN arithmetic.
+func (instruction *GenericInstruction) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 44
+}
+
+func (instruction *ClearScreen) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 3038
+}
+
+func (instruction *ReturnFromSubroutine) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 10
+}
+
+func (instruction *JumpToAddress) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 12
+}
+
+func (instruction *CallSubroutine) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 26
+}
+
+func (instruction *SkipIfVxEqual) VIPCycles(core *Chip8Core) int {
+	return vipSkipCycles(10, core.GetRegister(instruction.x()) == instruction.value())
+}
+
+func (instruction *SkipIfVxNotEqual) VIPCycles(core *Chip8Core) int {
+	return vipSkipCycles(10, core.GetRegister(instruction.x()) != instruction.value())
+}
+
+func (instruction *SkipIfVxVyEqual) VIPCycles(core *Chip8Core) int {
+	return vipSkipCycles(14, core.GetRegister(instruction.x()) == core.GetRegister(instruction.y()))
+}
+
+func (instruction *SkipIfVxVyNotEqual) VIPCycles(core *Chip8Core) int {
+	return vipSkipCycles(14, core.GetRegister(instruction.x()) != core.GetRegister(instruction.y()))
+}
+
+func (instruction *SkipIfKeyPressed) VIPCycles(core *Chip8Core) int {
+	return vipSkipCycles(14, core.GetKey(core.GetRegister(instruction.x())))
+}
+
+func (instruction *SkipIfKeyNotPressed) VIPCycles(core *Chip8Core) int {
+	return vipSkipCycles(14, !core.GetKey(core.GetRegister(instruction.x())))
+}
+
+// vipSkipCycles is the cost of a conditional skip, 4 cycles more when the next instruction is skipped.
+func vipSkipCycles(cycles int, taken bool) int {
+	if taken {
+		cycles += 4
+	}
+	return vipFetchCycles + cycles
+}
+
+// VIPCycles of 0NNN is the cost of the call; the cycles of the machine code itself are counted as it runs.
+func (instruction *CallMachineCode) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 26
+}
+
+func (instruction *SetVx) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 6
+}
+
+func (instruction *AddToVx) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 10
+}
+
+func (instruction *SetI) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 12
+}
+
+// VIPCycles of BNNN include 2 more cycles when adding V0 carries into the high byte of the address.
+func (instruction *JumpToAddressPlusV0) VIPCycles(core *Chip8Core) int {
+	if int(instruction.address()&0xFF)+int(core.GetRegister(0)) > 0xFF {
+		return vipFetchCycles + 24
+	}
+	return vipFetchCycles + 22
+}
+
+func (instruction *SetVxRandom) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 36
+}
+
+// VIPCycles of DXYN grow with the rows of the sprite; a sprite not aligned on a byte of the display is shifted
+// into two bytes, which costs 20 more cycles per row.
+func (instruction *DrawSprite) VIPCycles(core *Chip8Core) int {
+	rowCycles := 46
+	if core.GetRegister(instruction.x())%8 != 0 {
+		rowCycles += 20
+	}
+	return vipFetchCycles + 26 + int(instruction.nibble())*rowCycles
+}
+
+func (instruction *SetVxDelayTimer) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 10
+}
+
+// VIPCycles of FX0A is the cost of checking the keypad once; the instruction runs again until a key is pressed.
+func (instruction *WaitForKeyPress) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 18
+}
+
+func (instruction *SetDelayTimer) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 10
+}
+
+func (instruction *SetSoundTimer) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 10
+}
+
+func (instruction *SetIPlusVx) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 16
+}
+
+func (instruction *SetISprite) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 16
+}
+
+// VIPCycles of FX33 grow with the digits, each found by repeated subtraction.
+func (instruction *StoreBCD) VIPCycles(core *Chip8Core) int {
+	value := core.GetRegister(instruction.x())
+	return vipFetchCycles + 80 + 16*int(value/100+value/10%10+value%10)
+}
+
+func (instruction *Storeregisters) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 14 + 14*(int(instruction.x())+1)
+}
+
+func (instruction *Fillregisters) VIPCycles(core *Chip8Core) int {
+	return vipFetchCycles + 14 + 14*(int(instruction.x())+1)
+}
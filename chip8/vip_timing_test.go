@@ -0,0 +1,74 @@
+package chip8
+
+import "testing"
+
+// loadVIPTimedProgram loads a CHIP-8 program timed as on the COSMAC VIP, with the count of the instructions
+// each frame executes, without the VBlank quirk ending frames at the first sprite.
+func loadVIPTimedProgram(t *testing.T, program []byte) (*Machine, *int) {
+	t.Helper()
+	machine := NewMachine(NewManualClock())
+	machine.Defaults.VIPTiming = true
+	if err := machine.LoadROM(program); err != nil {
+		t.Fatal(err)
+	}
+	machine.Core.Quirks.VBlank = false
+	steps := new(int)
+	machine.OnStep(func(address uint16) { *steps++ })
+	return machine, steps
+}
+
+func TestVIPTimingFrames(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Program  []byte
+		Expected int
+	}{
+		// 0200: v0 := 1, 0202: jump 0200; 74 and 80 cycles
+		{"6XNN", []byte{0x60, 0x01, 0x12, 0x00}, 34},
+		// 0200: sprite v0 v0 5, 0202: jump 0200; 324 and 80 cycles
+		{"DXYN", []byte{0xD0, 0x05, 0x12, 0x00}, 13},
+	}
+	for _, timingCase := range cases {
+		machine, steps := loadVIPTimedProgram(t, timingCase.Program)
+		machine.RunFrame()
+		if *steps != timingCase.Expected {
+			t.Errorf("a frame of %s executed %d instructions, want %d", timingCase.Name, *steps, timingCase.Expected)
+		}
+	}
+}
+
+// TestVIPTimingOverrun checks that the cycles an instruction takes past the end of a frame are taken from the next.
+func TestVIPTimingOverrun(t *testing.T) {
+	// 0200: clear, 0202: v0 += 1, 0204: jump 0202
+	machine, steps := loadVIPTimedProgram(t, []byte{0x00, 0xE0, 0x70, 0x01, 0x12, 0x02})
+	machine.RunFrame()
+	budget := VIPFrameCycles - vipDisplayCycles
+	if overrun := vipFetchCycles + 3038 - budget; *steps != 1 || machine.vipCycles != -overrun {
+		t.Fatalf("the first frame executed %d instructions and left %d cycles, want 1 and %d", *steps, machine.vipCycles, -overrun)
+	}
+	*steps = 0
+	machine.RunFrame()
+	shortened := *steps
+	*steps = 0
+	machine.RunFrame()
+	if shortened >= *steps {
+		t.Errorf("the frame after the overrun executed %d instructions, the next one %d, want fewer", shortened, *steps)
+	}
+}
+
+// TestVIPTimingDefault checks that the default of -vip-timing applies to every program loaded.
+func TestVIPTimingDefault(t *testing.T) {
+	machine := NewMachine(NewManualClock())
+	machine.Defaults.VIPTiming = true
+	if err := machine.LoadROM([]byte{0x70, 0x01, 0x12, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	if !machine.VIPTiming {
+		t.Fatal("the program is not timed as on the COSMAC VIP")
+	}
+	machine.RunFrame()
+	// 17 of the 33 instructions fitting in the frame are 7XNN, where CyclesPerFrame would have run 5.
+	if machine.Core.V[0] != 17 {
+		t.Errorf("the frame added 1 to V0 %d times, want 17", machine.Core.V[0])
+	}
+}
@@ -0,0 +1,528 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image/color"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// ConformanceSuiteFile is the manifest describing the test ROMs of a directory.
+const ConformanceSuiteFile = "suite.json"
+
+// DefaultConformanceFrames is the number of frames a test ROM runs when its manifest entry does not say.
+const DefaultConformanceFrames = 300
+
+// DefaultConformanceProfiles are the profiles a suite runs under when it names none: the platforms whose
+// programs are plain CHIP-8 ones. A suite testing SUPER-CHIP or MegaChip names its profiles.
+var DefaultConformanceProfiles = []string{"chip8", "vip"}
+
+// ConformanceSuite lists the test ROMs of a directory and how to tell whether they passed. Maps keyed by
+// profile use "*" for every profile not named.
+//
+//	{
+//		"profiles": ["chip8", "vip", "schip"],
+//		"tests": [{
+//			"rom": "5-quirks.ch8",
+//			"frames": 600,
+//			"memory": {"chip8": {"1FF": 1}, "schip": {"1FF": 2}},
+//			"regions": [{"x": 0, "y": 0, "width": 64, "height": 32}],
+//			"pass": {"chip8": "3f786850e387550fdab836ed7e6dc881de23001b"},
+//			"failGlyph": ["88", "50", "20", "50", "88"]
+//		}]
+//	}
+type ConformanceSuite struct {
+	Profiles []string          `json:"profiles"` // Profiles are the platforms whose quirks the tests run under, DefaultConformanceProfiles when empty.
+	Tests    []ConformanceTest `json:"tests"`
+}
+
+// ConformanceTest is a test ROM of a suite. The screen it leaves after its frames decides the result: a fail
+// glyph anywhere in the regions fails it, otherwise the hash of the regions must match the pass hash of the
+// profile, or the pass glyph must be found. Without a fail glyph, pass hash or pass glyph the result is unknown
+// and the hash is reported so it can be recorded once the screen has been checked by eye.
+type ConformanceTest struct {
+	Name      string                       `json:"name"`      // Name identifies the test in the reports, the file name of the ROM without its extension by default.
+	ROM       string                       `json:"rom"`       // ROM is the path of the program, relative to the directory of the suite.
+	Frames    int                          `json:"frames"`    // Frames is the number of frames to run, DefaultConformanceFrames when 0.
+	Profiles  []string                     `json:"profiles"`  // Profiles restricts the test to some profiles of the suite.
+	Memory    map[string]map[string]byte   `json:"memory"`    // Memory sets bytes, at hexadecimal addresses, after loading, per profile; test menus read their choice from it.
+	Inputs    []ConformanceInput           `json:"inputs"`    // Inputs press and release keys along the run.
+	Regions   []ScreenRegion               `json:"regions"`   // Regions are the parts of the screen holding the verdict, the whole screen when empty.
+	Pass      map[string]string            `json:"pass"`      // Pass holds the SHA-1 of the regions of a passing run, per profile.
+	PassGlyph []string                     `json:"passGlyph"` // PassGlyph is the sprite drawn for a pass, its rows in hexadecimal.
+	FailGlyph []string                     `json:"failGlyph"` // FailGlyph is the sprite drawn for a failure, its rows in hexadecimal.
+	Skip      map[string]string            `json:"skip"`      // Skip gives the reason a profile is not run, for behaviours the emulator does not have.
+	Options   map[string]ConformanceOption `json:"options"`   // Options change the machine per profile.
+}
+
+// ConformanceOption changes the machine a test runs on for a profile.
+type ConformanceOption struct {
+	CyclesPerFrame int  `json:"cyclesPerFrame"`
+	VIPTiming      bool `json:"vipTiming"`
+	VIPMemory      bool `json:"vipMemory"`
+}
+
+// ConformanceInput presses or releases a key before a frame runs.
+type ConformanceInput struct {
+	Frame   int   `json:"frame"`
+	Key     uint8 `json:"key"`
+	Pressed bool  `json:"pressed"`
+}
+
+// ScreenRegion is a rectangle of the screen, in CHIP-8 pixels.
+type ScreenRegion struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// ConformanceStatus is the outcome of a test ROM under a profile.
+type ConformanceStatus string
+
+const (
+	ConformancePass    ConformanceStatus = "pass"
+	ConformanceFail    ConformanceStatus = "FAIL"
+	ConformanceUnknown ConformanceStatus = "?"
+	ConformanceSkipped ConformanceStatus = "skip"
+	ConformanceError   ConformanceStatus = "ERROR"
+)
+
+// ConformanceResult is the outcome of a test ROM under a profile.
+type ConformanceResult struct {
+	Test     string
+	Profile  string
+	Status   ConformanceStatus
+	Message  string        // Message explains any status but a pass.
+	Hash     string        // Hash is the SHA-1 of the regions of the final screen.
+	Duration time.Duration // Duration is the host time the run took.
+}
+
+// LoadConformanceSuite reads the manifest of a directory. Without one, every program of the directory with a
+// known extension is a test with no expectation, so a first run reports the hashes to record.
+func LoadConformanceSuite(directory string) (*ConformanceSuite, error) {
+	content, err := os.ReadFile(filepath.Join(directory, ConformanceSuiteFile))
+	if err == nil {
+		var suite ConformanceSuite
+		if err := json.Unmarshal(content, &suite); err != nil {
+			return nil, fmt.Errorf("%s: %w", ConformanceSuiteFile, err)
+		}
+		return &suite, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+	suite := &ConformanceSuite{}
+	for _, entry := range entries {
+		extension := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() && (chip8.PlatformForFile(entry.Name()) != nil || extension == ".8o" || extension == ".gif") {
+			suite.Tests = append(suite.Tests, ConformanceTest{ROM: entry.Name()})
+		}
+	}
+	return suite, nil
+}
+
+// ConformanceProfiles returns the profiles of the suite, DefaultConformanceProfiles when it names none.
+func (suite *ConformanceSuite) ConformanceProfiles() []string {
+	if len(suite.Profiles) > 0 {
+		return suite.Profiles
+	}
+	return DefaultConformanceProfiles
+}
+
+// RunConformanceSuite runs every test of the suite under every profile, headlessly and with a seeded random
+// sequence. When screenDirectory is not empty the final screens are saved in it as NAME.PROFILE.png.
+func RunConformanceSuite(directory string, suite *ConformanceSuite, screenDirectory string) []ConformanceResult {
+	var results []ConformanceResult
+	for _, test := range suite.Tests {
+		for _, profile := range suite.ConformanceProfiles() {
+			if len(test.Profiles) > 0 && !slices.Contains(test.Profiles, profile) {
+				continue
+			}
+			started := time.Now()
+			result := test.run(directory, profile, screenDirectory)
+			result.Test, result.Profile, result.Duration = test.TestName(), profile, time.Since(started)
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// TestName returns the name of the test in the reports.
+func (test *ConformanceTest) TestName() string {
+	if test.Name != "" {
+		return test.Name
+	}
+	return strings.TrimSuffix(filepath.Base(test.ROM), filepath.Ext(test.ROM))
+}
+
+func (test *ConformanceTest) run(directory string, profile string, screenDirectory string) ConformanceResult {
+	if reason, skipped := conformanceValue(test.Skip, profile); skipped {
+		return ConformanceResult{Status: ConformanceSkipped, Message: reason}
+	}
+	failure := func(err error) ConformanceResult {
+		return ConformanceResult{Status: ConformanceError, Message: err.Error()}
+	}
+	platform, err := chip8.LookupPlatform(profile)
+	if err != nil {
+		return failure(err)
+	}
+	romImage, err := chip8.ReadROMFile(filepath.Join(directory, test.ROM))
+	if err != nil {
+		return failure(err)
+	}
+	romImage.Platform = platform
+	machine := chip8.NewMachine(chip8.NewManualClock())
+	// Test ROMs end in a jump to themselves, which ends the run early.
+	machine.HaltOnLoop = true
+	if err := machine.LoadROMImage(romImage); err != nil {
+		return failure(err)
+	}
+	// The profile decides the quirks, whatever the program carries.
+	machine.Core.Quirks = platform.Quirks
+	machine.Seed(1)
+	if option, exists := conformanceValue(test.Options, profile); exists {
+		if option.CyclesPerFrame > 0 {
+			machine.CyclesPerFrame = option.CyclesPerFrame
+		}
+		machine.VIPTiming = option.VIPTiming
+		machine.VIPMemory = option.VIPMemory
+	}
+	memory, _ := conformanceValue(test.Memory, profile)
+	for text, value := range memory {
+		address, err := strconv.ParseUint(text, 16, 16)
+		if err != nil || int(address) >= len(machine.Core.Memory) {
+			return failure(fmt.Errorf("invalid memory address %q", text))
+		}
+		machine.Core.Memory[address] = value
+	}
+
+	frames := test.Frames
+	if frames <= 0 {
+		frames = DefaultConformanceFrames
+	}
+	inputs := test.Inputs
+	for frame := 0; frame < frames && !machine.Halted(); frame++ {
+		for len(inputs) > 0 && inputs[0].Frame <= frame {
+			machine.Core.SetKey(inputs[0].Key, inputs[0].Pressed)
+			inputs = inputs[1:]
+		}
+		machine.RunFrame()
+	}
+
+	if screenDirectory != "" {
+		if err := writeConformanceScreen(filepath.Join(screenDirectory, test.TestName()+"."+profile+".png"), machine.Core); err != nil {
+			return failure(err)
+		}
+	}
+	result := test.judge(machine.Core, profile)
+	if machine.Halted() && result.Status != ConformancePass {
+		result.Message += " (halted: " + machine.HaltReason() + ")"
+	}
+	return result
+}
+
+// judge decides the result from the final screen.
+func (test *ConformanceTest) judge(core *chip8.Chip8Core, profile string) ConformanceResult {
+	regions := test.Regions
+	if len(regions) == 0 {
+		regions = []ScreenRegion{{Width: core.ScreenWidth(), Height: core.ScreenHeight()}}
+	}
+	result := ConformanceResult{Hash: screenRegionsHash(core, regions)}
+	failGlyph, err := parseGlyph(test.FailGlyph)
+	if err != nil {
+		return ConformanceResult{Status: ConformanceError, Message: "fail glyph: " + err.Error()}
+	}
+	passGlyph, err := parseGlyph(test.PassGlyph)
+	if err != nil {
+		return ConformanceResult{Status: ConformanceError, Message: "pass glyph: " + err.Error()}
+	}
+	expected, hasHash := conformanceValue(test.Pass, profile)
+
+	if positionX, positionY, found := findGlyph(core, regions, failGlyph); found {
+		result.Status, result.Message = ConformanceFail, fmt.Sprintf("fail glyph at %d,%d", positionX, positionY)
+		return result
+	}
+	switch {
+	case hasHash && strings.EqualFold(expected, result.Hash):
+		result.Status = ConformancePass
+	case hasHash:
+		result.Status, result.Message = ConformanceFail, fmt.Sprintf("screen %s, expected %s", result.Hash, expected)
+	case len(passGlyph) > 0:
+		if _, _, found := findGlyph(core, regions, passGlyph); found {
+			result.Status = ConformancePass
+		} else {
+			result.Status, result.Message = ConformanceFail, fmt.Sprintf("no pass glyph, screen %s", result.Hash)
+		}
+	case len(failGlyph) > 0:
+		result.Status = ConformancePass
+	default:
+		result.Status, result.Message = ConformanceUnknown, fmt.Sprintf("no expectation, screen %s", result.Hash)
+	}
+	return result
+}
+
+// conformanceValue looks a profile up in a map of a test, falling back on the "*" entry.
+func conformanceValue[Value any](values map[string]Value, profile string) (Value, bool) {
+	if value, exists := values[profile]; exists {
+		return value, true
+	}
+	value, exists := values["*"]
+	return value, exists
+}
+
+// screenRegionsHash hashes the pixels of the regions, one byte per pixel, row by row. Pixels outside of the
+// screen count as unlit.
+func screenRegionsHash(core *chip8.Chip8Core, regions []ScreenRegion) string {
+	hash := sha1.New()
+	for _, region := range regions {
+		for positionY := region.Y; positionY < region.Y+region.Height; positionY++ {
+			row := make([]byte, region.Width)
+			for column := range row {
+				if screenLit(core, region.X+column, positionY) {
+					row[column] = 1
+				}
+			}
+			hash.Write(row)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func screenLit(core *chip8.Chip8Core, positionX int, positionY int) bool {
+	if positionX < 0 || positionY < 0 || positionX >= core.ScreenWidth() || positionY >= core.ScreenHeight() {
+		return false
+	}
+	return core.GetPixel(uint8(positionX), uint8(positionY))
+}
+
+// parseGlyph reads the rows of a sprite given in hexadecimal, a bit per pixel with the leftmost pixel in the
+// most significant bit, as DXYN draws them.
+func parseGlyph(rows []string) ([]byte, error) {
+	glyph := make([]byte, 0, len(rows))
+	for _, row := range rows {
+		value, err := strconv.ParseUint(row, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid row %q", row)
+		}
+		glyph = append(glyph, byte(value))
+	}
+	return glyph, nil
+}
+
+// findGlyph searches the regions for the glyph, its lit and unlit pixels both matching, and returns where its
+// top left corner is. The columns on the right that are unlit in every row of the glyph are not compared,
+// so a glyph narrower than 8 pixels still matches next to another one.
+func findGlyph(core *chip8.Chip8Core, regions []ScreenRegion, glyph []byte) (int, int, bool) {
+	var columns byte
+	for _, row := range glyph {
+		columns |= row
+	}
+	if columns == 0 {
+		return 0, 0, false
+	}
+	width := 8
+	for columns&1 == 0 {
+		columns >>= 1
+		width--
+	}
+	for _, region := range regions {
+		for positionY := region.Y; positionY+len(glyph) <= region.Y+region.Height; positionY++ {
+			for positionX := region.X; positionX+width <= region.X+region.Width; positionX++ {
+				if glyphAt(core, glyph, width, positionX, positionY) {
+					return positionX, positionY, true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func glyphAt(core *chip8.Chip8Core, glyph []byte, width int, positionX int, positionY int) bool {
+	for row, bits := range glyph {
+		for column := 0; column < width; column++ {
+			if screenLit(core, positionX+column, positionY+row) != (bits&(0x80>>column) != 0) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func writeConformanceScreen(path string, core *chip8.Chip8Core) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	palette := color.Palette{color.RGBA{A: 0xFF}, color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}}
+	return png.Encode(file, ColorScreenImage(core, 4, palette))
+}
+
+// ConformanceFailed tells whether any test failed or could not run, the condition for a failing exit status.
+func ConformanceFailed(results []ConformanceResult) bool {
+	return slices.ContainsFunc(results, func(result ConformanceResult) bool {
+		return result.Status == ConformanceFail || result.Status == ConformanceError
+	})
+}
+
+// WriteConformanceMatrix writes the results as a table, a row per test and a column per profile, followed by
+// the explanation of every result that is not a pass.
+func WriteConformanceMatrix(writer io.Writer, results []ConformanceResult) error {
+	var tests, profiles []string
+	statuses := map[[2]string]ConformanceStatus{}
+	nameWidth := len("TEST")
+	for _, result := range results {
+		if !slices.Contains(tests, result.Test) {
+			tests = append(tests, result.Test)
+		}
+		if !slices.Contains(profiles, result.Profile) {
+			profiles = append(profiles, result.Profile)
+		}
+		statuses[[2]string{result.Test, result.Profile}] = result.Status
+		nameWidth = max(nameWidth, len(result.Test))
+	}
+
+	fmt.Fprintf(writer, "%-*s", nameWidth, "TEST")
+	for _, profile := range profiles {
+		fmt.Fprintf(writer, "  %-8s", profile)
+	}
+	fmt.Fprintln(writer)
+	for _, test := range tests {
+		fmt.Fprintf(writer, "%-*s", nameWidth, test)
+		for _, profile := range profiles {
+			status, exists := statuses[[2]string{test, profile}]
+			if !exists {
+				status = "-"
+			}
+			fmt.Fprintf(writer, "  %-8s", status)
+		}
+		fmt.Fprintln(writer)
+	}
+
+	counts := map[ConformanceStatus]int{}
+	first := true
+	for _, result := range results {
+		counts[result.Status]++
+		if result.Status == ConformancePass {
+			continue
+		}
+		if first {
+			fmt.Fprintln(writer)
+			first = false
+		}
+		fmt.Fprintf(writer, "%s [%s] %s: %s\n", result.Test, result.Profile, result.Status, result.Message)
+	}
+	_, err := fmt.Fprintf(writer, "\n%d passed, %d failed, %d errors, %d unknown, %d skipped\n", counts[ConformancePass],
+		counts[ConformanceFail], counts[ConformanceError], counts[ConformanceUnknown], counts[ConformanceSkipped])
+	return err
+}
+
+// The elements of JUnit XML reports, as read by CI servers.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnitReport writes the results in the JUnit XML format, a test suite per profile. Unknown results are
+// reported as skipped, with the hash of the screen in the message.
+func WriteJUnitReport(writer io.Writer, results []ConformanceResult) error {
+	report := junitTestSuites{}
+	suites := map[string]int{}
+	durations := map[string]time.Duration{}
+	for _, result := range results {
+		index, exists := suites[result.Profile]
+		if !exists {
+			index = len(report.Suites)
+			suites[result.Profile] = index
+			report.Suites = append(report.Suites, junitTestSuite{Name: result.Profile})
+		}
+		suite := &report.Suites[index]
+		testCase := junitTestCase{
+			Name:      result.Test,
+			ClassName: "conformance." + result.Profile,
+			Time:      fmt.Sprintf("%.3f", result.Duration.Seconds()),
+		}
+		message := &junitMessage{Message: result.Message}
+		switch result.Status {
+		case ConformanceFail:
+			testCase.Failure = message
+			suite.Failures++
+		case ConformanceError:
+			testCase.Error = message
+			suite.Errors++
+		case ConformanceUnknown, ConformanceSkipped:
+			testCase.Skipped = message
+			suite.Skipped++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+		durations[result.Profile] += result.Duration
+	}
+	for index := range report.Suites {
+		suite := &report.Suites[index]
+		suite.Time = fmt.Sprintf("%.3f", durations[suite.Name].Seconds())
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+	}
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "\t")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, "\n")
+	return err
+}
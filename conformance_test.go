@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// conformanceDirectory holds a small suite covering every outcome of a test ROM.
+const conformanceDirectory = "testdata/conformance"
+
+func TestRunConformanceSuite(t *testing.T) {
+	suite, err := LoadConformanceSuite(conformanceDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	screenDirectory := t.TempDir()
+	results := RunConformanceSuite(conformanceDirectory, suite, screenDirectory)
+
+	expected := []struct {
+		Test    string
+		Profile string
+		Status  ConformanceStatus
+		Message string
+	}{
+		{"pass", "chip8", ConformancePass, ""},
+		{"pass", "vip", ConformancePass, ""},
+		{"fail", "chip8", ConformanceFail, "fail glyph at 0,0"},
+		{"fail", "vip", ConformanceFail, "fail glyph at 0,0"},
+		// Only the chip8 profile answers the menu.
+		{"menu", "chip8", ConformancePass, ""},
+		{"menu", "vip", ConformanceFail, "no pass glyph"},
+		{"blank", "chip8", ConformanceUnknown, "no expectation"},
+		{"blank", "vip", ConformanceSkipped, "not on the VIP"},
+		{"missing", "chip8", ConformanceError, "missing.ch8"},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(expected), results)
+	}
+	for index, result := range results {
+		want := expected[index]
+		if result.Test != want.Test || result.Profile != want.Profile || result.Status != want.Status || !strings.Contains(result.Message, want.Message) {
+			t.Errorf("result %d is %s [%s] %s: %q, want %s [%s] %s: %q", index, result.Test, result.Profile, result.Status,
+				result.Message, want.Test, want.Profile, want.Status, want.Message)
+		}
+	}
+	if !ConformanceFailed(results) {
+		t.Error("the suite did not fail")
+	}
+	if _, err := os.Stat(filepath.Join(screenDirectory, "pass.vip.png")); err != nil {
+		t.Errorf("the screen of pass under vip was not saved: %v", err)
+	}
+
+	var matrix bytes.Buffer
+	if err := WriteConformanceMatrix(&matrix, results); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"pass     pass      pass", "missing  ERROR     -", "3 passed, 3 failed, 1 errors, 1 unknown, 1 skipped"} {
+		if !strings.Contains(matrix.String(), line) {
+			t.Errorf("the matrix lacks %q:\n%s", line, matrix.String())
+		}
+	}
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	suite, err := LoadConformanceSuite(conformanceDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	if err := WriteJUnitReport(&output, RunConformanceSuite(conformanceDirectory, suite, "")); err != nil {
+		t.Fatal(err)
+	}
+	var report junitTestSuites
+	if err := xml.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("the report is not XML: %v\n%s", err, output.String())
+	}
+	if report.Tests != 9 || report.Failures != 3 || report.Errors != 1 || report.Skipped != 2 || len(report.Suites) != 2 {
+		t.Fatalf("the report counts %d tests, %d failures, %d errors and %d skipped in %d suites, want 9, 3, 1 and 2 in 2",
+			report.Tests, report.Failures, report.Errors, report.Skipped, len(report.Suites))
+	}
+	chip8Suite := report.Suites[0]
+	if chip8Suite.Name != "chip8" || chip8Suite.Tests != 5 || chip8Suite.Failures != 1 || chip8Suite.Errors != 1 || chip8Suite.Skipped != 1 {
+		t.Errorf("the chip8 suite is %+v", chip8Suite)
+	}
+	for _, testCase := range chip8Suite.Cases {
+		if testCase.ClassName != "conformance.chip8" {
+			t.Errorf("%s has the class %q, want conformance.chip8", testCase.Name, testCase.ClassName)
+		}
+		failed := testCase.Failure != nil || testCase.Error != nil
+		if failed != (testCase.Name == "fail" || testCase.Name == "missing") {
+			t.Errorf("%s is reported with failure %v and error %v", testCase.Name, testCase.Failure, testCase.Error)
+		}
+	}
+}
+
+// TestLoadConformanceSuiteWithoutManifest checks that without a manifest the programs of the directory are the tests.
+func TestLoadConformanceSuiteWithoutManifest(t *testing.T) {
+	directory := t.TempDir()
+	for _, name := range []string{"a.ch8", "b.8o", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(directory, name), []byte{0x12, 0x00}, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	suite, err := LoadConformanceSuite(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suite.Tests) != 2 || suite.Tests[0].ROM != "a.ch8" || suite.Tests[1].ROM != "b.8o" {
+		t.Errorf("the tests are %+v, want a.ch8 and b.8o", suite.Tests)
+	}
+	if profiles := suite.ConformanceProfiles(); len(profiles) != 2 || profiles[0] != "chip8" || profiles[1] != "vip" {
+		t.Errorf("the profiles are %v, want chip8 and vip", profiles)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// CoverageFlags tells how a byte of Memory was used by the program.
+type CoverageFlags byte
+
+const (
+	CoverageExecuted CoverageFlags = 1 << iota // CoverageExecuted marks the bytes of the executed instructions.
+	CoverageRead                               // CoverageRead marks the bytes loaded into registers.
+	CoverageWritten                            // CoverageWritten marks the bytes stored by the program.
+	CoverageDrawn                              // CoverageDrawn marks the bytes drawn as sprites.
+)
+
+// String gives the flags as four letters, XRWD, with a dash for each flag that is not set.
+func (flags CoverageFlags) String() string {
+	letters := []byte("----")
+	for index, letter := range []byte("XRWD") {
+		if flags&(1<<index) != 0 {
+			letters[index] = letter
+		}
+	}
+	return string(letters)
+}
+
+// Coverage records how every byte of Memory is used while a program runs, to find the code a test never
+// reached. The coverage of several runs of the same program can be merged, each run adding its flags.
+type Coverage struct {
+	ROM   string          `json:"rom"`   // ROM is the SHA-1 of the program, so only runs of the same program get merged.
+	Flags []CoverageFlags `json:"flags"` // Flags are the flags of every byte of Memory.
+
+	machine *chip8.Machine
+	drawing bool
+}
+
+// NewCoverage starts recording the coverage of the program loaded in the machine.
+func NewCoverage(machine *chip8.Machine) *Coverage {
+	coverage := &Coverage{
+		ROM:     chip8.ROMHash(machine.ROM()),
+		Flags:   make([]CoverageFlags, len(machine.Core.Memory)),
+		machine: machine,
+	}
+	machine.OnStep(func(address uint16) {
+		coverage.mark(uint32(address), CoverageExecuted)
+		coverage.mark(uint32(address)+1, CoverageExecuted)
+		memory := machine.Core.Memory
+		coverage.drawing = int(address) < len(memory) && memory[address]&0xF0 == 0xD0
+	})
+	machine.OnMemoryRead(func(address uint32) {
+		if coverage.drawing {
+			coverage.mark(address, CoverageDrawn)
+		} else {
+			coverage.mark(address, CoverageRead)
+		}
+	})
+	machine.OnMemoryWrite(func(address uint32, value byte) {
+		coverage.mark(address, CoverageWritten)
+	})
+	return coverage
+}
+
+func (coverage *Coverage) mark(address uint32, flags CoverageFlags) {
+	if int(address) < len(coverage.Flags) {
+		coverage.Flags[address] |= flags
+	}
+}
+
+// Merge adds the flags of another run of the same program.
+func (coverage *Coverage) Merge(other *Coverage) error {
+	if other.ROM != coverage.ROM {
+		return fmt.Errorf("coverage of another program")
+	}
+	if len(other.Flags) != len(coverage.Flags) {
+		return fmt.Errorf("coverage of %d bytes of memory, expected %d", len(other.Flags), len(coverage.Flags))
+	}
+	for address, flags := range other.Flags {
+		coverage.Flags[address] |= flags
+	}
+	return nil
+}
+
+// MergeFile merges the coverage saved in a file by WriteFile; a missing file is an empty coverage.
+func (coverage *Coverage) MergeFile(path string) error {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var other Coverage
+	if err := json.Unmarshal(content, &other); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := coverage.Merge(&other); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// WriteFile saves the coverage as JSON.
+func (coverage *Coverage) WriteFile(path string) error {
+	content, err := json.Marshal(coverage)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o644)
+}
+
+// programRange returns the addresses of the program in Memory, from its load address to its end.
+func (coverage *Coverage) programRange() (int, int) {
+	start := int(coverage.machine.Platform.LoadAddress)
+	return start, min(start+len(coverage.machine.ROM()), len(coverage.Flags))
+}
+
+// usedRange returns the addresses spanning the program and every byte used, in whole rows of the HTML map, so
+// a MegaChip memory of megabytes is not mapped byte by byte when only its start is used.
+func (coverage *Coverage) usedRange() (int, int) {
+	start, end := coverage.programRange()
+	first := slices.IndexFunc(coverage.Flags, func(flags CoverageFlags) bool { return flags != 0 })
+	if first >= 0 {
+		last := len(coverage.Flags) - 1
+		for coverage.Flags[last] == 0 {
+			last--
+		}
+		start, end = min(start, first), max(end, last+1)
+	}
+	start -= start % coverageHTMLColumns
+	end = min(end+(coverageHTMLColumns-end%coverageHTMLColumns)%coverageHTMLColumns, len(coverage.Flags))
+	return start, end
+}
+
+// WriteListing writes the disassembly of the program with the flags of every instruction, a line per
+// instruction, followed by a summary. Bytes skipped by an instruction starting at an odd address are listed
+// on their own as data.
+func (coverage *Coverage) WriteListing(writer io.Writer) error {
+	machine := coverage.machine
+	labels := map[int][]string{}
+	if machine.Program != nil {
+		for label, address := range machine.Program.Labels {
+			labels[int(address)] = append(labels[int(address)], label)
+		}
+	}
+	start, end := coverage.programRange()
+	rom := machine.ROM()
+	instructions, executed := 0, 0
+	for address := start; address < end; {
+		slices.Sort(labels[address])
+		for _, label := range labels[address] {
+			fmt.Fprintf(writer, "%s:\n", label)
+		}
+		if address+1 >= end || coverage.Flags[address]&CoverageExecuted == 0 && coverage.Flags[address+1]&CoverageExecuted != 0 {
+			fmt.Fprintf(writer, "%04X  %02X    %s  DB #%02X\n", address, rom[address-start], coverage.Flags[address], rom[address-start])
+			address++
+			continue
+		}
+		opcode := uint16(rom[address-start])<<8 | uint16(rom[address+1-start])
+		flags := coverage.Flags[address] | coverage.Flags[address+1]
+		fmt.Fprintf(writer, "%04X  %04X  %s  %s\n", address, opcode, flags, machine.Decoder.Decode(opcode))
+		instructions++
+		if flags&CoverageExecuted != 0 {
+			executed++
+		}
+		address += 2
+	}
+
+	fmt.Fprintf(writer, "\n%d of %d instructions executed (%.1f%%)\n", executed, instructions, 100*float64(executed)/float64(max(instructions, 1)))
+	for _, flag := range []CoverageFlags{CoverageRead, CoverageWritten, CoverageDrawn} {
+		count := 0
+		for address := start; address < end; address++ {
+			if coverage.Flags[address]&flag != 0 {
+				count++
+			}
+		}
+		fmt.Fprintf(writer, "%d of %d bytes %s\n", count, end-start, coverageFlagNames[flag])
+	}
+	return nil
+}
+
+var coverageFlagNames = map[CoverageFlags]string{
+	CoverageExecuted: "executed",
+	CoverageRead:     "read",
+	CoverageWritten:  "written",
+	CoverageDrawn:    "drawn",
+}
+
+// coverageColors are the colors of the HTML map, the first flag set giving the color of a byte.
+var coverageColors = []struct {
+	flag  CoverageFlags
+	color string
+}{
+	{CoverageExecuted, "#2E9E44"},
+	{CoverageDrawn, "#8E4EC6"},
+	{CoverageWritten, "#D1442E"},
+	{CoverageRead, "#2E6FD1"},
+}
+
+// coverageHTMLColumns is the number of bytes per row of the HTML map.
+const coverageHTMLColumns = 64
+
+// WriteHTML writes a map of the memory spanned by the program and the bytes it used, 64 bytes per row, colored
+// by how each byte was used, with the program outlined. Hovering a byte shows its address, value and flags.
+func (coverage *Coverage) WriteHTML(writer io.Writer) error {
+	start, end := coverage.programRange()
+	mapStart, mapEnd := coverage.usedRange()
+	memory := coverage.machine.Core.Memory
+	var page strings.Builder
+	page.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>Coverage</title><style>\n")
+	page.WriteString("body{font-family:monospace;background:#111;color:#ddd}table{border-collapse:collapse}\n")
+	page.WriteString("td{width:10px;height:10px;padding:0;background:#222;border:1px solid #111}td.rom{border-color:#666}\n")
+	page.WriteString("th{font-weight:normal;padding-right:6px;text-align:right}span{display:inline-block;width:10px;height:10px}\n")
+	page.WriteString("</style></head><body>\n<p>")
+	for _, entry := range coverageColors {
+		fmt.Fprintf(&page, "<span style=\"background:%s\"></span> %s ", entry.color, coverageFlagNames[entry.flag])
+	}
+	page.WriteString("</p>\n<table>\n")
+	for row := mapStart; row < mapEnd; row += coverageHTMLColumns {
+		fmt.Fprintf(&page, "<tr><th>%04X</th>", row)
+		for address := row; address < min(row+coverageHTMLColumns, mapEnd); address++ {
+			flags := coverage.Flags[address]
+			page.WriteString("<td")
+			if address >= start && address < end {
+				page.WriteString(" class=\"rom\"")
+			}
+			for _, entry := range coverageColors {
+				if flags&entry.flag != 0 {
+					fmt.Fprintf(&page, " style=\"background:%s\"", entry.color)
+					break
+				}
+			}
+			fmt.Fprintf(&page, " title=\"%04X %02X %s\"></td>", address, memory[address], flags)
+		}
+		page.WriteString("</tr>\n")
+	}
+	page.WriteString("</table>\n</body></html>\n")
+	_, err := io.WriteString(writer, page.String())
+	return err
+}
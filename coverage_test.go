@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// TestCoverageHTML checks that the map of a MegaChip memory only spans the program and the bytes it used.
+func TestCoverageHTML(t *testing.T) {
+	machine := chip8.NewMachine(chip8.NewManualClock())
+	// 0200: I := 0x300, 0202: save V0, 0204: jump 0204
+	program := []byte{0xA3, 0x00, 0xF0, 0x55, 0x12, 0x04}
+	if err := machine.LoadROMImage(&chip8.ROMImage{Data: program, Platform: chip8.Platforms["megachip"]}); err != nil {
+		t.Fatal(err)
+	}
+	coverage := NewCoverage(machine)
+	machine.RunFrame()
+
+	var page strings.Builder
+	if err := coverage.WriteHTML(&page); err != nil {
+		t.Fatal(err)
+	}
+	// The rows from 0200 to 033F hold the program and the byte written at 0300.
+	if cells := strings.Count(page.String(), "<td"); cells != 5*coverageHTMLColumns {
+		t.Errorf("the map has %d bytes, want %d", cells, 5*coverageHTMLColumns)
+	}
+	if !strings.Contains(page.String(), "<th>0200</th>") || !strings.Contains(page.String(), "<th>0300</th>") || strings.Contains(page.String(), "<th>0340</th>") {
+		t.Error("the map does not span the rows from 0200 to 0300")
+	}
+}
+
+// coveredProgram takes one path without a key and another with key 0:
+// 0200: if v0 key then jump 020A, 0204: i := 0210, 0206: load v0, 0208: jump 0208,
+// 020A: i := 0210, 020C: sprite v0 v0 1, 020E: jump 020E, 0210: the sprite
+var coveredProgram = []byte{0xE0, 0xA1, 0x12, 0x0A, 0xA2, 0x10, 0xF0, 0x65, 0x12, 0x08, 0xA2, 0x10, 0xD0, 0x01, 0x12, 0x0E, 0x80}
+
+// runCoverage records the coverage of a frame of coveredProgram, with key 0 pressed or not.
+func runCoverage(t *testing.T, key bool) *Coverage {
+	t.Helper()
+	machine := chip8.NewMachine(chip8.NewManualClock())
+	if err := machine.LoadROM(coveredProgram); err != nil {
+		t.Fatal(err)
+	}
+	coverage := NewCoverage(machine)
+	machine.Core.SetKey(0, key)
+	machine.RunFrame()
+	return coverage
+}
+
+func TestCoverageMerge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	first := runCoverage(t, false)
+	if err := first.MergeFile(path); err != nil {
+		t.Fatalf("merging a missing file failed: %v", err)
+	}
+	if err := first.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	second := runCoverage(t, true)
+	if err := second.MergeFile(path); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[uint32]CoverageFlags{
+		0x200: CoverageExecuted, 0x202: CoverageExecuted, 0x204: CoverageExecuted, 0x206: CoverageExecuted,
+		0x208: CoverageExecuted, 0x20A: CoverageExecuted, 0x20C: CoverageExecuted, 0x20E: CoverageExecuted,
+		0x210: CoverageRead | CoverageDrawn, 0x211: 0,
+	}
+	for address, flags := range expected {
+		if second.Flags[address] != flags {
+			t.Errorf("%04X is %s after merging both runs, want %s", address, second.Flags[address], flags)
+		}
+	}
+
+	other := NewCoverage(chip8.NewMachine(chip8.NewManualClock()))
+	if err := other.MergeFile(path); err == nil || !strings.Contains(err.Error(), "another program") {
+		t.Errorf("merging the coverage of another program gave the error %v", err)
+	}
+}
+
+// TestCoverageFile checks that WriteFile and MergeFile give back the same flags.
+func TestCoverageFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	written := runCoverage(t, true)
+	if err := written.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	read := NewCoverage(written.machine)
+	read.Flags = make([]CoverageFlags, len(written.Flags))
+	if err := read.MergeFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if read.ROM != written.ROM || !slices.Equal(read.Flags, written.Flags) {
+		t.Error("the coverage read back differs from the one written")
+	}
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := read.MergeFile(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("merging a broken file gave the error %v, want one naming it", err)
+	}
+}
+
+func TestCoverageListing(t *testing.T) {
+	coverage := runCoverage(t, false)
+	var listing strings.Builder
+	if err := coverage.WriteListing(&listing); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"0200  E0A1  X---  SKNP V0\n",
+		"0202  120A  ----  JP #20A\n",
+		"0206  F065  X---  LD V0, [I]\n",
+		"020C  D001  ----  DRW V0, V0, 1\n",
+		"0210  80    -R--  DB #80\n",
+		"4 of 8 instructions executed (50.0%)\n",
+		"1 of 17 bytes read\n",
+		"0 of 17 bytes written\n",
+		"0 of 17 bytes drawn\n",
+	} {
+		if !strings.Contains(listing.String(), line) {
+			t.Errorf("the listing lacks %q:\n%s", line, listing.String())
+		}
+	}
+}
@@ -0,0 +1,612 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// The variables references of the scopes shown for every stack frame.
+const (
+	dapRegistersReference = 1 + iota
+	dapTimersReference
+	dapStackReference
+)
+
+// dapThreadID is the only thread of the machine.
+const dapThreadID = 1
+
+// The most a single readMemory or disassemble request returns, whatever count the client asks for.
+const (
+	dapMaxReadCount        = 65536
+	dapMaxInstructionCount = 4096
+)
+
+// dapMaxMessageLength is the largest message a client may send, far above any request it has a reason to.
+const dapMaxMessageLength = 1 << 20
+
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type dapResponse struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+type dapEvent struct {
+	Seq   int    `json:"seq"`
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+type dapSource struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+// DAPServer lets editors speaking the Debug Adapter Protocol debug programs running on the machine,
+// over a local TCP socket or stdio, one session at a time. Breakpoints can be set by address, or by line
+// in the Octo source the program was assembled from. Stepping works one instruction at a time; stepping
+// over a call or out of a subroutine runs the machine until the return address is reached.
+type DAPServer struct {
+	machine *chip8.Machine
+	session *dapSession
+}
+
+func NewDAPServer(machine *chip8.Machine) *DAPServer {
+	dapServer := &DAPServer{machine: machine}
+	machine.OnBreak(func(address uint16) {
+		if dapServer.session != nil {
+			dapServer.session.stoppedAt(address)
+		}
+	})
+	machine.OnHalt(func(reason string) {
+		if session := dapServer.session; session != nil {
+			go func() {
+				session.sendEvent("exited", map[string]any{"exitCode": 0})
+				session.sendEvent("terminated", nil)
+			}()
+		}
+	})
+	return dapServer
+}
+
+// ListenAndServe accepts sessions on a TCP address until the listener fails. An address without a host
+// listens on localhost only.
+func (dapServer *DAPServer) ListenAndServe(address string) error {
+	if strings.HasPrefix(address, ":") {
+		address = "localhost" + address
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	for {
+		connection, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		dapServer.Serve(connection, connection)
+		connection.Close()
+	}
+}
+
+// Serve runs a session reading requests from input and writing responses and events to output,
+// until the client disconnects or input ends.
+func (dapServer *DAPServer) Serve(input io.Reader, output io.Writer) error {
+	session := &dapSession{
+		dapServer:         dapServer,
+		output:            output,
+		sourceBreakpoints: map[string][]uint16{},
+		installed:         map[uint16]bool{},
+		stepTarget:        -1,
+	}
+	dapServer.machine.Lock()
+	dapServer.session = session
+	dapServer.machine.Unlock()
+	defer func() {
+		dapServer.machine.Lock()
+		dapServer.session = nil
+		session.replaceBreakpoints()
+		dapServer.machine.Unlock()
+	}()
+
+	reader := textproto.NewReader(bufio.NewReader(input))
+	for !session.disconnected {
+		header, err := reader.ReadMIMEHeader()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			return fmt.Errorf("invalid Content-Length header: %w", err)
+		}
+		if length < 0 || length > dapMaxMessageLength {
+			return fmt.Errorf("invalid Content-Length header: %d is not between 0 and %d", length, dapMaxMessageLength)
+		}
+		content := make([]byte, length)
+		if _, err := io.ReadFull(reader.R, content); err != nil {
+			return err
+		}
+		var request dapRequest
+		if err := json.Unmarshal(content, &request); err != nil {
+			return err
+		}
+		session.handle(request)
+	}
+	return nil
+}
+
+// dapSession is the state of a connected client. The fields other than output and seq are guarded by the
+// lock of the machine.
+type dapSession struct {
+	dapServer    *DAPServer
+	output       io.Writer
+	writeMutex   sync.Mutex
+	seq          int
+	disconnected bool
+
+	programPath            string
+	launched               bool
+	stopOnEntry            bool
+	sourceBreakpoints      map[string][]uint16
+	instructionBreakpoints []uint16
+	installed              map[uint16]bool
+	stepTarget             int // stepTarget is the return address a step over or out waits for, -1 when none.
+}
+
+func (session *dapSession) send(message any) {
+	content, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(session.output, "Content-Length: %d\r\n\r\n%s", len(content), content)
+}
+
+func (session *dapSession) sendEvent(event string, body any) {
+	session.writeMutex.Lock()
+	defer session.writeMutex.Unlock()
+	session.seq++
+	session.send(dapEvent{Seq: session.seq, Type: "event", Event: event, Body: body})
+}
+
+func (session *dapSession) respond(request dapRequest, body any, err error) {
+	session.writeMutex.Lock()
+	defer session.writeMutex.Unlock()
+	session.seq++
+	response := dapResponse{Seq: session.seq, Type: "response", RequestSeq: request.Seq, Command: request.Command, Success: err == nil, Body: body}
+	if err != nil {
+		response.Message = err.Error()
+	}
+	session.send(response)
+}
+
+func (session *dapSession) stopped(reason string) {
+	session.sendEvent("stopped", map[string]any{"reason": reason, "threadId": dapThreadID, "allThreadsStopped": true})
+}
+
+// stoppedAt is notified with the machine locked when a breakpoint pauses it; the event is sent from
+// another goroutine so the frame is not held up by the client.
+func (session *dapSession) stoppedAt(address uint16) {
+	reason := "breakpoint"
+	if session.stepTarget >= 0 {
+		if int(address) == session.stepTarget {
+			reason = "step"
+		}
+		session.stepTarget = -1
+		session.replaceBreakpoints()
+	}
+	go session.stopped(reason)
+}
+
+func (session *dapSession) handle(request dapRequest) {
+	machine := session.dapServer.machine
+	switch request.Command {
+	case "initialize":
+		session.respond(request, map[string]any{
+			"supportsConfigurationDoneRequest": true,
+			"supportsInstructionBreakpoints":   true,
+			"supportsReadMemoryRequest":        true,
+			"supportsDisassembleRequest":       true,
+			"supportsTerminateRequest":         true,
+		}, nil)
+		session.sendEvent("initialized", nil)
+		return
+	case "disconnect":
+		// A launched program ends with the session unless told otherwise; an attached one keeps running.
+		var arguments struct {
+			TerminateDebuggee *bool `json:"terminateDebuggee"`
+		}
+		json.Unmarshal(request.Arguments, &arguments)
+		terminate := session.launched
+		if arguments.TerminateDebuggee != nil {
+			terminate = *arguments.TerminateDebuggee
+		}
+		machine.Lock()
+		if terminate {
+			machine.Stop()
+		} else {
+			machine.Resume()
+		}
+		machine.Unlock()
+		session.disconnected = true
+		session.respond(request, nil, nil)
+		return
+	case "terminate":
+		machine.Lock()
+		machine.Stop()
+		machine.Unlock()
+		session.respond(request, nil, nil)
+		session.sendEvent("terminated", nil)
+		return
+	}
+	machine.Lock()
+	body, event, err := session.execute(request)
+	machine.Unlock()
+	session.respond(request, body, err)
+	if event != "" {
+		session.stopped(event)
+	}
+}
+
+// execute runs a request with the machine locked and returns the body of the response, and the reason of
+// the stopped event to send after it, if any.
+func (session *dapSession) execute(request dapRequest) (any, string, error) {
+	machine := session.dapServer.machine
+	core := machine.Core
+	switch request.Command {
+	case "launch", "attach":
+		var arguments struct {
+			Program     string `json:"program"`
+			Platform    string `json:"platform"`
+			StopOnEntry bool   `json:"stopOnEntry"`
+		}
+		if err := json.Unmarshal(request.Arguments, &arguments); err != nil {
+			return nil, "", err
+		}
+		if arguments.Program != "" {
+			if err := session.launch(arguments.Program, arguments.Platform); err != nil {
+				return nil, "", err
+			}
+		}
+		session.launched = request.Command == "launch"
+		session.stopOnEntry = arguments.StopOnEntry
+		session.stepTarget = -1
+		// The program waits for the configuration of the breakpoints.
+		machine.Pause()
+		return nil, "", nil
+	case "configurationDone":
+		if session.stopOnEntry {
+			return nil, "entry", nil
+		}
+		machine.Resume()
+		return nil, "", nil
+	case "setBreakpoints":
+		return session.setBreakpoints(request.Arguments)
+	case "setInstructionBreakpoints":
+		return session.setInstructionBreakpoints(request.Arguments)
+	case "threads":
+		return map[string]any{"threads": []map[string]any{{"id": dapThreadID, "name": "CHIP-8"}}}, "", nil
+	case "stackTrace":
+		return map[string]any{"stackFrames": session.stackFrames(), "totalFrames": int(core.SP) + 1}, "", nil
+	case "scopes":
+		return map[string]any{"scopes": []map[string]any{
+			{"name": "Registers", "variablesReference": dapRegistersReference, "expensive": false},
+			{"name": "Timers", "variablesReference": dapTimersReference, "expensive": false},
+			{"name": "Stack", "variablesReference": dapStackReference, "expensive": false},
+		}}, "", nil
+	case "variables":
+		var arguments struct {
+			VariablesReference int `json:"variablesReference"`
+		}
+		if err := json.Unmarshal(request.Arguments, &arguments); err != nil {
+			return nil, "", err
+		}
+		return map[string]any{"variables": session.variables(arguments.VariablesReference)}, "", nil
+	case "continue":
+		session.stepTarget = -1
+		session.replaceBreakpoints()
+		machine.Resume()
+		return map[string]any{"allThreadsContinued": true}, "", nil
+	case "pause":
+		machine.Pause()
+		return nil, "pause", nil
+	case "stepIn":
+		machine.Step()
+		return nil, "step", nil
+	case "next":
+		if _, call := machine.Decoder.Decode(core.FetchOpcode()).(*chip8.CallSubroutine); call {
+			return nil, session.runTo(core.PC + 2), nil
+		}
+		machine.Step()
+		return nil, "step", nil
+	case "stepOut":
+		if core.SP == 0 {
+			machine.Step()
+			return nil, "step", nil
+		}
+		return nil, session.runTo(core.Stack[core.SP-1] + 2), nil
+	case "readMemory":
+		return session.readMemory(request.Arguments)
+	case "disassemble":
+		return session.disassemble(request.Arguments)
+	}
+	return nil, "", fmt.Errorf("unsupported request %q", request.Command)
+}
+
+// launch loads the program the client asks for, which also becomes the source of line breakpoints
+// when it is Octo source.
+func (session *dapSession) launch(programPath string, platformName string) error {
+	romImage, err := chip8.ReadROMFile(programPath)
+	if err != nil {
+		return err
+	}
+	if platformName != "" {
+		if romImage.Platform, err = chip8.LookupPlatform(platformName); err != nil {
+			return err
+		}
+	}
+	if err := session.dapServer.machine.LoadROMImage(romImage); err != nil {
+		return err
+	}
+	session.programPath = programPath
+	return nil
+}
+
+// runTo resumes the machine until it reaches the address, or another breakpoint first.
+func (session *dapSession) runTo(address uint16) string {
+	session.stepTarget = int(address)
+	session.replaceBreakpoints()
+	session.dapServer.machine.Resume()
+	return ""
+}
+
+// replaceBreakpoints installs the breakpoints of the session in the machine in place of the previous ones.
+func (session *dapSession) replaceBreakpoints() {
+	machine := session.dapServer.machine
+	for address := range session.installed {
+		machine.ClearBreakpoint(address)
+	}
+	clear(session.installed)
+	if session.dapServer.session != session {
+		return
+	}
+	addresses := append([]uint16(nil), session.instructionBreakpoints...)
+	for _, sourceAddresses := range session.sourceBreakpoints {
+		addresses = append(addresses, sourceAddresses...)
+	}
+	if session.stepTarget >= 0 {
+		addresses = append(addresses, uint16(session.stepTarget))
+	}
+	for _, address := range addresses {
+		machine.SetBreakpoint(address)
+		session.installed[address] = true
+	}
+}
+
+// sameFile tells whether a source path of the client names the launched program.
+func (session *dapSession) sameFile(sourcePath string) bool {
+	if session.programPath == "" {
+		return false
+	}
+	programPath, programErr := filepath.Abs(session.programPath)
+	path, pathErr := filepath.Abs(sourcePath)
+	return programErr == nil && pathErr == nil && programPath == path
+}
+
+func (session *dapSession) setBreakpoints(rawArguments json.RawMessage) (any, string, error) {
+	var arguments struct {
+		Source      dapSource `json:"source"`
+		Breakpoints []struct {
+			Line int `json:"line"`
+		} `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(rawArguments, &arguments); err != nil {
+		return nil, "", err
+	}
+	program := session.dapServer.machine.Program
+	var addresses []uint16
+	breakpoints := []map[string]any{}
+	for _, requested := range arguments.Breakpoints {
+		breakpoint := map[string]any{"verified": false, "line": requested.Line}
+		if program == nil || !session.sameFile(arguments.Source.Path) {
+			breakpoint["message"] = "the program was not assembled from this source"
+		} else if address, line, found := program.LineAddress(requested.Line); !found {
+			breakpoint["message"] = "no instruction at or after this line"
+		} else {
+			addresses = append(addresses, address)
+			breakpoint["verified"] = true
+			breakpoint["line"] = line
+			breakpoint["instructionReference"] = fmt.Sprintf("0x%03X", address)
+		}
+		breakpoints = append(breakpoints, breakpoint)
+	}
+	session.sourceBreakpoints[arguments.Source.Path] = addresses
+	session.replaceBreakpoints()
+	return map[string]any{"breakpoints": breakpoints}, "", nil
+}
+
+func (session *dapSession) setInstructionBreakpoints(rawArguments json.RawMessage) (any, string, error) {
+	var arguments struct {
+		Breakpoints []struct {
+			InstructionReference string `json:"instructionReference"`
+			Offset               int    `json:"offset"`
+		} `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(rawArguments, &arguments); err != nil {
+		return nil, "", err
+	}
+	session.instructionBreakpoints = nil
+	breakpoints := []map[string]any{}
+	for _, requested := range arguments.Breakpoints {
+		address, err := strconv.ParseUint(requested.InstructionReference, 0, 32)
+		address += uint64(requested.Offset)
+		if err != nil || address >= uint64(len(session.dapServer.machine.Core.Memory)) {
+			breakpoints = append(breakpoints, map[string]any{"verified": false, "message": "invalid address"})
+			continue
+		}
+		session.instructionBreakpoints = append(session.instructionBreakpoints, uint16(address))
+		breakpoints = append(breakpoints, map[string]any{"verified": true, "instructionReference": fmt.Sprintf("0x%03X", address)})
+	}
+	session.replaceBreakpoints()
+	return map[string]any{"breakpoints": breakpoints}, "", nil
+}
+
+// location adds the source line of an address, when known, to a stack frame or disassembled instruction.
+func (session *dapSession) location(address uint16, fields map[string]any) {
+	program := session.dapServer.machine.Program
+	if program == nil || session.programPath == "" {
+		return
+	}
+	if line, found := program.Lines[address]; found {
+		fields["source"] = dapSource{Name: filepath.Base(session.programPath), Path: session.programPath}
+		fields["line"] = line
+	}
+}
+
+// stackFrames returns the frame of PC followed by the calls on the stack, innermost first.
+func (session *dapSession) stackFrames() []map[string]any {
+	machine := session.dapServer.machine
+	core := machine.Core
+	addresses := []uint16{core.PC}
+	for index := int(core.SP) - 1; index >= 0; index-- {
+		addresses = append(addresses, core.Stack[index])
+	}
+	var frames []map[string]any
+	for index, address := range addresses {
+		name := fmt.Sprintf("0x%03X", address)
+		if machine.Program != nil {
+			if symbol := machine.Program.Symbol(address); symbol != "" {
+				name = symbol
+			}
+		}
+		frame := map[string]any{
+			"id":                          index + 1,
+			"name":                        name,
+			"line":                        0,
+			"column":                      0,
+			"instructionPointerReference": fmt.Sprintf("0x%03X", address),
+		}
+		session.location(address, frame)
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func (session *dapSession) variables(reference int) []map[string]any {
+	core := session.dapServer.machine.Core
+	variable := func(name string, value string) map[string]any {
+		return map[string]any{"name": name, "value": value, "variablesReference": 0}
+	}
+	var variables []map[string]any
+	switch reference {
+	case dapRegistersReference:
+		for index, value := range core.V {
+			variables = append(variables, variable(fmt.Sprintf("V%X", index), fmt.Sprintf("0x%02X (%d)", value, value)))
+		}
+		variables = append(variables,
+			variable("I", fmt.Sprintf("0x%03X", core.I)),
+			variable("PC", fmt.Sprintf("0x%03X", core.PC)),
+			variable("SP", strconv.Itoa(int(core.SP))))
+	case dapTimersReference:
+		variables = append(variables,
+			variable("DT", strconv.Itoa(int(core.DelayTimer))),
+			variable("ST", strconv.Itoa(int(core.SoundTimer))))
+	case dapStackReference:
+		for index := 0; index < int(core.SP) && index < len(core.Stack); index++ {
+			variables = append(variables, variable(strconv.Itoa(index), fmt.Sprintf("0x%03X", core.Stack[index])))
+		}
+	}
+	return variables
+}
+
+func (session *dapSession) readMemory(rawArguments json.RawMessage) (any, string, error) {
+	var arguments struct {
+		MemoryReference string `json:"memoryReference"`
+		Offset          int    `json:"offset"`
+		Count           int    `json:"count"`
+	}
+	if err := json.Unmarshal(rawArguments, &arguments); err != nil {
+		return nil, "", err
+	}
+	base, err := strconv.ParseUint(arguments.MemoryReference, 0, 32)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid memory reference %q", arguments.MemoryReference)
+	}
+	// A larger count is answered with its first bytes; the client asks again for the rest.
+	count := min(max(arguments.Count, 0), dapMaxReadCount)
+	memory := session.dapServer.machine.Core.Memory
+	start := min(max(int(base)+arguments.Offset, 0), len(memory))
+	end := min(start+count, len(memory))
+	return map[string]any{
+		"address":         fmt.Sprintf("0x%03X", start),
+		"data":            base64.StdEncoding.EncodeToString(memory[start:end]),
+		"unreadableBytes": count - (end - start),
+	}, "", nil
+}
+
+func (session *dapSession) disassemble(rawArguments json.RawMessage) (any, string, error) {
+	var arguments struct {
+		MemoryReference   string `json:"memoryReference"`
+		Offset            int    `json:"offset"`
+		InstructionOffset int    `json:"instructionOffset"`
+		InstructionCount  int    `json:"instructionCount"`
+	}
+	if err := json.Unmarshal(rawArguments, &arguments); err != nil {
+		return nil, "", err
+	}
+	base, err := strconv.ParseUint(arguments.MemoryReference, 0, 32)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid memory reference %q", arguments.MemoryReference)
+	}
+	if arguments.InstructionCount > dapMaxInstructionCount {
+		return nil, "", fmt.Errorf("cannot disassemble more than %d instructions at once", dapMaxInstructionCount)
+	}
+	machine := session.dapServer.machine
+	memory := machine.Core.Memory
+	address := int(base) + arguments.Offset + 2*arguments.InstructionOffset
+	instructions := []map[string]any{}
+	for count := 0; count < arguments.InstructionCount; count, address = count+1, address+2 {
+		if address < 0 || address+1 >= len(memory) {
+			instructions = append(instructions, map[string]any{"address": fmt.Sprintf("0x%03X", max(address, 0)), "instruction": "??", "presentationHint": "invalid"})
+			continue
+		}
+		opcode := uint16(memory[address])<<8 | uint16(memory[address+1])
+		instruction := map[string]any{
+			"address":          fmt.Sprintf("0x%03X", address),
+			"instructionBytes": fmt.Sprintf("%02X %02X", memory[address], memory[address+1]),
+			"instruction":      machine.Decoder.Decode(opcode).String(),
+		}
+		if machine.Program != nil {
+			if symbol := machine.Program.Symbol(uint16(address)); symbol != "" && !strings.Contains(symbol, "+") {
+				instruction["symbol"] = symbol
+			}
+		}
+		session.location(uint16(address), instruction)
+		instructions = append(instructions, instruction)
+	}
+	return map[string]any{"instructions": instructions}, "", nil
+}
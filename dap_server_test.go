@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// dapMessage holds the fields of the responses and events a test looks at.
+type dapMessage struct {
+	Type       string          `json:"type"`
+	Command    string          `json:"command"`
+	Event      string          `json:"event"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// dapClient speaks the Debug Adapter Protocol to a server through pipes, running the frames of the machine
+// itself while it waits for an event.
+type dapClient struct {
+	t        *testing.T
+	machine  *chip8.Machine
+	input    io.WriteCloser
+	messages chan dapMessage
+	events   []dapMessage
+	seq      int
+}
+
+func startDAPServer(t *testing.T) *dapClient {
+	t.Helper()
+	machine := chip8.NewMachine(chip8.NewManualClock())
+	dapServer := NewDAPServer(machine)
+	requestReader, requestWriter := io.Pipe()
+	responseReader, responseWriter := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- dapServer.Serve(requestReader, responseWriter)
+		responseWriter.Close()
+	}()
+	client := &dapClient{t: t, machine: machine, input: requestWriter, messages: make(chan dapMessage, 16)}
+	go func() {
+		defer close(client.messages)
+		reader := textproto.NewReader(bufio.NewReader(responseReader))
+		for {
+			header, err := reader.ReadMIMEHeader()
+			if err != nil {
+				return
+			}
+			length, _ := strconv.Atoi(header.Get("Content-Length"))
+			content := make([]byte, length)
+			if _, err := io.ReadFull(reader.R, content); err != nil {
+				return
+			}
+			var message dapMessage
+			if err := json.Unmarshal(content, &message); err != nil {
+				t.Errorf("invalid message %q: %v", content, err)
+				return
+			}
+			client.messages <- message
+		}
+	}()
+	t.Cleanup(func() {
+		requestWriter.Close()
+		if err := <-served; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	})
+	return client
+}
+
+// request sends a request and returns its response, keeping the events that come first.
+func (client *dapClient) request(command string, arguments any) dapMessage {
+	client.t.Helper()
+	client.seq++
+	content, err := json.Marshal(map[string]any{"seq": client.seq, "type": "request", "command": command, "arguments": arguments})
+	if err != nil {
+		client.t.Fatal(err)
+	}
+	fmt.Fprintf(client.input, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	for {
+		select {
+		case message, open := <-client.messages:
+			if !open {
+				client.t.Fatalf("the server closed the session before answering %s", command)
+			}
+			if message.Type == "event" {
+				client.events = append(client.events, message)
+				continue
+			}
+			if message.RequestSeq != client.seq || message.Command != command {
+				client.t.Fatalf("got the response to %s %d, want %s %d", message.Command, message.RequestSeq, command, client.seq)
+			}
+			return message
+		case <-time.After(5 * time.Second):
+			client.t.Fatalf("no response to %s", command)
+		}
+	}
+}
+
+// succeed sends a request that must succeed and decodes the body of its response into body, when not nil.
+func (client *dapClient) succeed(command string, arguments any, body any) {
+	client.t.Helper()
+	response := client.request(command, arguments)
+	if !response.Success {
+		client.t.Fatalf("%s failed: %s", command, response.Message)
+	}
+	if body != nil {
+		if err := json.Unmarshal(response.Body, body); err != nil {
+			client.t.Fatal(err)
+		}
+	}
+}
+
+// waitStopped runs frames until the server sends a stopped event, and returns its reason.
+func (client *dapClient) waitStopped() string {
+	client.t.Helper()
+	for frame := 0; frame < 600; frame++ {
+		for len(client.events) > 0 {
+			event := client.events[0]
+			client.events = client.events[1:]
+			if event.Event == "stopped" {
+				var body struct {
+					Reason string `json:"reason"`
+				}
+				json.Unmarshal(event.Body, &body)
+				return body.Reason
+			}
+		}
+		client.machine.RunFrame()
+		select {
+		case message := <-client.messages:
+			client.events = append(client.events, message)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	client.t.Fatal("the machine did not stop")
+	return ""
+}
+
+func TestDAPServer(t *testing.T) {
+	source := ": main\n  sub\n  v0 += 1\n  jump main\n: sub\n  v1 += 1\n  return\n"
+	programPath := filepath.Join(t.TempDir(), "game.8o")
+	if err := os.WriteFile(programPath, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	client := startDAPServer(t)
+	machine := client.machine
+
+	var capabilities map[string]bool
+	client.succeed("initialize", map[string]any{"adapterID": "chip8"}, &capabilities)
+	if !capabilities["supportsDisassembleRequest"] || !capabilities["supportsReadMemoryRequest"] {
+		t.Errorf("capabilities are %v", capabilities)
+	}
+	client.succeed("launch", map[string]any{"program": programPath, "stopOnEntry": true}, nil)
+	if len(client.events) != 1 || client.events[0].Event != "initialized" {
+		t.Errorf("events before the launch response are %v, want initialized", client.events)
+	}
+	client.events = nil
+
+	var breakpoints struct {
+		Breakpoints []struct {
+			Verified             bool   `json:"verified"`
+			Line                 int    `json:"line"`
+			InstructionReference string `json:"instructionReference"`
+		} `json:"breakpoints"`
+	}
+	client.succeed("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": programPath},
+		"breakpoints": []map[string]any{{"line": 3}, {"line": 100}},
+	}, &breakpoints)
+	if len(breakpoints.Breakpoints) != 2 || !breakpoints.Breakpoints[0].Verified || breakpoints.Breakpoints[0].InstructionReference != "0x202" ||
+		breakpoints.Breakpoints[1].Verified {
+		t.Errorf("breakpoints are %+v, want line 3 at 0x202 and line 100 unverified", breakpoints.Breakpoints)
+	}
+
+	client.succeed("configurationDone", nil, nil)
+	if reason := client.waitStopped(); reason != "entry" {
+		t.Errorf("stopped for %q, want entry", reason)
+	}
+
+	client.succeed("stepIn", map[string]any{"threadId": dapThreadID}, nil)
+	if reason := client.waitStopped(); reason != "step" || machine.Core.PC != 0x206 {
+		t.Errorf("stepIn stopped for %q at %04X, want step at 0206", reason, machine.Core.PC)
+	}
+	var stackTrace struct {
+		StackFrames []struct {
+			Name string `json:"name"`
+			Line int    `json:"line"`
+		} `json:"stackFrames"`
+	}
+	client.succeed("stackTrace", map[string]any{"threadId": dapThreadID}, &stackTrace)
+	if len(stackTrace.StackFrames) != 2 || stackTrace.StackFrames[0].Name != "sub" || stackTrace.StackFrames[0].Line != 6 ||
+		stackTrace.StackFrames[1].Name != "main" || stackTrace.StackFrames[1].Line != 2 {
+		t.Errorf("stack frames are %+v, want sub on line 6 called from main on line 2", stackTrace.StackFrames)
+	}
+
+	client.succeed("stepOut", map[string]any{"threadId": dapThreadID}, nil)
+	if reason := client.waitStopped(); reason != "step" || machine.Core.PC != 0x202 || machine.Core.V[1] != 1 {
+		t.Errorf("stepOut stopped for %q at %04X with V1 %d, want step at 0202 with V1 1", reason, machine.Core.PC, machine.Core.V[1])
+	}
+
+	client.succeed("continue", map[string]any{"threadId": dapThreadID}, nil)
+	if reason := client.waitStopped(); reason != "breakpoint" || machine.Core.PC != 0x202 || machine.Core.V[0] != 1 || machine.Core.V[1] != 2 {
+		t.Errorf("continue stopped for %q at %04X with V0 %d and V1 %d, want breakpoint at 0202 with 1 and 2",
+			reason, machine.Core.PC, machine.Core.V[0], machine.Core.V[1])
+	}
+
+	var variables struct {
+		Variables []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"variables"`
+	}
+	client.succeed("variables", map[string]any{"variablesReference": dapRegistersReference}, &variables)
+	if len(variables.Variables) != 19 || variables.Variables[1].Name != "V1" || variables.Variables[1].Value != "0x02 (2)" {
+		t.Errorf("registers are %+v", variables.Variables)
+	}
+
+	var memory struct {
+		Address         string `json:"address"`
+		Data            string `json:"data"`
+		UnreadableBytes int    `json:"unreadableBytes"`
+	}
+	client.succeed("readMemory", map[string]any{"memoryReference": "0x200", "count": 4}, &memory)
+	if data, _ := base64.StdEncoding.DecodeString(memory.Data); memory.Address != "0x200" || string(data) != "\x22\x06\x70\x01" {
+		t.Errorf("read % X at %s, want 22 06 70 01 at 0x200", data, memory.Address)
+	}
+	client.succeed("readMemory", map[string]any{"memoryReference": "0xFFE", "count": 1 << 40}, &memory)
+	if data, _ := base64.StdEncoding.DecodeString(memory.Data); len(data) != 2 || memory.UnreadableBytes != dapMaxReadCount-2 {
+		t.Errorf("a huge read returned %d bytes and %d unreadable, want 2 and %d", len(data), memory.UnreadableBytes, dapMaxReadCount-2)
+	}
+
+	var disassembly struct {
+		Instructions []struct {
+			Address     string `json:"address"`
+			Instruction string `json:"instruction"`
+			Symbol      string `json:"symbol"`
+			Line        int    `json:"line"`
+		} `json:"instructions"`
+	}
+	client.succeed("disassemble", map[string]any{"memoryReference": "0x200", "instructionOffset": 3, "instructionCount": 2}, &disassembly)
+	if len(disassembly.Instructions) != 2 || disassembly.Instructions[0].Address != "0x206" || disassembly.Instructions[0].Symbol != "sub" ||
+		disassembly.Instructions[0].Line != 6 || disassembly.Instructions[1].Instruction != "RET" {
+		t.Errorf("disassembled %+v, want sub at 0x206 on line 6 followed by RET", disassembly.Instructions)
+	}
+	if response := client.request("disassemble", map[string]any{"memoryReference": "0x200", "instructionCount": dapMaxInstructionCount + 1}); response.Success {
+		t.Error("a disassembly above the limit succeeded")
+	}
+	if response := client.request("goto", nil); response.Success {
+		t.Error("an unsupported request succeeded")
+	}
+
+	client.succeed("disconnect", nil, nil)
+}
+
+func TestDAPServerContentLength(t *testing.T) {
+	for _, length := range []string{"-1", strconv.Itoa(dapMaxMessageLength + 1), "many"} {
+		dapServer := NewDAPServer(chip8.NewMachine(chip8.NewManualClock()))
+		input := strings.NewReader("Content-Length: " + length + "\r\n\r\n{}")
+		if err := dapServer.Serve(input, io.Discard); err == nil || !strings.Contains(err.Error(), "Content-Length") {
+			t.Errorf("a Content-Length of %s returned %v, want an invalid Content-Length error", length, err)
+		}
+	}
+}
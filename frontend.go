@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// Display presents the Screen of the core, once per frame.
+type Display interface {
+	Draw(core *chip8.Chip8Core) error
+	Close() error
+}
+
+// AudioSink plays the buzzer driven by the sound timer.
+type AudioSink interface {
+	SetTone(on bool)
+	Close() error
+}
+
+// InputSource feeds the hex keypad of the core, once per frame.
+// Poll returns false when the user asked to quit.
+type InputSource interface {
+	Poll(core *chip8.Chip8Core) bool
+	Close() error
+}
+
+// MachineController is implemented by the peripherals which also control the machine, such as the debugger
+// views pausing it; Attach hands them the machine.
+type MachineController interface {
+	AttachMachine(machine *chip8.Machine)
+}
+
+// Frontend groups the peripherals the emulation loop talks to.
+// A backend may implement several of the interfaces with the same value.
+type Frontend struct {
+	Display Display
+	Audio   AudioSink
+	Input   InputSource
+}
+
+// FrontendConfig holds the settings shared by all the backends; each backend uses the ones it understands.
+type FrontendConfig struct {
+	Title       string           // Title is the window title.
+	Scale       int              // Scale is the size in host pixels of a CHIP-8 pixel.
+	KeyMap      map[string]uint8 // KeyMap maps host key names to hex keypad keys.
+	OutputDir   string           // OutputDir is where the image-sequence backend writes its frames.
+	ShowPanel   bool             // ShowPanel shows the registers next to the screen in the terminal backend.
+	FastForward float64          // FastForward is the speed while fast-forwarding, 0 running as fast as possible.
+
+	Foreground color.RGBA // Foreground is the color of the lit pixels.
+	Background color.RGBA // Background is the color of the unlit pixels.
+}
+
+// DefaultKeyMap is the usual layout mapping the left part of a QWERTY keyboard onto the hex keypad.
+var DefaultKeyMap = map[string]uint8{
+	"1": 0x1, "2": 0x2, "3": 0x3, "4": 0xC,
+	"q": 0x4, "w": 0x5, "e": 0x6, "r": 0xD,
+	"a": 0x7, "s": 0x8, "d": 0x9, "f": 0xE,
+	"z": 0xA, "x": 0x0, "c": 0xB, "v": 0xF,
+}
+
+func NewFrontendConfig() FrontendConfig {
+	return FrontendConfig{
+		Title:       "CHIP-8",
+		Scale:       10,
+		FastForward: 4,
+		KeyMap:      DefaultKeyMap,
+		Foreground:  color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		Background:  color.RGBA{R: 0, G: 0, B: 0, A: 255},
+	}
+}
+
+// ApplyROMInfo takes the title, key bindings and colors of a program from its ROM database entry.
+func (config *FrontendConfig) ApplyROMInfo(romInfo chip8.ROMInfo) error {
+	if romInfo.Title != "" {
+		config.Title = "CHIP-8 - " + romInfo.Title
+	}
+	if len(romInfo.Keys) > 0 {
+		keyMap := map[string]uint8{}
+		for name, keyIndex := range config.KeyMap {
+			keyMap[name] = keyIndex
+		}
+		for name, keyIndex := range romInfo.Keys {
+			keyMap[name] = keyIndex
+		}
+		config.KeyMap = keyMap
+	}
+	if romInfo.Colors == nil {
+		return nil
+	}
+	var err error
+	if romInfo.Colors.Foreground != "" {
+		if config.Foreground, err = ParseColor(romInfo.Colors.Foreground); err != nil {
+			return err
+		}
+	}
+	if romInfo.Colors.Background != "" {
+		if config.Background, err = ParseColor(romInfo.Colors.Background); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseColor reads a "#RRGGBB" color.
+func ParseColor(text string) (color.RGBA, error) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(text, "#"), 16, 32)
+	if err != nil || len(strings.TrimPrefix(text, "#")) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected #RRGGBB", text)
+	}
+	return color.RGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 255}, nil
+}
+
+// SlowMotionSpeeds are the speeds the slow motion key goes through, normal speed first.
+var SlowMotionSpeeds = []float64{1, 0.5, 0.25, 0.125}
+
+// SpeedControl implements the speed keys of the interactive backends: fast-forward, which wins while it is on,
+// and slow motion, which the machine returns to when fast-forward is released.
+type SpeedControl struct {
+	FastForward    float64 // FastForward is the speed while fast-forwarding, 0 running as fast as possible.
+	fastForwarding bool
+	slowMotion     int
+}
+
+// SetFastForward turns fast-forward on or off.
+func (speedControl *SpeedControl) SetFastForward(machine *chip8.Machine, on bool) {
+	speedControl.fastForwarding = on
+	speedControl.apply(machine)
+}
+
+// ToggleFastForward turns fast-forward on or off, for the backends that cannot tell when a key is released.
+func (speedControl *SpeedControl) ToggleFastForward(machine *chip8.Machine) {
+	speedControl.SetFastForward(machine, !speedControl.fastForwarding)
+}
+
+// CycleSlowMotion moves to the next of SlowMotionSpeeds.
+func (speedControl *SpeedControl) CycleSlowMotion(machine *chip8.Machine) {
+	speedControl.slowMotion = (speedControl.slowMotion + 1) % len(SlowMotionSpeeds)
+	speedControl.apply(machine)
+}
+
+func (speedControl *SpeedControl) apply(machine *chip8.Machine) {
+	if speedControl.fastForwarding {
+		machine.SetSpeed(speedControl.FastForward)
+	} else {
+		machine.SetSpeed(SlowMotionSpeeds[speedControl.slowMotion])
+	}
+}
+
+// FrontendFactory creates a frontend from the shared configuration.
+type FrontendFactory func(config FrontendConfig) (*Frontend, error)
+
+var frontendFactories = map[string]FrontendFactory{}
+
+// RegisterFrontend makes a backend selectable by name.
+func RegisterFrontend(name string, factory FrontendFactory) {
+	frontendFactories[name] = factory
+}
+
+// FrontendNames returns the names of the available backends, sorted.
+func FrontendNames() []string {
+	names := make([]string, 0, len(frontendFactories))
+	for name := range frontendFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func NewFrontend(name string, config FrontendConfig) (*Frontend, error) {
+	factory, exists := frontendFactories[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown frontend %q (available: %s)", name, strings.Join(FrontendNames(), ", "))
+	}
+	return factory(config)
+}
+
+// Attach plugs the frontend into the machine: input is polled and the display drawn at the end of
+// every frame, and the audio follows the sound timer and the digitized sounds. The machine is stopped
+// when the user quits or when the display fails.
+func (frontend *Frontend) Attach(machine *chip8.Machine) {
+	for _, peripheral := range []any{frontend.Display, frontend.Audio, frontend.Input} {
+		if controller, ok := peripheral.(MachineController); ok {
+			controller.AttachMachine(machine)
+		}
+	}
+	machine.OnFrame(func(core *chip8.Chip8Core) {
+		if !frontend.Input.Poll(core) {
+			machine.Stop()
+			return
+		}
+		if err := frontend.Display.Draw(core); err != nil {
+			fmt.Println("Error drawing frame:", err)
+			machine.Stop()
+		}
+	})
+	machine.OnSound(frontend.Audio.SetTone)
+	if player, ok := frontend.Audio.(chip8.SamplePlayer); ok {
+		machine.OnDigitizedSound(player.PlaySamples)
+	}
+}
+
+// Close releases the peripherals, closing each distinct value only once.
+func (frontend *Frontend) Close() error {
+	var firstErr error
+	closed := map[any]bool{}
+	for _, closer := range []interface{ Close() error }{frontend.Input, frontend.Audio, frontend.Display} {
+		if closed[closer] {
+			continue
+		}
+		closed[closer] = true
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// ImageSequenceFrontend writes every frame as a numbered PNG file, to be assembled into a video or compared by tools.
+type ImageSequenceFrontend struct {
+	directory string
+	scale     int
+	palette   color.Palette
+	frame     int
+}
+
+func init() {
+	RegisterFrontend("images", func(config FrontendConfig) (*Frontend, error) {
+		imageSequenceFrontend, err := NewImageSequenceFrontend(config.OutputDir, config.Scale)
+		if err != nil {
+			return nil, err
+		}
+		imageSequenceFrontend.palette = color.Palette{config.Background, config.Foreground}
+		return &Frontend{Display: imageSequenceFrontend, Audio: &NullFrontend{}, Input: &NullFrontend{}}, nil
+	})
+}
+
+func NewImageSequenceFrontend(directory string, scale int) (*ImageSequenceFrontend, error) {
+	if directory == "" {
+		directory = "frames"
+	}
+	if scale < 1 {
+		scale = 1
+	}
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, err
+	}
+	palette := color.Palette{color.Black, color.White}
+	return &ImageSequenceFrontend{directory: directory, scale: scale, palette: palette}, nil
+}
+
+func (imageSequenceFrontend *ImageSequenceFrontend) Draw(core *chip8.Chip8Core) error {
+	path := filepath.Join(imageSequenceFrontend.directory, fmt.Sprintf("frame_%06d.png", imageSequenceFrontend.frame))
+	imageSequenceFrontend.frame++
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, ColorScreenImage(core, imageSequenceFrontend.scale, imageSequenceFrontend.palette)); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (imageSequenceFrontend *ImageSequenceFrontend) Close() error {
+	return nil
+}
+
+// ScreenImage renders the Screen with the background and foreground colors of the palette,
+// each CHIP-8 pixel becoming a scale x scale square.
+func ScreenImage(core *chip8.Chip8Core, scale int, palette color.Palette) *image.Paletted {
+	screenImage := image.NewPaletted(image.Rect(0, 0, core.ScreenWidth()*scale, core.ScreenHeight()*scale), palette)
+	for positionY := 0; positionY < core.ScreenHeight()*scale; positionY++ {
+		for positionX := 0; positionX < core.ScreenWidth()*scale; positionX++ {
+			if core.GetPixel(uint8(positionX/scale), uint8(positionY/scale)) {
+				screenImage.SetColorIndex(positionX, positionY, 1)
+			}
+		}
+	}
+	return screenImage
+}
+
+// ColorScreenImage renders the Screen as ScreenImage does, or in the colors of the display on the platforms
+// having colors: MegaChip-8 in MegaChip mode and CHIP-8X.
+func ColorScreenImage(core *chip8.Chip8Core, scale int, palette color.Palette) image.Image {
+	if colorImage := colorScreenImage(core, scale); colorImage != nil {
+		return colorImage
+	}
+	return ScreenImage(core, scale, palette)
+}
+
+// colorScreenImage renders the colors of the display, nil when it is monochrome.
+func colorScreenImage(core *chip8.Chip8Core, scale int) *image.RGBA {
+	switch {
+	case core.MegaChipEnabled():
+		return chip8.MegaChipImage(core.MegaChip, scale)
+	case core.Chip8X != nil:
+		return chip8.Chip8XImage(core, scale)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import "github.com/nebul/chip8-go/chip8"
+
+// NullFrontend is the headless backend: nothing is shown, nothing is played and no key is ever pressed.
+type NullFrontend struct{}
+
+func init() {
+	RegisterFrontend("null", func(config FrontendConfig) (*Frontend, error) {
+		nullFrontend := &NullFrontend{}
+		return &Frontend{Display: nullFrontend, Audio: nullFrontend, Input: nullFrontend}, nil
+	})
+}
+
+func (nullFrontend *NullFrontend) Draw(core *chip8.Chip8Core) error {
+	return nil
+}
+
+func (nullFrontend *NullFrontend) SetTone(on bool) {}
+
+func (nullFrontend *NullFrontend) Poll(core *chip8.Chip8Core) bool {
+	return true
+}
+
+func (nullFrontend *NullFrontend) Close() error {
+	return nil
+}
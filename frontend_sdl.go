@@ -0,0 +1,507 @@
+//go:build !nosdl
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/nebul/chip8-go/chip8"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	sdlAudioFrequency = 44100
+	sdlToneFrequency  = 440
+)
+
+// The memory overlay draws the bitmap font at sdlOverlayTextScale window pixels per font pixel.
+const (
+	sdlOverlayTextScale   = 2
+	sdlOverlayCellWidth   = bitmapFontCellWidth * sdlOverlayTextScale
+	sdlOverlayLineHeight  = (bitmapFontCellHeight + 1) * sdlOverlayTextScale
+	sdlOverlayMargin      = 4
+	sdlOverlaySpritePixel = 5
+	sdlOverlayMemoryRows  = 8
+)
+
+// sdlOverlayHighlights are the cell backgrounds of the memory overlay.
+var sdlOverlayHighlights = map[MemoryHighlight]color.RGBA{
+	HighlightPC:     {R: 0xC0, G: 0x90, B: 0x00, A: 0xFF},
+	HighlightI:      {R: 0x00, G: 0x90, B: 0xA0, A: 0xFF},
+	HighlightSprite: {R: 0x00, G: 0x40, B: 0x50, A: 0xFF},
+}
+
+// SDLFrontend is the windowed backend: a single SDL window provides the display and the keyboard,
+// and an SDL audio device plays a square wave while the sound timer runs.
+// F2 shows a memory overlay at the bottom of the window and F5 pauses the machine; see Poll for the editing keys.
+// F3 opens a second window with the registers, the stack, the timers, the keypad and the last instructions.
+// Holding Tab fast-forwards, F7 goes through the slow motion speeds and F10 advances a single frame; the
+// window title shows the speed.
+type SDLFrontend struct {
+	window      *sdl.Window
+	renderer    *sdl.Renderer
+	audio       sdl.AudioDeviceID
+	keyMap      map[sdl.Keycode]uint8
+	foreground  color.RGBA
+	background  color.RGBA
+	machine     *chip8.Machine
+	memoryView  *MemoryView
+	showMemory  bool
+	title       string
+	debugWindow *sdlDebugWindow
+	speed       SpeedControl
+	shownStatus string
+}
+
+func init() {
+	// SDL expects to be driven from the thread that initialised it.
+	runtime.LockOSThread()
+	RegisterFrontend("sdl", func(config FrontendConfig) (*Frontend, error) {
+		sdlFrontend, err := NewSDLFrontend(config)
+		if err != nil {
+			return nil, err
+		}
+		return &Frontend{Display: sdlFrontend, Audio: sdlFrontend, Input: sdlFrontend}, nil
+	})
+}
+
+func NewSDLFrontend(config FrontendConfig) (*SDLFrontend, error) {
+	if err := sdl.Init(sdl.INIT_VIDEO | sdl.INIT_AUDIO | sdl.INIT_EVENTS); err != nil {
+		return nil, err
+	}
+	sdlFrontend := &SDLFrontend{
+		keyMap:     map[sdl.Keycode]uint8{},
+		foreground: config.Foreground,
+		background: config.Background,
+		memoryView: NewMemoryView(sdlOverlayMemoryRows),
+		title:      config.Title,
+		speed:      SpeedControl{FastForward: config.FastForward},
+	}
+
+	scale := int32(config.Scale)
+	window, err := sdl.CreateWindow(config.Title, sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, 64*scale, 32*scale, sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE)
+	if err != nil {
+		sdl.Quit()
+		return nil, err
+	}
+	sdlFrontend.window = window
+
+	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
+	if err != nil {
+		window.Destroy()
+		sdl.Quit()
+		return nil, err
+	}
+	sdlFrontend.renderer = renderer
+
+	for name, keyIndex := range config.KeyMap {
+		keycode := sdl.GetKeyFromName(name)
+		if keycode == sdl.K_UNKNOWN {
+			sdlFrontend.Close()
+			return nil, fmt.Errorf("unknown key name %q", name)
+		}
+		sdlFrontend.keyMap[keycode] = keyIndex
+	}
+
+	// A missing audio device is not fatal, the game simply stays silent.
+	desired := &sdl.AudioSpec{Freq: sdlAudioFrequency, Format: sdl.AUDIO_S8, Channels: 1, Samples: 512}
+	if audio, err := sdl.OpenAudioDevice("", false, desired, nil, 0); err == nil {
+		sdlFrontend.audio = audio
+		sdl.PauseAudioDevice(audio, false)
+	} else {
+		fmt.Println("Error opening audio device:", err)
+	}
+	if config.ShowPanel {
+		if err := sdlFrontend.toggleDebugWindow(); err != nil {
+			sdlFrontend.Close()
+			return nil, err
+		}
+	}
+	return sdlFrontend, nil
+}
+
+// Poll forwards the mapped keys to the keypad. While the memory overlay is shown, the arrows and Page Up/Down
+// move its cursor, F6 toggles following I and, when the machine is paused, hex digits edit the byte at the cursor.
+func (sdlFrontend *SDLFrontend) Poll(core *chip8.Chip8Core) bool {
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		switch e := event.(type) {
+		case *sdl.QuitEvent:
+			return false
+		case *sdl.WindowEvent:
+			// Closing the debug window only closes the debugger.
+			if e.Event == sdl.WINDOWEVENT_CLOSE {
+				if sdlFrontend.debugWindow == nil || e.WindowID != sdlFrontend.debugWindow.id {
+					return false
+				}
+				sdlFrontend.toggleDebugWindow()
+			}
+		case *sdl.KeyboardEvent:
+			if e.Keysym.Sym == sdl.K_TAB && sdlFrontend.machine != nil {
+				if e.Repeat == 0 {
+					sdlFrontend.speed.SetFastForward(sdlFrontend.machine, e.Type == sdl.KEYDOWN)
+				}
+				continue
+			}
+			if e.Type == sdl.KEYDOWN && sdlFrontend.handleDebugKey(core, e.Keysym.Sym) {
+				continue
+			}
+			if keyIndex, exists := sdlFrontend.keyMap[e.Keysym.Sym]; exists {
+				core.SetKey(keyIndex, e.Type == sdl.KEYDOWN)
+			}
+		}
+	}
+	return true
+}
+
+func (sdlFrontend *SDLFrontend) AttachMachine(machine *chip8.Machine) {
+	sdlFrontend.machine = machine
+}
+
+func (sdlFrontend *SDLFrontend) toggleDebugWindow() error {
+	if sdlFrontend.debugWindow != nil {
+		sdlFrontend.debugWindow.Close()
+		sdlFrontend.debugWindow = nil
+		return nil
+	}
+	debugWindow, err := newSDLDebugWindow(sdlFrontend.title + " - Debugger")
+	if err != nil {
+		return err
+	}
+	sdlFrontend.debugWindow = debugWindow
+	return nil
+}
+
+// handleDebugKey handles the keys of the memory overlay and tells whether the key was used.
+func (sdlFrontend *SDLFrontend) handleDebugKey(core *chip8.Chip8Core, key sdl.Keycode) bool {
+	memoryView := sdlFrontend.memoryView
+	switch key {
+	case sdl.K_F2:
+		sdlFrontend.showMemory = !sdlFrontend.showMemory
+		return true
+	case sdl.K_F3:
+		if err := sdlFrontend.toggleDebugWindow(); err != nil {
+			fmt.Println("Error opening debug window:", err)
+		}
+		return true
+	case sdl.K_F5:
+		if sdlFrontend.machine != nil {
+			if sdlFrontend.machine.Paused() {
+				sdlFrontend.machine.Resume()
+			} else {
+				sdlFrontend.machine.Pause()
+			}
+		}
+		return true
+	case sdl.K_F7:
+		if sdlFrontend.machine != nil {
+			sdlFrontend.speed.CycleSlowMotion(sdlFrontend.machine)
+		}
+		return true
+	case sdl.K_F10:
+		if sdlFrontend.machine != nil {
+			sdlFrontend.machine.AdvanceFrame()
+		}
+		return true
+	}
+	if !sdlFrontend.showMemory {
+		return false
+	}
+	switch key {
+	case sdl.K_F6:
+		memoryView.FollowI = !memoryView.FollowI
+		return true
+	case sdl.K_UP:
+		memoryView.MoveCursor(core, -MemoryViewColumns)
+		return true
+	case sdl.K_DOWN:
+		memoryView.MoveCursor(core, MemoryViewColumns)
+		return true
+	case sdl.K_LEFT:
+		memoryView.MoveCursor(core, -1)
+		return true
+	case sdl.K_RIGHT:
+		memoryView.MoveCursor(core, 1)
+		return true
+	case sdl.K_PAGEUP:
+		memoryView.MoveCursor(core, -MemoryViewColumns*memoryView.Rows)
+		return true
+	case sdl.K_PAGEDOWN:
+		memoryView.MoveCursor(core, MemoryViewColumns*memoryView.Rows)
+		return true
+	}
+	if name := sdl.GetKeyName(key); sdlFrontend.machine != nil && sdlFrontend.machine.Paused() && len(name) == 1 {
+		if digit, err := strconv.ParseUint(name, 16, 8); err == nil {
+			memoryView.EditNibble(core, byte(digit))
+			return true
+		}
+	}
+	return false
+}
+
+func (sdlFrontend *SDLFrontend) Draw(core *chip8.Chip8Core) error {
+	renderer := sdlFrontend.renderer
+	outputWidth, outputHeight, err := renderer.GetOutputSize()
+	if err != nil {
+		return err
+	}
+	// Pixels are scaled by a whole factor and centered, so every resolution of the core fills the window.
+	width, height := int32(core.ScreenWidth()), int32(core.ScreenHeight())
+	pixelSize := max(1, min(outputWidth/width, outputHeight/height))
+	offsetX, offsetY := (outputWidth-pixelSize*width)/2, (outputHeight-pixelSize*height)/2
+
+	background, foreground := sdlFrontend.background, sdlFrontend.foreground
+	renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+	renderer.SetDrawColor(background.R, background.G, background.B, 255)
+	renderer.Clear()
+
+	if colorImage := colorScreenImage(core, 1); colorImage != nil {
+		drawColorImage(renderer, colorImage, offsetX, offsetY, pixelSize)
+	} else {
+		var rectangles []sdl.Rect
+		for positionY := int32(0); positionY < height; positionY++ {
+			for positionX := int32(0); positionX < width; positionX++ {
+				if core.GetPixel(uint8(positionX), uint8(positionY)) {
+					rectangles = append(rectangles, sdl.Rect{X: offsetX + positionX*pixelSize, Y: offsetY + positionY*pixelSize, W: pixelSize, H: pixelSize})
+				}
+			}
+		}
+		fillRectangles(renderer, foreground, rectangles)
+	}
+
+	if sdlFrontend.machine != nil {
+		sdlFrontend.drawScriptOverlay(offsetX, offsetY, pixelSize)
+		sdlFrontend.drawSpeedStatus(outputWidth)
+	}
+	if sdlFrontend.showMemory {
+		sdlFrontend.drawMemoryOverlay(core, outputWidth, outputHeight)
+	}
+	renderer.Present()
+	if sdlFrontend.debugWindow != nil {
+		sdlFrontend.debugWindow.Draw(core, sdlFrontend.machine)
+	}
+	return nil
+}
+
+// drawColorImage draws a display having colors of its own, one batch of rectangles per color.
+func drawColorImage(renderer *sdl.Renderer, colorImage *image.RGBA, offsetX int32, offsetY int32, pixelSize int32) {
+	batches := map[color.RGBA][]sdl.Rect{}
+	bounds := colorImage.Bounds()
+	for positionY := int32(0); positionY < int32(bounds.Dy()); positionY++ {
+		for positionX := int32(0); positionX < int32(bounds.Dx()); positionX++ {
+			pixel := colorImage.RGBAAt(int(positionX), int(positionY))
+			batches[pixel] = append(batches[pixel], sdl.Rect{X: offsetX + positionX*pixelSize, Y: offsetY + positionY*pixelSize, W: pixelSize, H: pixelSize})
+		}
+	}
+	for fill, rectangles := range batches {
+		fillRectangles(renderer, fill, rectangles)
+	}
+}
+
+// fillRectangles draws the rectangles in one call; SDL refuses an empty batch.
+func fillRectangles(renderer *sdl.Renderer, fill color.RGBA, rectangles []sdl.Rect) {
+	if len(rectangles) == 0 {
+		return
+	}
+	renderer.SetDrawColor(fill.R, fill.G, fill.B, fill.A)
+	renderer.FillRects(rectangles)
+}
+
+// textRectangles returns the pixels of text drawn with the bitmap font from (left, top) in window coordinates,
+// scale window pixels per font pixel.
+func textRectangles(text string, left int32, top int32, scale int32) []sdl.Rect {
+	var rectangles []sdl.Rect
+	DrawBitmapText(text, func(positionX int, positionY int) {
+		rectangles = append(rectangles, sdl.Rect{
+			X: left + int32(positionX)*scale,
+			Y: top + int32(positionY)*scale,
+			W: scale,
+			H: scale,
+		})
+	})
+	return rectangles
+}
+
+// drawSpeedStatus shows the speed of the machine in the top right corner of the window and in its title.
+func (sdlFrontend *SDLFrontend) drawSpeedStatus(outputWidth int32) {
+	status := strings.ToUpper(sdlFrontend.machine.SpeedStatus())
+	if status != sdlFrontend.shownStatus {
+		sdlFrontend.shownStatus = status
+		if status == "" {
+			sdlFrontend.window.SetTitle(sdlFrontend.title)
+		} else {
+			sdlFrontend.window.SetTitle(sdlFrontend.title + " [" + status + "]")
+		}
+	}
+	if status != "" {
+		left := outputWidth - sdlOverlayMargin - int32(len(status)*sdlOverlayCellWidth)
+		fillRectangles(sdlFrontend.renderer, sdlOverlayHighlights[HighlightPC], textRectangles(status, left, sdlOverlayMargin, sdlOverlayTextScale))
+	}
+}
+
+// drawScriptOverlay draws the overlay of the machine over the screen, whose pixels are pixelSize window pixels
+// wide from (offsetX, offsetY).
+func (sdlFrontend *SDLFrontend) drawScriptOverlay(offsetX int32, offsetY int32, pixelSize int32) {
+	renderer := sdlFrontend.renderer
+	renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	defer renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+	for _, item := range sdlFrontend.machine.Overlay.Items {
+		left, top := offsetX+int32(item.X)*pixelSize, offsetY+int32(item.Y)*pixelSize
+		if item.Text != "" {
+			fillRectangles(renderer, item.Color, textRectangles(item.Text, left, top, max(pixelSize/2, 1)))
+			continue
+		}
+		fillRectangles(renderer, item.Color, []sdl.Rect{{X: left, Y: top, W: int32(item.Width) * pixelSize, H: int32(item.Height) * pixelSize}})
+	}
+}
+
+// drawMemoryOverlay draws the memory view over the bottom of the window: the bytes of the instruction at PC,
+// at I and the rest of the sprite on colored cells, the cursor outlined, and the sprite at I previewed on the right.
+func (sdlFrontend *SDLFrontend) drawMemoryOverlay(core *chip8.Chip8Core, outputWidth int32, outputHeight int32) {
+	renderer := sdlFrontend.renderer
+	memoryView := sdlFrontend.memoryView
+	memoryView.Update(core)
+
+	status := memoryView.Status(core)
+	if sdlFrontend.machine != nil && sdlFrontend.machine.Paused() {
+		status += "  PAUSED"
+	}
+	addresses := memoryView.RowAddresses(core)
+	sprite := memoryView.Sprite(core)
+	textHeight := int32(len(addresses)+1) * sdlOverlayLineHeight
+	spriteHeight := sdlOverlayLineHeight + int32(len(sprite))*sdlOverlaySpritePixel
+	overlayHeight := max(textHeight, spriteHeight) + 2*sdlOverlayMargin
+	top := outputHeight - overlayHeight
+
+	renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	renderer.SetDrawColor(0, 0, 0, 0xD0)
+	renderer.FillRect(&sdl.Rect{X: 0, Y: top, W: outputWidth, H: overlayHeight})
+	renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+
+	// Columns of the text drawn by FormatMemoryRow: "AAAA  XX XX ...  ASCII".
+	addressWidth := len(FormatMemoryAddress(core, 0)) + 2
+	hexColumn := func(column int) int32 { return int32(addressWidth+3*column) * sdlOverlayCellWidth }
+	asciiColumn := func(column int) int32 { return int32(addressWidth+3*MemoryViewColumns+column) * sdlOverlayCellWidth }
+
+	left := int32(sdlOverlayMargin)
+	lineTop := top + sdlOverlayMargin
+	text := textRectangles(status, left, lineTop, sdlOverlayTextScale)
+	highlights := map[MemoryHighlight][]sdl.Rect{}
+	var cursor *sdl.Rect
+	for _, address := range addresses {
+		lineTop += sdlOverlayLineHeight
+		for column := 0; column < MemoryViewColumns && int(address)+column < len(core.Memory); column++ {
+			current := address + uint32(column)
+			hexCell := sdl.Rect{X: left + hexColumn(column) - sdlOverlayTextScale, Y: lineTop - sdlOverlayTextScale, W: 2*sdlOverlayCellWidth + sdlOverlayTextScale, H: sdlOverlayLineHeight}
+			if highlight := memoryView.Highlight(core, current); highlight != HighlightNone {
+				asciiCell := sdl.Rect{X: left + asciiColumn(column) - sdlOverlayTextScale, Y: hexCell.Y, W: sdlOverlayCellWidth, H: sdlOverlayLineHeight}
+				highlights[highlight] = append(highlights[highlight], hexCell, asciiCell)
+			}
+			if current == memoryView.Cursor {
+				cursor = &hexCell
+			}
+		}
+		text = append(text, textRectangles(FormatMemoryRow(core, address), left, lineTop, sdlOverlayTextScale)...)
+	}
+	for highlight, rectangles := range highlights {
+		fillRectangles(renderer, sdlOverlayHighlights[highlight], rectangles)
+	}
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	fillRectangles(renderer, white, text)
+	if cursor != nil {
+		renderer.SetDrawColor(white.R, white.G, white.B, white.A)
+		renderer.DrawRect(cursor)
+	}
+
+	// The sprite preview shows unlit pixels dimmed so its outline stays visible.
+	spriteLeft := outputWidth - sdlOverlayMargin - 8*sdlOverlaySpritePixel
+	spriteTop := top + sdlOverlayMargin + sdlOverlayLineHeight
+	var lit, unlit []sdl.Rect
+	for row, pixels := range sprite {
+		for column, pixel := range pixels {
+			rectangle := sdl.Rect{
+				X: spriteLeft + int32(column)*sdlOverlaySpritePixel,
+				Y: spriteTop + int32(row)*sdlOverlaySpritePixel,
+				W: sdlOverlaySpritePixel - 1,
+				H: sdlOverlaySpritePixel - 1,
+			}
+			if pixel {
+				lit = append(lit, rectangle)
+			} else {
+				unlit = append(unlit, rectangle)
+			}
+		}
+	}
+	fillRectangles(renderer, color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xFF}, unlit)
+	fillRectangles(renderer, sdlFrontend.foreground, lit)
+}
+
+// SetTone queues a square wave long enough to outlast the longest sound timer, and drops it when the tone stops.
+func (sdlFrontend *SDLFrontend) SetTone(on bool) {
+	if sdlFrontend.audio == 0 {
+		return
+	}
+	sdl.ClearQueuedAudio(sdlFrontend.audio)
+	if !on {
+		return
+	}
+	samples := make([]byte, sdlAudioFrequency*256/60)
+	halfPeriod := sdlAudioFrequency / sdlToneFrequency / 2
+	for index := range samples {
+		if (index/halfPeriod)%2 == 0 {
+			samples[index] = 0x20
+		} else {
+			samples[index] = 0xE0
+		}
+	}
+	if err := sdl.QueueAudio(sdlFrontend.audio, samples); err != nil {
+		fmt.Println("Error queueing audio:", err)
+	}
+}
+
+// PlaySamples replaces the queued sound with a digitized sound, resampled to the rate of the device; a looping
+// sound is repeated for a minute.
+func (sdlFrontend *SDLFrontend) PlaySamples(sound *chip8.DigitizedSound) {
+	if sdlFrontend.audio == 0 {
+		return
+	}
+	sdl.ClearQueuedAudio(sdlFrontend.audio)
+	if sound == nil || sound.Rate == 0 || len(sound.Samples) == 0 {
+		return
+	}
+	length := len(sound.Samples) * sdlAudioFrequency / sound.Rate
+	total := length
+	if sound.Loop {
+		total = max(length, sdlAudioFrequency*60)
+	}
+	samples := make([]byte, total)
+	for index := range samples {
+		// The device takes signed samples; they are halved to the volume of the buzzer.
+		sample := sound.Samples[(index%max(length, 1))*sound.Rate/sdlAudioFrequency]
+		samples[index] = byte(int8((int(sample) - 0x80) / 2))
+	}
+	if err := sdl.QueueAudio(sdlFrontend.audio, samples); err != nil {
+		fmt.Println("Error queueing audio:", err)
+	}
+}
+
+func (sdlFrontend *SDLFrontend) Close() error {
+	if sdlFrontend.debugWindow != nil {
+		sdlFrontend.debugWindow.Close()
+	}
+	if sdlFrontend.audio != 0 {
+		sdl.CloseAudioDevice(sdlFrontend.audio)
+	}
+	if sdlFrontend.renderer != nil {
+		sdlFrontend.renderer.Destroy()
+	}
+	if sdlFrontend.window != nil {
+		sdlFrontend.window.Destroy()
+	}
+	sdl.Quit()
+	return nil
+}
@@ -0,0 +1,154 @@
+//go:build !nosdl
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/nebul/chip8-go/chip8"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// The debug window is laid out in cells of the overlay font: the registers, stack and keypad on the left,
+// the trace of the last instructions on the right.
+const (
+	sdlDebugTraceColumn = 30
+	sdlDebugColumns     = sdlDebugTraceColumn + 26
+	sdlDebugLines       = 19
+	sdlDebugTraceLines  = sdlDebugLines - 1
+)
+
+var (
+	sdlDebugText      = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	sdlDebugDimmed    = color.RGBA{R: 0x60, G: 0x60, B: 0x60, A: 0xFF}
+	sdlDebugHighlight = color.RGBA{R: 0xC0, G: 0x90, B: 0x00, A: 0xFF}
+	sdlDebugPressed   = color.RGBA{R: 0x00, G: 0x90, B: 0xA0, A: 0xFF}
+)
+
+// sdlDebugKeypad is the layout of the COSMAC VIP keypad.
+var sdlDebugKeypad = [4][4]uint8{
+	{0x1, 0x2, 0x3, 0xC},
+	{0x4, 0x5, 0x6, 0xD},
+	{0x7, 0x8, 0x9, 0xE},
+	{0xA, 0x0, 0xB, 0xF},
+}
+
+// sdlDebugWindow is the second window of the SDL frontend, showing the state of the machine every frame.
+// The text of a frame is batched by color so a frame costs a handful of draw calls.
+type sdlDebugWindow struct {
+	window     *sdl.Window
+	renderer   *sdl.Renderer
+	id         uint32
+	text       map[color.RGBA][]sdl.Rect
+	background map[color.RGBA][]sdl.Rect
+}
+
+func newSDLDebugWindow(title string) (*sdlDebugWindow, error) {
+	width := int32(sdlDebugColumns*sdlOverlayCellWidth + 2*sdlOverlayMargin)
+	height := int32(sdlDebugLines*sdlOverlayLineHeight + 2*sdlOverlayMargin)
+	window, err := sdl.CreateWindow(title, sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, width, height, sdl.WINDOW_SHOWN)
+	if err != nil {
+		return nil, err
+	}
+	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
+	if err != nil {
+		window.Destroy()
+		return nil, err
+	}
+	id, err := window.GetID()
+	if err != nil {
+		renderer.Destroy()
+		window.Destroy()
+		return nil, err
+	}
+	return &sdlDebugWindow{
+		window:     window,
+		renderer:   renderer,
+		id:         id,
+		text:       map[color.RGBA][]sdl.Rect{},
+		background: map[color.RGBA][]sdl.Rect{},
+	}, nil
+}
+
+// print writes text at a cell of the window.
+func (debugWindow *sdlDebugWindow) print(column int, line int, fill color.RGBA, text string) {
+	left := int32(sdlOverlayMargin + column*sdlOverlayCellWidth)
+	top := int32(sdlOverlayMargin + line*sdlOverlayLineHeight)
+	debugWindow.text[fill] = append(debugWindow.text[fill], textRectangles(text, left, top, sdlOverlayTextScale)...)
+}
+
+// mark fills the background of width cells of a line.
+func (debugWindow *sdlDebugWindow) mark(column int, line int, width int, fill color.RGBA) {
+	debugWindow.background[fill] = append(debugWindow.background[fill], sdl.Rect{
+		X: int32(sdlOverlayMargin+column*sdlOverlayCellWidth) - sdlOverlayTextScale,
+		Y: int32(sdlOverlayMargin+line*sdlOverlayLineHeight) - sdlOverlayTextScale,
+		W: int32(width*sdlOverlayCellWidth) + sdlOverlayTextScale,
+		H: sdlOverlayLineHeight,
+	})
+}
+
+// Draw shows the registers, the timers, the stack (the entries above SP dimmed), the keypad with the pressed
+// keys marked and, when the machine is known, its last instructions with the one executed last marked.
+func (debugWindow *sdlDebugWindow) Draw(core *chip8.Chip8Core, machine *chip8.Machine) {
+	for fill := range debugWindow.text {
+		debugWindow.text[fill] = debugWindow.text[fill][:0]
+	}
+	for fill := range debugWindow.background {
+		debugWindow.background[fill] = debugWindow.background[fill][:0]
+	}
+
+	debugWindow.print(0, 0, sdlDebugText, fmt.Sprintf("PC %04X  I %04X", core.PC, core.I))
+	debugWindow.print(0, 1, sdlDebugText, fmt.Sprintf("SP %X  DT %02X  ST %02X", core.SP, core.DelayTimer, core.SoundTimer))
+	for index := 0; index < len(core.V); index++ {
+		debugWindow.print(index%4*7, 3+index/4, sdlDebugText, fmt.Sprintf("V%X %02X", index, core.V[index]))
+	}
+
+	debugWindow.print(0, 8, sdlDebugText, "STACK")
+	for index, address := range core.Stack {
+		fill := sdlDebugText
+		if index >= int(core.SP) {
+			fill = sdlDebugDimmed
+		}
+		debugWindow.print(index%4*6, 9+index/4, fill, fmt.Sprintf("%04X", address))
+	}
+
+	debugWindow.print(0, 14, sdlDebugText, "KEYPAD")
+	for row, keys := range sdlDebugKeypad {
+		for column, key := range keys {
+			if core.Keys[key] {
+				debugWindow.mark(column*2, 15+row, 1, sdlDebugPressed)
+			}
+			debugWindow.print(column*2, 15+row, sdlDebugText, fmt.Sprintf("%X", key))
+		}
+	}
+
+	debugWindow.print(sdlDebugTraceColumn, 0, sdlDebugText, "TRACE")
+	if machine != nil {
+		trace := machine.Trace()
+		trace = trace[max(0, len(trace)-sdlDebugTraceLines):]
+		for index, entry := range trace {
+			line := 1 + index
+			if index == len(trace)-1 {
+				debugWindow.mark(sdlDebugTraceColumn, line, 24, sdlDebugHighlight)
+			}
+			debugWindow.print(sdlDebugTraceColumn, line, sdlDebugText, fmt.Sprintf("%04X %04X %s", entry.Address, entry.Instruction.Opcode(), entry.Instruction))
+		}
+	}
+
+	renderer := debugWindow.renderer
+	renderer.SetDrawColor(0, 0, 0, 0xFF)
+	renderer.Clear()
+	for fill, rectangles := range debugWindow.background {
+		fillRectangles(renderer, fill, rectangles)
+	}
+	for fill, rectangles := range debugWindow.text {
+		fillRectangles(renderer, fill, rectangles)
+	}
+	renderer.Present()
+}
+
+func (debugWindow *sdlDebugWindow) Close() {
+	debugWindow.renderer.Destroy()
+	debugWindow.window.Destroy()
+}
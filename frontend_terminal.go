@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nebul/chip8-go/chip8"
+	"golang.org/x/term"
+)
+
+// terminalKeyHoldFrames is how long a key stays pressed after the terminal sent it.
+// Terminals only report key presses, so a key is released once its auto-repeat stops refreshing it.
+const terminalKeyHoldFrames = 6
+
+// terminalMemoryRows is the number of rows of the memory view of the terminal debugger.
+const terminalMemoryRows = 8
+
+// TerminalFrontend renders the Screen in the terminal with Unicode half-blocks, each character cell showing
+// two pixels stacked vertically, reads the keypad from raw-mode stdin and rings the terminal bell for the buzzer.
+// Tab toggles a side panel with the registers, Escape or Ctrl-C quits.
+//
+// It doubles as a terminal debugger: F5 or Ctrl-P pauses, F2 or Ctrl-E shows a memory view below the screen
+// where the arrows and Page Up/Down move the cursor, Ctrl-F toggles follow-I mode and, while paused, hex
+// digits edit the byte under the cursor instead of pressing keypad keys. Terminals do not report released keys,
+// so F8 or Ctrl-T toggles fast-forward; F7 or Ctrl-S goes through the slow motion speeds and F10 or Ctrl-N
+// advances a single frame. The speed is shown under the screen.
+type TerminalFrontend struct {
+	output     *bufio.Writer
+	input      *os.File
+	state      *term.State
+	chunks     chan []byte
+	keyMap     map[rune]uint8
+	held       [256]int
+	showPanel  bool
+	showMemory bool
+	memoryView *MemoryView
+	machine    *chip8.Machine
+	speed      SpeedControl
+	colors     string
+	last       string
+}
+
+func init() {
+	RegisterFrontend("terminal", func(config FrontendConfig) (*Frontend, error) {
+		terminalFrontend, err := NewTerminalFrontend(os.Stdin, os.Stdout, config)
+		if err != nil {
+			return nil, err
+		}
+		return &Frontend{Display: terminalFrontend, Audio: terminalFrontend, Input: terminalFrontend}, nil
+	})
+}
+
+// NewTerminalFrontend switches input to raw mode when it is a terminal; otherwise no key is ever pressed.
+// Only the single-character names of the key map can be typed in a terminal, and the colors are only
+// sent, as 24-bit escape sequences, when they differ from the default white on black.
+func NewTerminalFrontend(input *os.File, output io.Writer, config FrontendConfig) (*TerminalFrontend, error) {
+	terminalFrontend := &TerminalFrontend{
+		output:     bufio.NewWriter(output),
+		keyMap:     map[rune]uint8{},
+		showPanel:  config.ShowPanel,
+		memoryView: NewMemoryView(terminalMemoryRows),
+		speed:      SpeedControl{FastForward: config.FastForward},
+	}
+	defaults := NewFrontendConfig()
+	if config.Foreground != defaults.Foreground || config.Background != defaults.Background {
+		foreground, background := config.Foreground, config.Background
+		terminalFrontend.colors = fmt.Sprintf("\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm", foreground.R, foreground.G, foreground.B, background.R, background.G, background.B)
+	}
+	for name, keyIndex := range config.KeyMap {
+		if character, size := utf8.DecodeRuneInString(name); size == len(name) {
+			terminalFrontend.keyMap[character] = keyIndex
+		}
+	}
+	if input != nil && term.IsTerminal(int(input.Fd())) {
+		state, err := term.MakeRaw(int(input.Fd()))
+		if err != nil {
+			return nil, err
+		}
+		terminalFrontend.input = input
+		terminalFrontend.state = state
+		terminalFrontend.chunks = make(chan []byte, 64)
+		go terminalFrontend.readInput()
+	}
+	terminalFrontend.output.WriteString("\x1b[2J\x1b[?25l")
+	return terminalFrontend, nil
+}
+
+func (terminalFrontend *TerminalFrontend) readInput() {
+	buffer := make([]byte, 64)
+	for {
+		count, err := terminalFrontend.input.Read(buffer)
+		if err != nil {
+			close(terminalFrontend.chunks)
+			return
+		}
+		terminalFrontend.chunks <- append([]byte(nil), buffer[:count]...)
+	}
+}
+
+func (terminalFrontend *TerminalFrontend) Poll(core *chip8.Chip8Core) bool {
+	for keyIndex, frames := range terminalFrontend.held {
+		if frames > 0 {
+			terminalFrontend.held[keyIndex]--
+			if frames == 1 {
+				core.SetKey(uint8(keyIndex), false)
+			}
+		}
+	}
+	for {
+		select {
+		case chunk, open := <-terminalFrontend.chunks:
+			if !open {
+				return false
+			}
+			if !terminalFrontend.handleInput(core, chunk) {
+				return false
+			}
+		default:
+			return true
+		}
+	}
+}
+
+func (terminalFrontend *TerminalFrontend) AttachMachine(machine *chip8.Machine) {
+	terminalFrontend.machine = machine
+}
+
+// terminalEscapeSequences maps the escape sequences of the keys the debugger uses to their control character equivalent.
+var terminalEscapeSequences = map[string]rune{
+	"\x1bOQ":   0x05, // F2
+	"\x1b[12~": 0x05, // F2
+	"\x1b[15~": 0x10, // F5
+	"\x1b[18~": 0x13, // F7
+	"\x1b[19~": 0x14, // F8
+	"\x1b[21~": 0x0E, // F10
+	"\x1b[A":   terminalKeyUp,
+	"\x1b[B":   terminalKeyDown,
+	"\x1b[C":   terminalKeyRight,
+	"\x1b[D":   terminalKeyLeft,
+	"\x1b[5~":  terminalKeyPageUp,
+	"\x1b[6~":  terminalKeyPageDown,
+}
+
+// Private-use runes standing for the navigation keys once their escape sequence is decoded.
+const (
+	terminalKeyUp rune = 0xE000 + iota
+	terminalKeyDown
+	terminalKeyRight
+	terminalKeyLeft
+	terminalKeyPageUp
+	terminalKeyPageDown
+)
+
+func (terminalFrontend *TerminalFrontend) handleInput(core *chip8.Chip8Core, chunk []byte) bool {
+	if len(chunk) == 1 && chunk[0] == 0x1B {
+		return false
+	}
+	text := string(chunk)
+	for len(text) > 0 {
+		if text[0] == 0x1B {
+			matched := false
+			for sequence, key := range terminalEscapeSequences {
+				if strings.HasPrefix(text, sequence) {
+					terminalFrontend.handleKey(core, key)
+					text = text[len(sequence):]
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				// Other escape sequences are not part of the keypad.
+				return true
+			}
+			continue
+		}
+		character, size := utf8.DecodeRuneInString(text)
+		text = text[size:]
+		if !terminalFrontend.handleKey(core, character) {
+			return false
+		}
+	}
+	return true
+}
+
+func (terminalFrontend *TerminalFrontend) handleKey(core *chip8.Chip8Core, key rune) bool {
+	memoryView := terminalFrontend.memoryView
+	switch key {
+	case 0x03:
+		return false
+	case '\t':
+		terminalFrontend.showPanel = !terminalFrontend.showPanel
+		terminalFrontend.clear()
+		return true
+	case 0x05:
+		terminalFrontend.showMemory = !terminalFrontend.showMemory
+		terminalFrontend.clear()
+		return true
+	case 0x10:
+		if terminalFrontend.machine != nil {
+			if terminalFrontend.machine.Paused() {
+				terminalFrontend.machine.Resume()
+			} else {
+				terminalFrontend.machine.Pause()
+			}
+		}
+		return true
+	case 0x13:
+		if terminalFrontend.machine != nil {
+			terminalFrontend.speed.CycleSlowMotion(terminalFrontend.machine)
+		}
+		return true
+	case 0x14:
+		if terminalFrontend.machine != nil {
+			terminalFrontend.speed.ToggleFastForward(terminalFrontend.machine)
+		}
+		return true
+	case 0x0E:
+		if terminalFrontend.machine != nil {
+			terminalFrontend.machine.AdvanceFrame()
+		}
+		return true
+	}
+	if terminalFrontend.showMemory {
+		switch key {
+		case 0x06:
+			memoryView.FollowI = !memoryView.FollowI
+			return true
+		case terminalKeyUp:
+			memoryView.MoveCursor(core, -MemoryViewColumns)
+			return true
+		case terminalKeyDown:
+			memoryView.MoveCursor(core, MemoryViewColumns)
+			return true
+		case terminalKeyLeft:
+			memoryView.MoveCursor(core, -1)
+			return true
+		case terminalKeyRight:
+			memoryView.MoveCursor(core, 1)
+			return true
+		case terminalKeyPageUp:
+			memoryView.MoveCursor(core, -MemoryViewColumns*memoryView.Rows)
+			return true
+		case terminalKeyPageDown:
+			memoryView.MoveCursor(core, MemoryViewColumns*memoryView.Rows)
+			return true
+		}
+		if terminalFrontend.machine != nil && terminalFrontend.machine.Paused() {
+			if digit, err := strconv.ParseUint(string(key), 16, 8); err == nil {
+				memoryView.EditNibble(core, byte(digit))
+				return true
+			}
+		}
+	}
+	if keyIndex, exists := terminalFrontend.keyMap[key]; exists {
+		core.SetKey(keyIndex, true)
+		terminalFrontend.held[keyIndex] = terminalKeyHoldFrames
+	}
+	return true
+}
+
+func (terminalFrontend *TerminalFrontend) clear() {
+	terminalFrontend.output.WriteString("\x1b[2J")
+	terminalFrontend.last = ""
+}
+
+func (terminalFrontend *TerminalFrontend) Draw(core *chip8.Chip8Core) error {
+	lines := terminalScreenLines(core)
+	if terminalFrontend.showPanel {
+		panel := terminalPanelLines(core)
+		for len(lines) < len(panel) {
+			lines = append(lines, strings.Repeat(" ", core.ScreenWidth()))
+		}
+		for index, line := range panel {
+			lines[index] += "  │ " + line
+		}
+	}
+	if terminalFrontend.machine != nil {
+		// Only the text of the script overlay fits in a terminal; it is listed under the screen.
+		for _, item := range terminalFrontend.machine.Overlay.Items {
+			if item.Text != "" {
+				lines = append(lines, item.Text)
+			}
+		}
+		if status := terminalFrontend.machine.SpeedStatus(); status != "" {
+			lines = append(lines, "["+strings.ToUpper(status)+"]")
+		}
+	}
+	if terminalFrontend.showMemory {
+		terminalFrontend.memoryView.Update(core)
+		lines = append(lines, "")
+		lines = append(lines, terminalFrontend.memoryLines(core)...)
+	}
+	frame := strings.Join(lines, "\x1b[K\r\n")
+	if frame == terminalFrontend.last {
+		return nil
+	}
+	terminalFrontend.last = frame
+
+	terminalFrontend.output.WriteString("\x1b[H" + terminalFrontend.colors)
+	terminalFrontend.output.WriteString(frame)
+	terminalFrontend.output.WriteString("\x1b[K\r\n\x1b[J\x1b[0m")
+	return terminalFrontend.output.Flush()
+}
+
+// terminalScreenLines renders two rows of pixels per line: the upper one with the top half-block, the lower one with the bottom half-block.
+func terminalScreenLines(core *chip8.Chip8Core) []string {
+	var lines []string
+	for positionY := 0; positionY < core.ScreenHeight(); positionY += 2 {
+		var line strings.Builder
+		for positionX := 0; positionX < core.ScreenWidth(); positionX++ {
+			top := core.GetPixel(uint8(positionX), uint8(positionY))
+			bottom := positionY+1 < core.ScreenHeight() && core.GetPixel(uint8(positionX), uint8(positionY+1))
+			switch {
+			case top && bottom:
+				line.WriteString("█")
+			case top:
+				line.WriteString("▀")
+			case bottom:
+				line.WriteString("▄")
+			default:
+				line.WriteString(" ")
+			}
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+func terminalPanelLines(core *chip8.Chip8Core) []string {
+	lines := []string{
+		fmt.Sprintf("PC %04X  I %04X", core.PC, core.I),
+		fmt.Sprintf("SP %02X  DT %02X  ST %02X", core.SP, core.DelayTimer, core.SoundTimer),
+		"",
+	}
+	for row := 0; row < 4; row++ {
+		var line strings.Builder
+		for column := 0; column < 4; column++ {
+			registerIndex := row*4 + column
+			fmt.Fprintf(&line, "V%X %02X  ", registerIndex, core.V[registerIndex])
+		}
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+	lines = append(lines, "", "Stack")
+	for index := uint16(0); index < core.SP && int(index) < len(core.Stack); index++ {
+		lines = append(lines, fmt.Sprintf("%2d %04X", index, core.Stack[index]))
+	}
+	return lines
+}
+
+// memoryLines renders the memory view with the instruction at PC, the byte at I and the rest of the sprite
+// in color, the cursor in reverse video, and the sprite at I previewed on the right.
+func (terminalFrontend *TerminalFrontend) memoryLines(core *chip8.Chip8Core) []string {
+	memoryView := terminalFrontend.memoryView
+	lines := []string{memoryView.Status(core)}
+	sprite := memoryView.Sprite(core)
+	for row, address := range memoryView.RowAddresses(core) {
+		var line strings.Builder
+		line.WriteString(FormatMemoryAddress(core, address) + " ")
+		var asciiPart strings.Builder
+		for column := 0; column < MemoryViewColumns && int(address)+column < len(core.Memory); column++ {
+			current := address + uint32(column)
+			style := ""
+			switch memoryView.Highlight(core, current) {
+			case HighlightPC:
+				style = "\x1b[30;43m"
+			case HighlightI:
+				style = "\x1b[30;46m"
+			case HighlightSprite:
+				style = "\x1b[36m"
+			}
+			if current == memoryView.Cursor {
+				style += "\x1b[7m"
+			}
+			reset := ""
+			if style != "" {
+				reset = "\x1b[0m" + terminalFrontend.colors
+			}
+			fmt.Fprintf(&line, " %s%02X%s", style, core.Memory[current], reset)
+			asciiPart.WriteString(style + MemoryASCII(core.Memory[current]) + reset)
+		}
+		line.WriteString("  " + asciiPart.String())
+		if row < len(sprite) {
+			line.WriteString("   " + terminalSpriteRow(sprite[row]))
+		}
+		lines = append(lines, line.String())
+	}
+	// Sprites taller than the view continue below it, aligned with the preview.
+	for row := len(lines) - 1; row < len(sprite); row++ {
+		addressWidth := len(FormatMemoryAddress(core, 0)) + 1
+		lines = append(lines, strings.Repeat(" ", addressWidth+3*MemoryViewColumns+2+MemoryViewColumns+3)+terminalSpriteRow(sprite[row]))
+	}
+	return lines
+}
+
+// terminalSpriteRow draws a row of the sprite preview, two characters per pixel to keep it square.
+func terminalSpriteRow(pixels []bool) string {
+	var row strings.Builder
+	for _, pixel := range pixels {
+		if pixel {
+			row.WriteString("██")
+		} else {
+			row.WriteString("··")
+		}
+	}
+	return row.String()
+}
+
+func (terminalFrontend *TerminalFrontend) SetTone(on bool) {
+	if on {
+		terminalFrontend.output.WriteString("\a")
+	}
+}
+
+func (terminalFrontend *TerminalFrontend) Close() error {
+	terminalFrontend.output.WriteString("\x1b[?25h\r\n")
+	err := terminalFrontend.output.Flush()
+	if terminalFrontend.state != nil {
+		if restoreErr := term.Restore(int(terminalFrontend.input.Fd()), terminalFrontend.state); restoreErr != nil && err == nil {
+			err = restoreErr
+		}
+	}
+	return err
+}
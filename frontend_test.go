@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// TestSpeedControl checks that fast-forward wins over slow motion while it is on, and that releasing it brings
+// the machine back to the slow motion speed.
+func TestSpeedControl(t *testing.T) {
+	machine := chip8.NewMachine(chip8.NewManualClock())
+	speedControl := SpeedControl{FastForward: 4}
+	steps := []struct {
+		Name     string
+		Action   func()
+		Expected float64
+	}{
+		{"slow motion", func() { speedControl.CycleSlowMotion(machine) }, 0.5},
+		{"fast-forward", func() { speedControl.SetFastForward(machine, true) }, 4},
+		{"slow motion while fast-forwarding", func() { speedControl.CycleSlowMotion(machine) }, 4},
+		{"fast-forward released", func() { speedControl.SetFastForward(machine, false) }, 0.25},
+		{"fast-forward toggled", func() { speedControl.ToggleFastForward(machine) }, 4},
+		{"fast-forward toggled again", func() { speedControl.ToggleFastForward(machine) }, 0.25},
+		{"slow motion back to normal", func() {
+			speedControl.CycleSlowMotion(machine)
+			speedControl.CycleSlowMotion(machine)
+		}, 1},
+	}
+	for _, step := range steps {
+		step.Action()
+		if machine.Speed() != step.Expected {
+			t.Errorf("after %s the machine runs at %g, want %g", step.Name, machine.Speed(), step.Expected)
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// gdbRegister describes a register of the machine as seen by GDB.
+type gdbRegister struct {
+	name string
+	size int // size is the size of the register in bytes.
+}
+
+// gdbRegisters lists the registers in GDB numbering order: V0 to VF, I, PC, SP and both timers. I has 32 bits
+// to reach all of the MegaChip memory. Register values are sent in little-endian byte order, as GDB expects
+// from a target without an architecture.
+var gdbRegisters = func() []gdbRegister {
+	var registers []gdbRegister
+	for index := 0; index < 16; index++ {
+		registers = append(registers, gdbRegister{fmt.Sprintf("v%x", index), 1})
+	}
+	return append(registers, gdbRegister{"i", 4}, gdbRegister{"pc", 2}, gdbRegister{"sp", 1}, gdbRegister{"dt", 1}, gdbRegister{"st", 1})
+}()
+
+// gdbPacketSize is the longest packet the stub accepts and sends, advertised in hexadecimal as GDB reads it.
+// Memory reads are cut to fit a reply in it, GDB asking again for the rest.
+const gdbPacketSize = 0x4000
+
+// gdbTargetDescription is served through qXfer so clients can name and size the registers.
+var gdbTargetDescription = func() string {
+	var description strings.Builder
+	description.WriteString(`<?xml version="1.0"?><!DOCTYPE target SYSTEM "gdb-target.dtd"><target version="1.0"><feature name="org.chip8.core">`)
+	for _, register := range gdbRegisters {
+		registerType := "uint" + strconv.Itoa(register.size*8)
+		if register.name == "pc" {
+			registerType = "code_ptr"
+		}
+		fmt.Fprintf(&description, `<reg name="%s" bitsize="%d" type="%s"/>`, register.name, register.size*8, registerType)
+	}
+	description.WriteString(`</feature></target>`)
+	return description.String()
+}()
+
+// GDBStub lets GDB and other tools speaking the GDB remote serial protocol debug the machine over TCP,
+// one client at a time. While a client is attached, the machine only runs when the client continues it;
+// it stops again at software breakpoints, when the client interrupts it, or for good when it halts.
+type GDBStub struct {
+	machine  *chip8.Machine
+	listener net.Listener
+	stops    chan string
+}
+
+// ListenGDB opens the TCP port of the stub. An address without a host listens on localhost only.
+func ListenGDB(address string, machine *chip8.Machine) (*GDBStub, error) {
+	if strings.HasPrefix(address, ":") {
+		address = "localhost" + address
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	gdbStub := &GDBStub{machine: machine, listener: listener, stops: make(chan string, 1)}
+	machine.OnBreak(func(address uint16) { gdbStub.notify("S05") })
+	machine.OnHalt(func(reason string) { gdbStub.notify("W00") })
+	return gdbStub, nil
+}
+
+func (gdbStub *GDBStub) Addr() net.Addr {
+	return gdbStub.listener.Addr()
+}
+
+// Serve accepts clients until the stub is closed.
+func (gdbStub *GDBStub) Serve() error {
+	for {
+		connection, err := gdbStub.listener.Accept()
+		if err != nil {
+			return err
+		}
+		gdbStub.serveConnection(connection)
+	}
+}
+
+func (gdbStub *GDBStub) Close() error {
+	return gdbStub.listener.Close()
+}
+
+// notify records why the machine stopped; it is called with the machine locked, so it never blocks.
+func (gdbStub *GDBStub) notify(reply string) {
+	select {
+	case gdbStub.stops <- reply:
+	default:
+	}
+}
+
+// gdbSession is the state of an attached client.
+type gdbSession struct {
+	gdbStub    *GDBStub
+	connection net.Conn
+	running    bool
+	detached   bool
+}
+
+func (gdbStub *GDBStub) serveConnection(connection net.Conn) {
+	machine := gdbStub.machine
+	machine.Lock()
+	machine.Pause()
+	machine.Unlock()
+	select {
+	case <-gdbStub.stops:
+	default:
+	}
+
+	packets := make(chan string)
+	go readGDBPackets(connection, packets)
+	defer func() {
+		connection.Close()
+		for range packets {
+		}
+	}()
+	session := &gdbSession{gdbStub: gdbStub, connection: connection}
+	for !session.detached {
+		select {
+		case packet, open := <-packets:
+			if !open {
+				// A client that goes away leaves the program running.
+				machine.Lock()
+				machine.Resume()
+				machine.Unlock()
+				return
+			}
+			session.handle(packet)
+		case reply := <-gdbStub.stops:
+			if session.running {
+				session.running = false
+				session.send(reply)
+			}
+		}
+	}
+}
+
+// readGDBPackets acknowledges and forwards the packets of the client, and forwards interrupt requests as "\x03".
+// The channel is closed when the connection is.
+func readGDBPackets(connection net.Conn, packets chan<- string) {
+	defer close(packets)
+	reader := bufio.NewReader(connection)
+	for {
+		character, err := reader.ReadByte()
+		if err != nil {
+			return
+		}
+		switch character {
+		case 0x03:
+			packets <- "\x03"
+		case '$':
+			data, err := reader.ReadString('#')
+			if err != nil {
+				return
+			}
+			data = strings.TrimSuffix(data, "#")
+			checksum := make([]byte, 2)
+			if _, err := io.ReadFull(reader, checksum); err != nil {
+				return
+			}
+			expected, err := strconv.ParseUint(string(checksum), 16, 8)
+			if err != nil || byte(expected) != gdbChecksum(data) {
+				connection.Write([]byte("-"))
+				continue
+			}
+			connection.Write([]byte("+"))
+			packets <- data
+		}
+	}
+}
+
+func gdbChecksum(data string) byte {
+	var sum byte
+	for index := 0; index < len(data); index++ {
+		sum += data[index]
+	}
+	return sum
+}
+
+func (session *gdbSession) send(data string) {
+	fmt.Fprintf(session.connection, "$%s#%02x", data, gdbChecksum(data))
+}
+
+func (session *gdbSession) handle(packet string) {
+	machine := session.gdbStub.machine
+	if packet == "\x03" {
+		if session.running {
+			machine.Lock()
+			machine.Pause()
+			machine.Unlock()
+			session.running = false
+			session.send("S02")
+		}
+		return
+	}
+	if packet == "k" {
+		machine.Lock()
+		machine.Stop()
+		machine.Unlock()
+		session.detached = true
+		return
+	}
+	machine.Lock()
+	reply := session.reply(packet)
+	machine.Unlock()
+	if !session.running {
+		session.send(reply)
+	}
+}
+
+// reply executes a command with the machine locked and returns the answer; unsupported commands get an empty one.
+func (session *gdbSession) reply(packet string) string {
+	if packet == "" {
+		return ""
+	}
+	machine := session.gdbStub.machine
+	core := machine.Core
+	command, arguments := packet[:1], packet[1:]
+	switch {
+	case command == "?":
+		return session.stopReply()
+	case command == "g":
+		var registers strings.Builder
+		for index := range gdbRegisters {
+			registers.WriteString(encodeGDBRegister(core, index))
+		}
+		return registers.String()
+	case command == "G":
+		for index, register := range gdbRegisters {
+			if len(arguments) < register.size*2 {
+				return "E01"
+			}
+			if !decodeGDBRegister(core, index, arguments[:register.size*2]) {
+				return "E01"
+			}
+			arguments = arguments[register.size*2:]
+		}
+		return "OK"
+	case command == "p":
+		index, err := strconv.ParseUint(arguments, 16, 8)
+		if err != nil || int(index) >= len(gdbRegisters) {
+			return "E01"
+		}
+		return encodeGDBRegister(core, int(index))
+	case command == "P":
+		number, value, found := strings.Cut(arguments, "=")
+		index, err := strconv.ParseUint(number, 16, 8)
+		if !found || err != nil || int(index) >= len(gdbRegisters) || !decodeGDBRegister(core, int(index), value) {
+			return "E01"
+		}
+		return "OK"
+	case command == "m":
+		address, length, ok := parseGDBRange(arguments, len(core.Memory))
+		if !ok {
+			return "E01"
+		}
+		length = min(length, gdbPacketSize/2)
+		return hex.EncodeToString(core.Memory[address : address+length])
+	case command == "M":
+		memoryRange, data, found := strings.Cut(arguments, ":")
+		address, length, ok := parseGDBRange(memoryRange, len(core.Memory))
+		content, err := hex.DecodeString(data)
+		if !found || !ok || err != nil || len(content) != length {
+			return "E01"
+		}
+		copy(core.Memory[address:], content)
+		return "OK"
+	case command == "s":
+		if !session.resumeAt(arguments) {
+			return "E01"
+		}
+		if !machine.Halted() {
+			machine.Step()
+		}
+		return session.stopReply()
+	case command == "c":
+		if !session.resumeAt(arguments) {
+			return "E01"
+		}
+		if machine.Halted() {
+			return "W00"
+		}
+		machine.Resume()
+		session.running = true
+		return ""
+	case command == "Z" || command == "z":
+		fields := strings.Split(arguments, ",")
+		if len(fields) < 2 || (fields[0] != "0" && fields[0] != "1") {
+			return ""
+		}
+		address, err := strconv.ParseUint(fields[1], 16, 16)
+		if err != nil {
+			return "E01"
+		}
+		if command == "Z" {
+			machine.SetBreakpoint(uint16(address))
+		} else {
+			machine.ClearBreakpoint(uint16(address))
+		}
+		return "OK"
+	case command == "D":
+		machine.Resume()
+		session.detached = true
+		return "OK"
+	case command == "H" || command == "T":
+		return "OK"
+	case packet == "qC":
+		return "QC1"
+	case packet == "qAttached":
+		return "1"
+	case packet == "qfThreadInfo":
+		return "m1"
+	case packet == "qsThreadInfo":
+		return "l"
+	case strings.HasPrefix(packet, "qSupported"):
+		return fmt.Sprintf("PacketSize=%X;qXfer:features:read+", gdbPacketSize)
+	case strings.HasPrefix(packet, "qXfer:features:read:target.xml:"):
+		offset, length, ok := parseGDBRange(strings.TrimPrefix(packet, "qXfer:features:read:target.xml:"), len(gdbTargetDescription)+1)
+		if !ok {
+			return "E01"
+		}
+		end := min(offset+length, len(gdbTargetDescription))
+		offset = min(offset, end)
+		if end == len(gdbTargetDescription) {
+			return "l" + gdbTargetDescription[offset:end]
+		}
+		return "m" + gdbTargetDescription[offset:end]
+	}
+	return ""
+}
+
+func (session *gdbSession) stopReply() string {
+	if session.gdbStub.machine.Halted() {
+		return "W00"
+	}
+	return "S05"
+}
+
+// resumeAt moves PC to the optional address of a step or continue command.
+func (session *gdbSession) resumeAt(arguments string) bool {
+	if arguments == "" {
+		return true
+	}
+	address, err := strconv.ParseUint(arguments, 16, 16)
+	if err != nil {
+		return false
+	}
+	session.gdbStub.machine.Core.SetPC(uint16(address))
+	return true
+}
+
+// parseGDBRange parses "address,length" and checks the range lies within size bytes.
+func parseGDBRange(arguments string, size int) (int, int, bool) {
+	addressText, lengthText, found := strings.Cut(arguments, ",")
+	address, addressErr := strconv.ParseUint(addressText, 16, 32)
+	length, lengthErr := strconv.ParseUint(lengthText, 16, 32)
+	if !found || addressErr != nil || lengthErr != nil || int(address) > size || int(address+length) > size {
+		return 0, 0, false
+	}
+	return int(address), int(length), true
+}
+
+func gdbRegisterValue(core *chip8.Chip8Core, index int) uint32 {
+	switch {
+	case index < 16:
+		return uint32(core.V[index])
+	case index == 16:
+		return core.I
+	case index == 17:
+		return uint32(core.PC)
+	case index == 18:
+		return uint32(core.SP)
+	case index == 19:
+		return uint32(core.DelayTimer)
+	}
+	return uint32(core.SoundTimer)
+}
+
+// setGDBRegister writes a register the way the instructions would, I and PC wrapping around at the end of memory.
+func setGDBRegister(core *chip8.Chip8Core, index int, value uint32) {
+	switch {
+	case index < 16:
+		core.V[index] = byte(value)
+	case index == 16:
+		core.SetI(value)
+	case index == 17:
+		core.SetPC(uint16(value))
+	case index == 18:
+		core.SP = uint16(min(value, uint32(len(core.Stack))))
+	case index == 19:
+		core.DelayTimer = byte(value)
+	default:
+		core.SoundTimer = byte(value)
+	}
+}
+
+func encodeGDBRegister(core *chip8.Chip8Core, index int) string {
+	bytes := binary.LittleEndian.AppendUint32(nil, gdbRegisterValue(core, index))
+	return hex.EncodeToString(bytes[:gdbRegisters[index].size])
+}
+
+func decodeGDBRegister(core *chip8.Chip8Core, index int, text string) bool {
+	bytes, err := hex.DecodeString(text)
+	if err != nil || len(bytes) != gdbRegisters[index].size {
+		return false
+	}
+	var value uint32
+	for position, byteValue := range bytes {
+		value |= uint32(byteValue) << (8 * position)
+	}
+	setGDBRegister(core, index, value)
+	return true
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// gdbClient speaks the GDB remote serial protocol to a stub, the way GDB would.
+type gdbClient struct {
+	t          *testing.T
+	connection net.Conn
+	reader     *bufio.Reader
+}
+
+// startGDBStub serves a machine running program on a loopback port and connects a client to it.
+func startGDBStub(t *testing.T, program []byte) (*chip8.Machine, *gdbClient) {
+	t.Helper()
+	return startGDBStubOn(t, chip8.Platforms["chip8"], program)
+}
+
+// startGDBStubOn is startGDBStub for a program of the given platform.
+func startGDBStubOn(t *testing.T, platform *chip8.Platform, program []byte) (*chip8.Machine, *gdbClient) {
+	t.Helper()
+	machine := chip8.NewMachine(chip8.NewManualClock())
+	if err := machine.LoadROMImage(&chip8.ROMImage{Data: program, Platform: platform}); err != nil {
+		t.Fatal(err)
+	}
+	gdbStub, err := ListenGDB("127.0.0.1:0", machine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go gdbStub.Serve()
+	connection, err := net.Dial("tcp", gdbStub.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		connection.Close()
+		gdbStub.Close()
+	})
+	return machine, &gdbClient{t: t, connection: connection, reader: bufio.NewReader(connection)}
+}
+
+// send sends a packet and waits for the stub to acknowledge it.
+func (gdbClient *gdbClient) send(packet string) {
+	gdbClient.t.Helper()
+	fmt.Fprintf(gdbClient.connection, "$%s#%02x", packet, gdbChecksum(packet))
+	gdbClient.connection.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if acknowledgement, err := gdbClient.reader.ReadByte(); err != nil || acknowledgement != '+' {
+		gdbClient.t.Fatalf("packet %q acknowledged with %q (%v)", packet, acknowledgement, err)
+	}
+}
+
+// receive reads the next packet of the stub, or returns the error of the connection, such as the timeout expiring.
+func (gdbClient *gdbClient) receive(timeout time.Duration) (string, error) {
+	gdbClient.connection.SetReadDeadline(time.Now().Add(timeout))
+	if _, err := gdbClient.reader.ReadString('$'); err != nil {
+		return "", err
+	}
+	data, err := gdbClient.reader.ReadString('#')
+	if err != nil {
+		return "", err
+	}
+	checksum := make([]byte, 2)
+	if _, err := io.ReadFull(gdbClient.reader, checksum); err != nil {
+		return "", err
+	}
+	data = strings.TrimSuffix(data, "#")
+	if fmt.Sprintf("%02x", gdbChecksum(data)) != string(checksum) {
+		gdbClient.t.Fatalf("packet %q has checksum %s", data, checksum)
+	}
+	return data, nil
+}
+
+// exchange sends a packet and returns the reply.
+func (gdbClient *gdbClient) exchange(packet string) string {
+	gdbClient.t.Helper()
+	gdbClient.send(packet)
+	reply, err := gdbClient.receive(5 * time.Second)
+	if err != nil {
+		gdbClient.t.Fatalf("no reply to %q: %v", packet, err)
+	}
+	return reply
+}
+
+// TestGDBStubPacketSize checks that a memory read of the whole MegaChip memory is cut to fit the packet size
+// advertised by qSupported.
+func TestGDBStubPacketSize(t *testing.T) {
+	_, client := startGDBStubOn(t, chip8.Platforms["megachip"], []byte{0x12, 0x00})
+	if reply := client.exchange("qSupported"); !strings.HasPrefix(reply, "PacketSize=4000;") {
+		t.Fatalf("qSupported replied %q, want a packet size of 4000", reply)
+	}
+	if reply := client.exchange("m0,100000"); len(reply) != 0x4000 || reply[0x400:0x404] != "1200" {
+		t.Errorf("m0,100000 replied %d characters starting the program with %q, want 4000 starting it with 1200",
+			len(reply), reply[0x400:0x404])
+	}
+}
+
+func TestGDBStub(t *testing.T) {
+	// 0200: V0 := 1, 0202: V0 += 1, 0204: jump 0202
+	machine, client := startGDBStub(t, []byte{0x60, 0x01, 0x70, 0x01, 0x12, 0x02})
+
+	if reply := client.exchange("?"); reply != "S05" {
+		t.Errorf("? replied %q, want S05", reply)
+	}
+	registers := client.exchange("g")
+	if len(registers) != 2*(16+4+2+1+1+1) || registers[40:44] != "0002" {
+		t.Errorf("g replied %q, want 21 registers with PC 0002", registers)
+	}
+
+	for _, registerCase := range []struct {
+		Write    string
+		Read     string
+		Expected string
+	}{
+		{"P3=2a", "p3", "2a"},
+		{"P10=34120000", "p10", "34120000"},
+		{"P11=0402", "p11", "0402"},
+		// PC wraps around at the end of the 4096 bytes of memory, as JP would.
+		{"P11=0410", "p11", "0400"},
+		{"P12=ff", "p12", "10"},
+	} {
+		if reply := client.exchange(registerCase.Write); reply != "OK" {
+			t.Errorf("%s replied %q, want OK", registerCase.Write, reply)
+		}
+		if reply := client.exchange(registerCase.Read); reply != registerCase.Expected {
+			t.Errorf("%s after %s replied %q, want %q", registerCase.Read, registerCase.Write, reply, registerCase.Expected)
+		}
+	}
+	for _, packet := range []string{"P17=00", "P11=02", "p17", "m1000,1", "M300,2:abcdef"} {
+		if reply := client.exchange(packet); reply != "E01" {
+			t.Errorf("%s replied %q, want E01", packet, reply)
+		}
+	}
+
+	if reply := client.exchange("m200,6"); reply != "600170011202" {
+		t.Errorf("m200,6 replied %q, want the program", reply)
+	}
+	if reply := client.exchange("M300,2:abcd"); reply != "OK" {
+		t.Errorf("M300,2 replied %q, want OK", reply)
+	}
+	if reply := client.exchange("m300,2"); reply != "abcd" {
+		t.Errorf("m300,2 after M replied %q, want abcd", reply)
+	}
+
+	if reply := client.exchange("s200"); reply != "S05" {
+		t.Errorf("s200 replied %q, want S05", reply)
+	}
+	if machine.Core.PC != 0x202 || machine.Core.V[0] != 1 {
+		t.Errorf("step left PC at %04X and V0 at %d, want 0202 and 1", machine.Core.PC, machine.Core.V[0])
+	}
+
+	if reply := client.exchange("Z0,204"); reply != "OK" {
+		t.Errorf("Z0,204 replied %q, want OK", reply)
+	}
+	client.send("c")
+	// The machine runs once the stub resumed it, and stops at the breakpoint.
+	reply, err := "", error(nil)
+	for attempt := 0; attempt < 100 && reply == ""; attempt++ {
+		machine.RunFrame()
+		reply, err = client.receive(10 * time.Millisecond)
+		if netError, ok := err.(net.Error); err != nil && !(ok && netError.Timeout()) {
+			t.Fatal(err)
+		}
+	}
+	if reply != "S05" || machine.Core.PC != 0x204 {
+		t.Errorf("c stopped with %q at %04X, want S05 at 0204", reply, machine.Core.PC)
+	}
+	if reply := client.exchange("z0,204"); reply != "OK" {
+		t.Errorf("z0,204 replied %q, want OK", reply)
+	}
+
+	machine.Lock()
+	machine.Halt("halted by the test")
+	machine.Unlock()
+	for _, packet := range []string{"?", "c"} {
+		if reply := client.exchange(packet); reply != "W00" {
+			t.Errorf("%s on a halted machine replied %q, want W00", packet, reply)
+		}
+	}
+}
@@ -1,5 +1,11 @@
-module main
+module github.com/nebul/chip8-go
 
 go 1.21.3
 
-require github.com/veandco/go-sdl2 v0.4.38
+require (
+	github.com/veandco/go-sdl2 v0.4.38
+	github.com/yuin/gopher-lua v1.1.1
+	golang.org/x/term v0.20.0
+)
+
+require golang.org/x/sys v0.20.0 // indirect
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "regenerate the golden images of TestGoldenImages")
+
+// goldenScale is the size of a CHIP-8 pixel in the golden images, big enough to look at them.
+const goldenScale = 4
+
+// goldenInput presses or releases a key before a frame runs.
+type goldenInput struct {
+	Frame   int
+	Key     uint8
+	Pressed bool
+}
+
+// goldenCases are the programs run by TestGoldenImages, each compared to testdata/golden/NAME.png after its frames.
+var goldenCases = []struct {
+	Name   string
+	ROM    string
+	Frames int
+	Inputs []goldenInput
+}{
+	{Name: "TEST_OPCODE", ROM: "roms/TEST_OPCODE", Frames: 120},
+	{Name: "PONG", ROM: "roms/PONG", Frames: 600, Inputs: []goldenInput{
+		{Frame: 30, Key: 0x1, Pressed: true},
+		{Frame: 60, Key: 0x1},
+		{Frame: 120, Key: 0x4, Pressed: true},
+		{Frame: 200, Key: 0x4},
+		{Frame: 240, Key: 0xD, Pressed: true},
+		{Frame: 300, Key: 0xD},
+	}},
+}
+
+var goldenPalette = color.Palette{color.RGBA{A: 0xFF}, color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}}
+
+// TestGoldenImages runs programs headlessly with scripted input and a seeded random sequence, and compares the
+// screen to the checked-in golden images. A mismatch writes NAME.diff.png next to the golden image: pixels lit
+// only in the golden image are red, pixels lit only now are green. Run with -update-golden to regenerate them.
+func TestGoldenImages(t *testing.T) {
+	for _, goldenCase := range goldenCases {
+		t.Run(goldenCase.Name, func(t *testing.T) {
+			rom, err := os.ReadFile(goldenCase.ROM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			machine := chip8.NewMachine(chip8.NewManualClock())
+			if err := machine.LoadROM(rom); err != nil {
+				t.Fatal(err)
+			}
+			machine.Seed(1)
+			inputs := goldenCase.Inputs
+			for frame := 0; frame < goldenCase.Frames; frame++ {
+				for len(inputs) > 0 && inputs[0].Frame == frame {
+					machine.Core.SetKey(inputs[0].Key, inputs[0].Pressed)
+					inputs = inputs[1:]
+				}
+				machine.RunFrame()
+			}
+
+			actual := ScreenImage(machine.Core, goldenScale, goldenPalette)
+			path := filepath.Join("testdata", "golden", goldenCase.Name+".png")
+			diffPath := filepath.Join("testdata", "golden", goldenCase.Name+".diff.png")
+			if *updateGolden {
+				if err := writeGoldenPNG(path, actual); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			expected, err := readGoldenPNG(path)
+			if err != nil {
+				t.Fatalf("%v (run go test -run TestGoldenImages -update-golden to create it)", err)
+			}
+			diff, differs := goldenDiff(expected, actual)
+			if !differs {
+				os.Remove(diffPath)
+				return
+			}
+			if err := writeGoldenPNG(diffPath, diff); err != nil {
+				t.Fatal(err)
+			}
+			t.Errorf("screen differs from %s, see %s", path, diffPath)
+		})
+	}
+}
+
+func readGoldenPNG(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return png.Decode(file)
+}
+
+func writeGoldenPNG(path string, goldenImage image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, goldenImage)
+}
+
+// goldenDiff paints the pixels of both images over the union of their bounds: gray where both are lit, red where
+// only the expected one is, green where only the actual one is.
+func goldenDiff(expected image.Image, actual image.Image) (*image.RGBA, bool) {
+	diff := image.NewRGBA(expected.Bounds().Union(actual.Bounds()))
+	lit := func(source image.Image, positionX int, positionY int) bool {
+		if !(image.Point{X: positionX, Y: positionY}).In(source.Bounds()) {
+			return false
+		}
+		red, green, blue, _ := source.At(positionX, positionY).RGBA()
+		return red+green+blue > 0
+	}
+	differs := expected.Bounds() != actual.Bounds()
+	for positionY := diff.Bounds().Min.Y; positionY < diff.Bounds().Max.Y; positionY++ {
+		for positionX := diff.Bounds().Min.X; positionX < diff.Bounds().Max.X; positionX++ {
+			expectedLit, actualLit := lit(expected, positionX, positionY), lit(actual, positionX, positionY)
+			pixel := color.RGBA{A: 0xFF}
+			switch {
+			case expectedLit && actualLit:
+				pixel = color.RGBA{R: 0x60, G: 0x60, B: 0x60, A: 0xFF}
+			case expectedLit:
+				pixel, differs = color.RGBA{R: 0xFF, A: 0xFF}, true
+			case actualLit:
+				pixel, differs = color.RGBA{G: 0xFF, A: 0xFF}, true
+			}
+			diff.Set(positionX, positionY, pixel)
+		}
+	}
+	return diff, differs
+}
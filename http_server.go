@@ -0,0 +1,457 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// maxHTTPBodySize bounds the programs and states the HTTP API accepts.
+const maxHTTPBodySize = 16 << 20
+
+// maxHTTPStepCount bounds the instructions of a single /step, which holds the machine and every other request
+// until they have run.
+const maxHTTPStepCount = 1 << 16
+
+// defaultHTTPMemoryLength is the length /memory returns when none is given.
+const defaultHTTPMemoryLength = 256
+
+// HTTPServer drives the machine from scripts and test harnesses through a local HTTP/JSON API:
+//
+//	GET  /status              paused, halted, idle, platform and title
+//	POST /rom?name=FILE       load the program in the body, decoded like a file called FILE
+//	POST /reset, /pause, /resume
+//	POST /step?count=N        execute N instructions (1 by default, at most 65536) and return the registers
+//	POST /frames?count=N      run N frames, then pause, and return the registers
+//	POST /keys/K, DELETE /keys/K  press or release the key K (0-F)
+//	GET  /registers
+//	GET  /memory?address=A&length=N  N bytes from A, 256 by default
+//	GET  /screen, /screen.png?scale=S
+//	GET  /state, PUT /state   save or restore the whole machine as JSON
+//
+// Once AttachCheats has been called, it also searches memory and edits the cheats of the program:
+//
+//	POST /search              start a memory search, every address being a candidate
+//	POST /search/narrow?compare=C&value=V  keep the candidates comparing as C (eq, changed, unchanged, inc, dec)
+//	GET  /search              the candidates left, with their current values
+//	GET  /cheats, PUT /cheats read or replace the cheat list of the program, in the format of ParseCheats
+//
+// Requests are served with the machine locked. Frames are still run by the loop driving the machine,
+// so /frames resumes it and waits for them; with a ManualClock, it also lets exactly these frames through.
+type HTTPServer struct {
+	machine        *chip8.Machine
+	mux            *http.ServeMux
+	methodHandlers map[string]map[string]http.HandlerFunc
+	framesLeft     int
+	framesDone     chan struct{}
+}
+
+type httpRegisters struct {
+	V          [16]byte   `json:"v"`
+	I          uint32     `json:"i"`
+	PC         uint16     `json:"pc"`
+	SP         uint16     `json:"sp"`
+	Stack      [16]uint16 `json:"stack"`
+	DelayTimer byte       `json:"delayTimer"`
+	SoundTimer byte       `json:"soundTimer"`
+	Keys       [16]bool   `json:"keys"`
+}
+
+type httpStatus struct {
+	Paused     bool   `json:"paused"`
+	Halted     bool   `json:"halted"`
+	HaltReason string `json:"haltReason,omitempty"`
+	Idle       string `json:"idle,omitempty"`
+	Platform   string `json:"platform"`
+	Title      string `json:"title,omitempty"`
+}
+
+type httpSearchCandidate struct {
+	Address uint32 `json:"address"`
+	Value   byte   `json:"value"`
+}
+
+type httpSearch struct {
+	Count      int                   `json:"count"`
+	Candidates []httpSearchCandidate `json:"candidates"` // Candidates are the first maxHTTPSearchCandidates addresses left.
+}
+
+// maxHTTPSearchCandidates bounds the candidates listed by /search; the first steps of a search leave thousands.
+const maxHTTPSearchCandidates = 256
+
+type httpScreen struct {
+	Width  int      `json:"width"`
+	Height int      `json:"height"`
+	Rows   []string `json:"rows"` // Rows are the lines of the screen, top first, with "1" for a lit pixel and "0" for an unlit one.
+}
+
+func NewHTTPServer(machine *chip8.Machine) *HTTPServer {
+	httpServer := &HTTPServer{machine: machine, mux: http.NewServeMux(), methodHandlers: map[string]map[string]http.HandlerFunc{}}
+	machine.OnFrame(httpServer.frameDone)
+
+	httpServer.handle("/status", http.MethodGet, httpServer.status)
+	httpServer.handle("/rom", http.MethodPost, httpServer.loadROM)
+	httpServer.handle("/reset", http.MethodPost, func(writer http.ResponseWriter, request *http.Request) {
+		machine.Reset()
+		httpServer.status(writer, request)
+	})
+	httpServer.handle("/pause", http.MethodPost, func(writer http.ResponseWriter, request *http.Request) {
+		machine.Pause()
+		httpServer.status(writer, request)
+	})
+	httpServer.handle("/resume", http.MethodPost, func(writer http.ResponseWriter, request *http.Request) {
+		machine.Resume()
+		httpServer.status(writer, request)
+	})
+	httpServer.handle("/step", http.MethodPost, httpServer.step)
+	httpServer.handle("/registers", http.MethodGet, httpServer.registers)
+	httpServer.handle("/memory", http.MethodGet, httpServer.memory)
+	httpServer.handle("/screen", http.MethodGet, httpServer.screen)
+	httpServer.handle("/screen.png", http.MethodGet, httpServer.screenPNG)
+	httpServer.handle("/state", http.MethodGet, func(writer http.ResponseWriter, request *http.Request) {
+		writeJSON(writer, machine.SaveState())
+	})
+	httpServer.handle("/state", http.MethodPut, func(writer http.ResponseWriter, request *http.Request) {
+		if err := machine.ReadState(http.MaxBytesReader(writer, request.Body, maxHTTPBodySize)); err != nil {
+			writeHTTPError(writer, http.StatusBadRequest, err)
+			return
+		}
+		httpServer.status(writer, request)
+	})
+	httpServer.handle("/keys/", http.MethodPost, httpServer.setKey)
+	httpServer.handle("/keys/", http.MethodDelete, httpServer.setKey)
+	// Running frames waits for the loop driving the machine, so the machine must not stay locked.
+	httpServer.mux.HandleFunc("/frames", httpServer.runFrames)
+	return httpServer
+}
+
+// ListenAndServe serves the API on a TCP address. An address without a host listens on localhost only.
+func (httpServer *HTTPServer) ListenAndServe(address string) error {
+	if strings.HasPrefix(address, ":") {
+		address = "localhost" + address
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return http.Serve(listener, httpServer)
+}
+
+func (httpServer *HTTPServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	httpServer.mux.ServeHTTP(writer, request)
+}
+
+// handle registers a handler run with the machine locked for a path and method. Several methods can share a path.
+func (httpServer *HTTPServer) handle(path string, method string, handler http.HandlerFunc) {
+	handlers, exists := httpServer.methodHandlers[path]
+	if !exists {
+		handlers = map[string]http.HandlerFunc{}
+		httpServer.methodHandlers[path] = handlers
+		httpServer.mux.HandleFunc(path, func(writer http.ResponseWriter, request *http.Request) {
+			handler, allowed := handlers[request.Method]
+			if !allowed {
+				writeHTTPError(writer, http.StatusMethodNotAllowed, fmt.Errorf("%s is not allowed on %s", request.Method, path))
+				return
+			}
+			httpServer.machine.Lock()
+			defer httpServer.machine.Unlock()
+			handler(writer, request)
+		})
+	}
+	handlers[method] = handler
+}
+
+func writeJSON(writer http.ResponseWriter, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(value)
+}
+
+func writeHTTPError(writer http.ResponseWriter, status int, err error) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(map[string]string{"error": err.Error()})
+}
+
+// queryNumber reads a number of the query string, in decimal or with a 0x prefix, or returns fallback when it is missing.
+func queryNumber(request *http.Request, name string, fallback int) (int, error) {
+	text := request.URL.Query().Get(name)
+	if text == "" {
+		return fallback, nil
+	}
+	value, err := strconv.ParseInt(text, 0, 32)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, text)
+	}
+	return int(value), nil
+}
+
+func (httpServer *HTTPServer) status(writer http.ResponseWriter, request *http.Request) {
+	machine := httpServer.machine
+	status := httpStatus{
+		Paused:     machine.Paused(),
+		Halted:     machine.Halted(),
+		HaltReason: machine.HaltReason(),
+		Idle:       machine.Idle().String(),
+		Platform:   machine.Platform.Name,
+	}
+	if machine.ROMInfo != nil {
+		status.Title = machine.ROMInfo.Title
+	}
+	writeJSON(writer, status)
+}
+
+func (httpServer *HTTPServer) loadROM(writer http.ResponseWriter, request *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, maxHTTPBodySize))
+	if err != nil {
+		writeHTTPError(writer, http.StatusBadRequest, err)
+		return
+	}
+	name := request.URL.Query().Get("name")
+	if name == "" {
+		name = "rom"
+	}
+	romImage, err := chip8.DecodeROM(name, data)
+	if err == nil {
+		err = httpServer.machine.LoadROMImage(romImage)
+	}
+	if err != nil {
+		writeHTTPError(writer, http.StatusBadRequest, err)
+		return
+	}
+	httpServer.status(writer, request)
+}
+
+func (httpServer *HTTPServer) step(writer http.ResponseWriter, request *http.Request) {
+	count, err := queryNumber(request, "count", 1)
+	if err == nil && count > maxHTTPStepCount {
+		err = fmt.Errorf("cannot step more than %d instructions at once", maxHTTPStepCount)
+	}
+	if err != nil {
+		writeHTTPError(writer, http.StatusBadRequest, err)
+		return
+	}
+	httpServer.machine.RunCycles(count)
+	httpServer.registers(writer, request)
+}
+
+func (httpServer *HTTPServer) registers(writer http.ResponseWriter, request *http.Request) {
+	core := httpServer.machine.Core
+	writeJSON(writer, httpRegisters{
+		V:          core.V,
+		I:          core.I,
+		PC:         core.PC,
+		SP:         core.SP,
+		Stack:      core.Stack,
+		DelayTimer: core.DelayTimer,
+		SoundTimer: core.SoundTimer,
+		Keys:       core.Keys,
+	})
+}
+
+// memory returns the bytes as a hex string, under "data", along with the address they start at.
+func (httpServer *HTTPServer) memory(writer http.ResponseWriter, request *http.Request) {
+	memory := httpServer.machine.Core.Memory
+	address, err := queryNumber(request, "address", 0)
+	if err != nil {
+		writeHTTPError(writer, http.StatusBadRequest, err)
+		return
+	}
+	if address > len(memory) {
+		writeHTTPError(writer, http.StatusBadRequest, fmt.Errorf("address outside of the %d bytes of memory", len(memory)))
+		return
+	}
+	length, err := queryNumber(request, "length", min(defaultHTTPMemoryLength, len(memory)-address))
+	if err != nil || address+length > len(memory) {
+		writeHTTPError(writer, http.StatusBadRequest, fmt.Errorf("range outside of the %d bytes of memory", len(memory)))
+		return
+	}
+	writeJSON(writer, map[string]any{"address": address, "data": hex.EncodeToString(memory[address : address+length])})
+}
+
+func (httpServer *HTTPServer) screen(writer http.ResponseWriter, request *http.Request) {
+	core := httpServer.machine.Core
+	screen := httpScreen{Width: core.ScreenWidth(), Height: core.ScreenHeight()}
+	for _, pixels := range core.Screen {
+		var row strings.Builder
+		for _, pixel := range pixels {
+			if pixel {
+				row.WriteByte('1')
+			} else {
+				row.WriteByte('0')
+			}
+		}
+		screen.Rows = append(screen.Rows, row.String())
+	}
+	writeJSON(writer, screen)
+}
+
+func (httpServer *HTTPServer) screenPNG(writer http.ResponseWriter, request *http.Request) {
+	scale, err := queryNumber(request, "scale", 1)
+	if err != nil || scale < 1 || scale > 64 {
+		writeHTTPError(writer, http.StatusBadRequest, fmt.Errorf("scale must be between 1 and 64"))
+		return
+	}
+	palette := color.Palette{color.RGBA{A: 0xFF}, color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}}
+	writer.Header().Set("Content-Type", "image/png")
+	png.Encode(writer, ColorScreenImage(httpServer.machine.Core, scale, palette))
+}
+
+func (httpServer *HTTPServer) setKey(writer http.ResponseWriter, request *http.Request) {
+	key, err := strconv.ParseUint(strings.TrimPrefix(request.URL.Path, "/keys/"), 16, 8)
+	if err != nil || key > 0xF {
+		writeHTTPError(writer, http.StatusNotFound, fmt.Errorf("keys are named 0 to F"))
+		return
+	}
+	httpServer.machine.Core.SetKey(uint8(key), request.Method == http.MethodPost)
+	httpServer.registers(writer, request)
+}
+
+// runFrames resumes the machine for count frames and answers once they have run, the machine paused again.
+// It answers early when the machine pauses or halts by itself, at a breakpoint for instance.
+func (httpServer *HTTPServer) runFrames(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writeHTTPError(writer, http.StatusMethodNotAllowed, fmt.Errorf("%s is not allowed on /frames", request.Method))
+		return
+	}
+	count, err := queryNumber(request, "count", 1)
+	if err != nil {
+		writeHTTPError(writer, http.StatusBadRequest, err)
+		return
+	}
+	machine := httpServer.machine
+	machine.Lock()
+	switch {
+	case machine.Halted():
+		machine.Unlock()
+		writeHTTPError(writer, http.StatusConflict, fmt.Errorf("the machine is halted: %s", machine.HaltReason()))
+		return
+	case httpServer.framesDone != nil:
+		machine.Unlock()
+		writeHTTPError(writer, http.StatusConflict, fmt.Errorf("frames are already running"))
+		return
+	case count == 0:
+		machine.Unlock()
+		httpServer.locked(httpServer.registers)(writer, request)
+		return
+	}
+	done := make(chan struct{})
+	httpServer.framesLeft = count
+	httpServer.framesDone = done
+	machine.Resume()
+	machine.Unlock()
+	// A manual clock lets frames through only when told to: these are the ones asked for.
+	if manualClock, ok := machine.Clock.(*chip8.ManualClock); ok {
+		manualClock.Advance(count)
+	}
+
+	select {
+	case <-done:
+	case <-request.Context().Done():
+		machine.Lock()
+		if httpServer.framesDone == done {
+			httpServer.framesDone = nil
+			machine.Pause()
+		}
+		machine.Unlock()
+		return
+	}
+	httpServer.locked(httpServer.registers)(writer, request)
+}
+
+// frameDone counts the frames run for /frames; it is called with the machine locked.
+func (httpServer *HTTPServer) frameDone(core *chip8.Chip8Core) {
+	if httpServer.framesDone == nil {
+		return
+	}
+	machine := httpServer.machine
+	if !machine.Paused() && !machine.Halted() {
+		httpServer.framesLeft--
+		if httpServer.framesLeft > 0 {
+			return
+		}
+		machine.Pause()
+	}
+	close(httpServer.framesDone)
+	httpServer.framesDone = nil
+}
+
+// AttachCheats serves the memory search and the cheat list of the engine; a PUT on /cheats saves the list.
+func (httpServer *HTTPServer) AttachCheats(cheatEngine *CheatEngine) {
+	httpServer.handle("/search", http.MethodPost, func(writer http.ResponseWriter, request *http.Request) {
+		cheatEngine.Search = NewMemorySearch(httpServer.machine.Core)
+		httpServer.search(writer, cheatEngine.Search)
+	})
+	httpServer.handle("/search", http.MethodGet, func(writer http.ResponseWriter, request *http.Request) {
+		if cheatEngine.Search == nil {
+			writeHTTPError(writer, http.StatusConflict, fmt.Errorf("no search has been started"))
+			return
+		}
+		httpServer.search(writer, cheatEngine.Search)
+	})
+	httpServer.handle("/search/narrow", http.MethodPost, func(writer http.ResponseWriter, request *http.Request) {
+		if cheatEngine.Search == nil {
+			writeHTTPError(writer, http.StatusConflict, fmt.Errorf("no search has been started"))
+			return
+		}
+		comparison, err := ParseMemoryComparison(request.URL.Query().Get("compare"))
+		if err != nil {
+			writeHTTPError(writer, http.StatusBadRequest, err)
+			return
+		}
+		value, err := queryNumber(request, "value", 0)
+		if err != nil || value > 0xFF {
+			writeHTTPError(writer, http.StatusBadRequest, fmt.Errorf("value must be a byte"))
+			return
+		}
+		cheatEngine.Search.Narrow(httpServer.machine.Core, comparison, byte(value))
+		httpServer.search(writer, cheatEngine.Search)
+	})
+	httpServer.handle("/cheats", http.MethodGet, func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain")
+		io.WriteString(writer, FormatCheats(cheatEngine.Cheats))
+	})
+	httpServer.handle("/cheats", http.MethodPut, func(writer http.ResponseWriter, request *http.Request) {
+		text, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, maxHTTPBodySize))
+		if err != nil {
+			writeHTTPError(writer, http.StatusBadRequest, err)
+			return
+		}
+		cheats, err := ParseCheats(string(text))
+		if err != nil {
+			writeHTTPError(writer, http.StatusBadRequest, err)
+			return
+		}
+		cheatEngine.Cheats = cheats
+		if err := cheatEngine.Save(); err != nil {
+			writeHTTPError(writer, http.StatusInternalServerError, err)
+			return
+		}
+		writer.Header().Set("Content-Type", "text/plain")
+		io.WriteString(writer, FormatCheats(cheatEngine.Cheats))
+	})
+}
+
+func (httpServer *HTTPServer) search(writer http.ResponseWriter, memorySearch *MemorySearch) {
+	result := httpSearch{Count: memorySearch.Count(), Candidates: []httpSearchCandidate{}}
+	for _, address := range memorySearch.Candidates(maxHTTPSearchCandidates) {
+		result.Candidates = append(result.Candidates, httpSearchCandidate{Address: address, Value: httpServer.machine.Core.Memory[address]})
+	}
+	writeJSON(writer, result)
+}
+
+func (httpServer *HTTPServer) locked(handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		httpServer.machine.Lock()
+		defer httpServer.machine.Unlock()
+		handler(writer, request)
+	}
+}
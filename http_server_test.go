@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// httpRequest serves a request and decodes the JSON of the answer into body, when not nil.
+func httpRequest(t *testing.T, httpServer *HTTPServer, method string, target string, requestBody []byte, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	httpServer.ServeHTTP(recorder, httptest.NewRequest(method, target, bytes.NewReader(requestBody)))
+	if body != nil && recorder.Code == http.StatusOK {
+		if err := json.Unmarshal(recorder.Body.Bytes(), body); err != nil {
+			t.Fatalf("%s %s answered %q: %v", method, target, recorder.Body, err)
+		}
+	}
+	return recorder
+}
+
+// newTestHTTPServer serves a machine running V0 += 1 in a loop.
+func newTestHTTPServer(t *testing.T) (*chip8.Machine, *HTTPServer) {
+	t.Helper()
+	machine := chip8.NewMachine(chip8.NewManualClock())
+	httpServer := NewHTTPServer(machine)
+	var status httpStatus
+	if recorder := httpRequest(t, httpServer, http.MethodPost, "/rom?name=loop.ch8", []byte{0x70, 0x01, 0x12, 0x00}, &status); recorder.Code != http.StatusOK {
+		t.Fatalf("/rom answered %d: %s", recorder.Code, recorder.Body)
+	}
+	if status.Platform != "chip8" {
+		t.Fatalf("the program runs on %q, want chip8", status.Platform)
+	}
+	return machine, httpServer
+}
+
+func TestHTTPServer(t *testing.T) {
+	machine, httpServer := newTestHTTPServer(t)
+
+	var registers httpRegisters
+	httpRequest(t, httpServer, http.MethodPost, "/step?count=3", nil, &registers)
+	if registers.V[0] != 2 || registers.PC != 0x202 {
+		t.Errorf("3 steps left V0 at %d and PC at %04X, want 2 and 0202", registers.V[0], registers.PC)
+	}
+
+	httpRequest(t, httpServer, http.MethodPost, "/keys/a", nil, &registers)
+	if !registers.Keys[0xA] {
+		t.Error("POST /keys/a did not press A")
+	}
+	httpRequest(t, httpServer, http.MethodDelete, "/keys/A", nil, &registers)
+	if registers.Keys[0xA] {
+		t.Error("DELETE /keys/A did not release A")
+	}
+
+	machine.Core.SetPixel(3, 1, true)
+	var screen httpScreen
+	httpRequest(t, httpServer, http.MethodGet, "/screen", nil, &screen)
+	if screen.Width != 64 || screen.Height != 32 || len(screen.Rows) != 32 || screen.Rows[1][:5] != "00010" {
+		t.Errorf("screen is %dx%d starting with %q", screen.Width, screen.Height, screen.Rows[:2])
+	}
+	recorder := httpRequest(t, httpServer, http.MethodGet, "/screen.png?scale=2", nil, nil)
+	if screenImage, err := png.Decode(recorder.Body); err != nil || screenImage.Bounds().Dx() != 128 || screenImage.Bounds().Dy() != 64 {
+		t.Errorf("/screen.png?scale=2 is not a 128x64 PNG: %v", err)
+	}
+}
+
+func TestHTTPServerMemory(t *testing.T) {
+	_, httpServer := newTestHTTPServer(t)
+	cases := []struct {
+		Target   string
+		Code     int
+		Expected string
+	}{
+		{"/memory?address=0x200&length=4", http.StatusOK, "70011200"},
+		{"/memory?address=0xFFE", http.StatusOK, "0000"},
+		{"/memory?address=0xE00", http.StatusOK, strings.Repeat("00", 256)},
+		{"/memory?address=0x800&length=0x800", http.StatusOK, strings.Repeat("00", 0x800)},
+		{"/memory?address=0x1000", http.StatusOK, ""},
+		{"/memory?address=0x1001", http.StatusBadRequest, ""},
+		{"/memory?address=0x2000", http.StatusBadRequest, ""},
+		{"/memory?address=0xFFF&length=2", http.StatusBadRequest, ""},
+		{"/memory?address=-1", http.StatusBadRequest, ""},
+	}
+	for _, memoryCase := range cases {
+		var memory struct {
+			Data string `json:"data"`
+		}
+		recorder := httpRequest(t, httpServer, http.MethodGet, memoryCase.Target, nil, &memory)
+		if recorder.Code != memoryCase.Code || memory.Data != memoryCase.Expected {
+			t.Errorf("%s answered %d with %q, want %d with %q", memoryCase.Target, recorder.Code, memory.Data, memoryCase.Code, memoryCase.Expected)
+		}
+	}
+}
+
+func TestHTTPServerErrors(t *testing.T) {
+	_, httpServer := newTestHTTPServer(t)
+	cases := []struct {
+		Method string
+		Target string
+		Body   string
+		Code   int
+	}{
+		{http.MethodPost, "/status", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/frames", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/step?count=many", "", http.StatusBadRequest},
+		{http.MethodPost, "/step?count=2000000000", "", http.StatusBadRequest},
+		{http.MethodPost, "/keys/10", "", http.StatusNotFound},
+		{http.MethodGet, "/screen.png?scale=0", "", http.StatusBadRequest},
+		{http.MethodPost, "/rom?name=empty.ch8", "", http.StatusBadRequest},
+		{http.MethodPut, "/state", "{", http.StatusBadRequest},
+	}
+	for _, errorCase := range cases {
+		recorder := httpRequest(t, httpServer, errorCase.Method, errorCase.Target, []byte(errorCase.Body), nil)
+		if recorder.Code != errorCase.Code || !strings.Contains(recorder.Body.String(), `"error"`) {
+			t.Errorf("%s %s answered %d: %s, want %d with an error", errorCase.Method, errorCase.Target, recorder.Code, recorder.Body, errorCase.Code)
+		}
+	}
+}
+
+func TestHTTPServerState(t *testing.T) {
+	machine, httpServer := newTestHTTPServer(t)
+	httpRequest(t, httpServer, http.MethodPost, "/step?count=5", nil, nil)
+	machine.Core.SetPixel(10, 10, true)
+	saved := httpRequest(t, httpServer, http.MethodGet, "/state", nil, nil)
+	if saved.Code != http.StatusOK {
+		t.Fatalf("GET /state answered %d: %s", saved.Code, saved.Body)
+	}
+	state := saved.Body.Bytes()
+
+	httpRequest(t, httpServer, http.MethodPost, "/step?count=4", nil, nil)
+	httpRequest(t, httpServer, http.MethodPost, "/rom?name=other.ch8", []byte{0x00, 0xE0}, nil)
+	if recorder := httpRequest(t, httpServer, http.MethodPut, "/state", state, nil); recorder.Code != http.StatusOK {
+		t.Fatalf("PUT /state answered %d: %s", recorder.Code, recorder.Body)
+	}
+	var registers httpRegisters
+	httpRequest(t, httpServer, http.MethodGet, "/registers", nil, &registers)
+	if registers.V[0] != 3 || registers.PC != 0x202 || !machine.Core.GetPixel(10, 10) {
+		t.Errorf("restored V0 %d and PC %04X, want 3 and 0202 with the screen", registers.V[0], registers.PC)
+	}
+	var memory struct {
+		Data string `json:"data"`
+	}
+	httpRequest(t, httpServer, http.MethodGet, "/memory?address=0x200&length=4", nil, &memory)
+	if memory.Data != "70011200" {
+		t.Errorf("restored program is %s, want 70011200", memory.Data)
+	}
+	if again := httpRequest(t, httpServer, http.MethodGet, "/state", nil, nil); !bytes.Equal(again.Body.Bytes(), state) {
+		t.Error("saving the restored state gives another state")
+	}
+}
+
+func TestHTTPServerFrames(t *testing.T) {
+	machine, httpServer := newTestHTTPServer(t)
+	machine.Pause()
+	answered := make(chan *httptest.ResponseRecorder)
+	go func() {
+		recorder := httptest.NewRecorder()
+		httpServer.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/frames?count=2", nil))
+		answered <- recorder
+	}()
+	// The frames are run here, as the loop driving the machine would.
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case recorder := <-answered:
+			var registers httpRegisters
+			if err := json.Unmarshal(recorder.Body.Bytes(), &registers); err != nil || recorder.Code != http.StatusOK {
+				t.Fatalf("/frames answered %d: %s", recorder.Code, recorder.Body)
+			}
+			// Every other instruction increments V0.
+			if expected := byte(machine.CyclesPerFrame); registers.V[0] != expected || !machine.Paused() {
+				t.Errorf("2 frames left V0 at %d and paused %t, want %d and paused", registers.V[0], machine.Paused(), expected)
+			}
+			return
+		case <-deadline:
+			t.Fatal("/frames did not answer")
+		case <-time.After(time.Millisecond):
+			machine.RunFrame()
+		}
+	}
+}
+
+// TestHTTPServerManualClock checks that /frames lets its frames through a manual clock, which otherwise holds
+// the machine still.
+func TestHTTPServerManualClock(t *testing.T) {
+	machine, httpServer := newTestHTTPServer(t)
+	machine.Pause()
+	stopped := make(chan struct{})
+	go func() {
+		machine.Run()
+		close(stopped)
+	}()
+	var registers httpRegisters
+	if recorder := httpRequest(t, httpServer, http.MethodPost, "/frames?count=2", nil, &registers); recorder.Code != http.StatusOK {
+		t.Fatalf("/frames answered %d: %s", recorder.Code, recorder.Body)
+	}
+	if expected := byte(machine.CyclesPerFrame); registers.V[0] != expected {
+		t.Errorf("2 frames left V0 at %d, want %d", registers.V[0], expected)
+	}
+	machine.Lock()
+	machine.Stop()
+	machine.Unlock()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return once stopped")
+	}
+}
@@ -1,89 +1,307 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/veandco/go-sdl2/sdl"
+	"github.com/nebul/chip8-go/chip8"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "test" {
+		testCommand(os.Args[2:])
+		return
+	}
+
+	config := NewFrontendConfig()
+	frontendName := flag.String("frontend", defaultFrontendName(), "frontend to use: "+strings.Join(FrontendNames(), ", "))
+	flag.IntVar(&config.Scale, "scale", config.Scale, "size in host pixels of a CHIP-8 pixel")
+	flag.StringVar(&config.OutputDir, "output", "frames", "directory where the images frontend writes its frames")
+	flag.BoolVar(&config.ShowPanel, "panel", false, "show the registers next to the screen in the terminal frontend")
+	flag.Float64Var(&config.FastForward, "fast-forward", config.FastForward, "speed while fast-forwarding, 0 for as fast as possible")
+	romDatabasePath := flag.String("romdb", chip8.DefaultROMDatabasePath(), "user ROM database overriding the bundled one")
+	platformName := flag.String("platform", "", "platform to run the ROM on, guessed from the file and the ROM database when empty")
+	gdbAddress := flag.String("gdb", "", "wait for a GDB remote debugger on this TCP address, such as :1234")
+	scriptPath := flag.String("script", "", "Lua script to run alongside the ROM")
+	httpAddress := flag.String("http", "", "serve the HTTP/JSON control API on this TCP address, such as :8080")
+	cheatDirectory := flag.String("cheats", DefaultCheatDirectory(), "directory of the per-ROM cheat lists")
+	profilePath := flag.String("profile", "", "profile the ROM, printing a report on exit and writing a pprof profile to this file")
+	symbolPath := flag.String("symbols", "", "symbol file naming the addresses of the ROM in profiles, one ADDRESS NAME per line")
+	coveragePath := flag.String("coverage", "", "record coverage, merged into this JSON file, with a listing and an HTML map written next to it")
+	clockName := flag.String("clock", "realtime", "clock pacing the frames: "+strings.Join(chip8.ClockNames, ", ")+" (fast runs them as fast as possible, manual only when POST /frames asks)")
+	frameLimit := flag.Int("frames", 0, "stop after this many frames, 0 to run until the frontend quits")
+	dapAddress := flag.String("dap", "", "serve the Debug Adapter Protocol on stdio, or on this TCP address such as :4711")
+	vipMemory := flag.Bool("vip-memory", false, "keep the registers, the stack and the display in memory at the COSMAC VIP addresses")
+	vipTiming := flag.Bool("vip-timing", false, "time the instructions as the COSMAC VIP did, instead of running the same number every frame")
+	haltOnLoop := flag.Bool("halt-on-loop", false, "halt once the program is caught in an endless loop, always on with the null and images frontends")
+	flag.Parse()
+
+	// Headless runs tell how the program ended through the exit status. Deferred first, the exit comes after
+	// the other deferred calls have written their reports.
+	headless := *frontendName == "null" || *frontendName == "images"
+
+	// With -dap stdio the protocol owns stdin and stdout: messages go to stderr instead, and the terminal
+	// frontend, which draws on stdout and reads the keys from stdin, gives way to the null one.
+	dapInput, dapOutput := os.Stdin, os.Stdout
+	if *dapAddress == "stdio" {
+		os.Stdout = os.Stderr
+		if *frontendName == "terminal" {
+			fmt.Fprintln(os.Stderr, "The terminal frontend cannot share stdio with the debug adapter, running without a display")
+			*frontendName = "null"
+		}
+	}
+	exitStatus := 0
+	defer func() {
+		if exitStatus != 0 {
+			os.Exit(exitStatus)
+		}
+	}()
 
-	if err := sdl.Init(sdl.INIT_EVERYTHING); err != nil {
-		panic(err)
+	romPath := "roms/PONG"
+	if flag.NArg() > 0 {
+		romPath = flag.Arg(0)
 	}
-	defer sdl.Quit()
 
-	window, err := sdl.CreateWindow("CHIP-8", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, 640, 320, sdl.WINDOW_SHOWN)
+	romImage, err := chip8.ReadROMFile(romPath)
 	if err != nil {
-		panic(err)
+		fmt.Println("Error reading file:", err)
+		os.Exit(1)
+	}
+	if *platformName != "" {
+		if romImage.Platform, err = chip8.LookupPlatform(*platformName); err != nil {
+			fmt.Println("Error selecting platform:", err)
+			os.Exit(1)
+		}
 	}
-	defer window.Destroy()
 
-	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
+	romDatabase, err := chip8.LoadROMDatabase(*romDatabasePath)
 	if err != nil {
-		panic(err)
+		fmt.Println("Error reading ROM database:", err)
+		os.Exit(1)
 	}
-	defer renderer.Destroy()
 
-	chip8Core := NewChip8Core()
-	opcodeDecoder := NewOpcodeDecoder()
-	clock := NewFixedClock()
+	clock, err := chip8.NewClock(*clockName)
+	if err != nil {
+		fmt.Println("Error selecting clock:", err)
+		os.Exit(1)
+	}
+	machine := chip8.NewMachine(clock)
+	machine.ROMDatabase = romDatabase
+	machine.HaltOnLoop = *haltOnLoop || headless
+	machine.Defaults.VIPTiming = *vipTiming
+	machine.Defaults.VIPMemory = *vipMemory
+	if err := machine.LoadROMImage(romImage); err != nil {
+		fmt.Println("Error loading ROM:", err)
+		os.Exit(1)
+	}
+	if machine.ROMInfo != nil {
+		if err := config.ApplyROMInfo(*machine.ROMInfo); err != nil {
+			fmt.Println("Error applying ROM settings:", err)
+			os.Exit(1)
+		}
+	}
 
-	// TODO - Improvement Load ROM another way
-	data, err := os.ReadFile("roms/PONG")
+	cheatEngine, err := NewCheatEngine(machine, *cheatDirectory)
 	if err != nil {
-		fmt.Println("Error reading file:", err)
-		return
+		fmt.Println("Error loading cheats:", err)
+		os.Exit(1)
 	}
 
-	chip8Core.LoadROM(data)
-	chip8Core.Start()
-	defer chip8Core.Stop()
-
-	clock.Start()
-	defer clock.Stop()
-
-	running := true
-	for running {
-		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-			switch e := event.(type) {
-			case *sdl.QuitEvent:
-				running = false
-			case *sdl.KeyboardEvent:
-				keyMap := map[sdl.Keycode]uint8{
-					sdl.K_1: 0x1, sdl.K_2: 0x2, sdl.K_3: 0x3, sdl.K_4: 0xC,
-					sdl.K_q: 0x4, sdl.K_w: 0x5, sdl.K_e: 0x6, sdl.K_r: 0xD,
-					sdl.K_a: 0x7, sdl.K_s: 0x8, sdl.K_d: 0x9, sdl.K_f: 0xE,
-					sdl.K_z: 0xA, sdl.K_x: 0x0, sdl.K_c: 0xB, sdl.K_v: 0xF,
-				}
-				if keyIndex, exists := keyMap[e.Keysym.Sym]; exists {
-					chip8Core.SetKey(keyIndex, e.Type == sdl.KEYDOWN)
-				}
+	if *frameLimit > 0 {
+		framesLeft := *frameLimit
+		machine.OnFrame(func(core *chip8.Chip8Core) {
+			if framesLeft--; framesLeft == 0 {
+				machine.Stop()
+			}
+		})
+	}
+
+	if *profilePath != "" {
+		profiler := NewProfiler(machine)
+		if *symbolPath != "" {
+			labels, err := chip8.ReadSymbolFile(*symbolPath)
+			if err != nil {
+				fmt.Println("Error reading symbols:", err)
+				os.Exit(1)
+			}
+			for label, address := range labels {
+				profiler.Labels[label] = address
 			}
 		}
-		<-clock.Tick()
-		opcode := chip8Core.FetchOpcode()
-		instruction := opcodeDecoder.Decode(opcode)
-		instruction.Execute(chip8Core)
-		chip8Core.UpdateTimers()
-
-		renderer.SetDrawColor(0, 0, 0, 255)
-		renderer.Clear()
-
-		pixelSize := int32(10)
-
-		for positionY := 0; positionY < 32; positionY++ {
-			for positionX := 0; positionX < 64; positionX++ {
-				color := sdl.Color{R: 0, G: 0, B: 0, A: 255}
-				if chip8Core.GetPixel(uint8(positionX), uint8(positionY)) {
-					color = sdl.Color{R: 255, G: 255, B: 255, A: 255}
+		// Deferred before the frontend is created, so the report is printed once the frontend is closed.
+		defer writeProfile(profiler, *profilePath)
+	}
+
+	if *coveragePath != "" {
+		coverage := NewCoverage(machine)
+		if err := coverage.MergeFile(*coveragePath); err != nil {
+			fmt.Println("Error reading coverage:", err)
+			os.Exit(1)
+		}
+		defer writeCoverage(coverage, *coveragePath)
+	}
+
+	if *scriptPath != "" {
+		script, err := LoadScript(machine, *scriptPath)
+		if err != nil {
+			fmt.Println("Error loading script:", err)
+			os.Exit(1)
+		}
+		defer script.Close()
+	}
+
+	if *gdbAddress != "" {
+		gdbStub, err := ListenGDB(*gdbAddress, machine)
+		if err != nil {
+			fmt.Println("Error starting GDB stub:", err)
+			os.Exit(1)
+		}
+		defer gdbStub.Close()
+		// The program waits for the debugger to continue it.
+		machine.Pause()
+		fmt.Println("Waiting for GDB on", gdbStub.Addr())
+		go gdbStub.Serve()
+	}
+
+	if *dapAddress != "" {
+		dapServer := NewDAPServer(machine)
+		// The program waits for the client to launch or attach.
+		machine.Pause()
+		if *dapAddress == "stdio" {
+			go func() {
+				if err := dapServer.Serve(dapInput, dapOutput); err != nil {
+					fmt.Fprintln(os.Stderr, "Error serving DAP:", err)
 				}
-				renderer.SetDrawColor(color.R, color.G, color.B, color.A)
-				rectangle := sdl.Rect{X: int32(positionX) * pixelSize, Y: int32(positionY) * pixelSize, W: pixelSize, H: pixelSize}
-				renderer.FillRect(&rectangle)
+				machine.Lock()
+				machine.Stop()
+				machine.Unlock()
+			}()
+		} else {
+			go func() {
+				if err := dapServer.ListenAndServe(*dapAddress); err != nil {
+					fmt.Println("Error serving DAP:", err)
+					os.Exit(1)
+				}
+			}()
+		}
+	}
+
+	if *httpAddress != "" {
+		httpServer := NewHTTPServer(machine)
+		httpServer.AttachCheats(cheatEngine)
+		go func() {
+			if err := httpServer.ListenAndServe(*httpAddress); err != nil {
+				fmt.Println("Error serving HTTP:", err)
+				os.Exit(1)
 			}
+		}()
+	}
+
+	frontend, err := NewFrontend(*frontendName, config)
+	if err != nil {
+		fmt.Println("Error creating frontend:", err)
+		os.Exit(1)
+	}
+	defer frontend.Close()
+	frontend.Attach(machine)
+	machine.Run()
+
+	if headless {
+		switch {
+		case machine.Halted():
+			fmt.Fprintln(os.Stderr, "Halted:", machine.HaltReason())
+		case *frameLimit > 0:
+			fmt.Fprintf(os.Stderr, "Still running after %d frames\n", *frameLimit)
+			exitStatus = 2
+		}
+	}
+}
+
+// writeProfile prints the report of the profiler and writes its pprof profile.
+func writeProfile(profiler *Profiler, path string) {
+	profiler.WriteReport(os.Stdout)
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Println("Error writing profile:", err)
+		return
+	}
+	defer file.Close()
+	if err := profiler.WriteProfile(file); err != nil {
+		fmt.Println("Error writing profile:", err)
+	}
+}
+
+// writeCoverage saves the coverage and writes its listing and map, replacing the extension of path with .lst and .html.
+func writeCoverage(coverage *Coverage, path string) {
+	if err := coverage.WriteFile(path); err != nil {
+		fmt.Println("Error writing coverage:", err)
+		return
+	}
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for extension, write := range map[string]func(io.Writer) error{".lst": coverage.WriteListing, ".html": coverage.WriteHTML} {
+		file, err := os.Create(base + extension)
+		if err != nil {
+			fmt.Println("Error writing coverage:", err)
+			continue
+		}
+		if err := write(file); err != nil {
+			fmt.Println("Error writing coverage:", err)
+		}
+		file.Close()
+	}
+}
+
+// testCommand runs the conformance suite of a directory: chip8 test [-junit FILE] [-screens DIR] DIRECTORY.
+// It exits with a failing status when a test fails or cannot run.
+func testCommand(arguments []string) {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	junitPath := flags.String("junit", "", "also write the results to this file as JUnit XML")
+	screenDirectory := flags.String("screens", "", "save the final screen of every test in this directory")
+	profileNames := flags.String("profiles", "", "comma-separated profiles to run, the ones of the suite when empty")
+	flags.Parse(arguments)
+
+	directory := "."
+	if flags.NArg() > 0 {
+		directory = flags.Arg(0)
+	}
+	suite, err := LoadConformanceSuite(directory)
+	if err != nil {
+		fmt.Println("Error reading suite:", err)
+		os.Exit(1)
+	}
+	if *profileNames != "" {
+		suite.Profiles = strings.Split(*profileNames, ",")
+	}
+
+	results := RunConformanceSuite(directory, suite, *screenDirectory)
+	WriteConformanceMatrix(os.Stdout, results)
+	if *junitPath != "" {
+		file, err := os.Create(*junitPath)
+		if err != nil {
+			fmt.Println("Error writing JUnit report:", err)
+			os.Exit(1)
+		}
+		err = WriteJUnitReport(file, results)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Println("Error writing JUnit report:", err)
+			os.Exit(1)
 		}
-		renderer.Present()
 	}
+	if ConformanceFailed(results) {
+		os.Exit(1)
+	}
+}
+
+func defaultFrontendName() string {
+	if _, exists := frontendFactories["sdl"]; exists {
+		return "sdl"
+	}
+	return "terminal"
 }
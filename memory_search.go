@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// MemoryComparison tells how a byte must compare to keep its address among the candidates of a MemorySearch.
+type MemoryComparison int
+
+const (
+	CompareEqual     MemoryComparison = iota // CompareEqual keeps the bytes equal to a value.
+	CompareChanged                           // CompareChanged keeps the bytes that changed since the last snapshot.
+	CompareUnchanged                         // CompareUnchanged keeps the bytes that did not change since the last snapshot.
+	CompareIncreased                         // CompareIncreased keeps the bytes that grew since the last snapshot.
+	CompareDecreased                         // CompareDecreased keeps the bytes that shrank since the last snapshot.
+)
+
+var memoryComparisonNames = map[string]MemoryComparison{
+	"eq":        CompareEqual,
+	"changed":   CompareChanged,
+	"unchanged": CompareUnchanged,
+	"inc":       CompareIncreased,
+	"dec":       CompareDecreased,
+}
+
+// ParseMemoryComparison reads the name of a comparison: eq, changed, unchanged, inc or dec.
+func ParseMemoryComparison(name string) (MemoryComparison, error) {
+	comparison, exists := memoryComparisonNames[strings.ToLower(name)]
+	if !exists {
+		return 0, fmt.Errorf("unknown comparison %q, expected eq, changed, unchanged, inc or dec", name)
+	}
+	return comparison, nil
+}
+
+// MemorySearch finds the bytes of Memory holding a value of interest, such as a score or a number of lives,
+// by narrowing the candidate addresses step by step: play until the value changes, narrow, and again.
+// Every step compares Memory with the snapshot taken by the previous step.
+type MemorySearch struct {
+	snapshot   []byte
+	candidates []uint32 // candidates are only listed from the first step on, MegaChip memories being 32 MiB.
+	narrowed   bool
+}
+
+// NewMemorySearch snapshots Memory, every address being a candidate.
+func NewMemorySearch(core *chip8.Chip8Core) *MemorySearch {
+	return &MemorySearch{snapshot: append([]byte(nil), core.Memory...)}
+}
+
+// Narrow keeps the candidates whose byte compares as asked, value only being used by CompareEqual,
+// then snapshots Memory for the next step.
+func (memorySearch *MemorySearch) Narrow(core *chip8.Chip8Core, comparison MemoryComparison, value byte) {
+	kept := memorySearch.candidates[:0]
+	for index := 0; index < memorySearch.Count(); index++ {
+		address := uint32(index)
+		if memorySearch.narrowed {
+			address = memorySearch.candidates[index]
+		}
+		if int(address) >= len(core.Memory) || int(address) >= len(memorySearch.snapshot) {
+			continue
+		}
+		previous, current := memorySearch.snapshot[address], core.Memory[address]
+		var keep bool
+		switch comparison {
+		case CompareEqual:
+			keep = current == value
+		case CompareChanged:
+			keep = current != previous
+		case CompareUnchanged:
+			keep = current == previous
+		case CompareIncreased:
+			keep = current > previous
+		case CompareDecreased:
+			keep = current < previous
+		}
+		if keep {
+			kept = append(kept, address)
+		}
+	}
+	memorySearch.candidates, memorySearch.narrowed = kept, true
+	memorySearch.snapshot = append(memorySearch.snapshot[:0], core.Memory...)
+}
+
+// Count returns the number of addresses still matching every step.
+func (memorySearch *MemorySearch) Count() int {
+	if !memorySearch.narrowed {
+		return len(memorySearch.snapshot)
+	}
+	return len(memorySearch.candidates)
+}
+
+// Candidates returns the first limit addresses still matching every step, in increasing order.
+func (memorySearch *MemorySearch) Candidates(limit int) []uint32 {
+	if memorySearch.narrowed {
+		return memorySearch.candidates[:min(limit, len(memorySearch.candidates))]
+	}
+	candidates := make([]uint32, min(limit, len(memorySearch.snapshot)))
+	for index := range candidates {
+		candidates[index] = uint32(index)
+	}
+	return candidates
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// newSearchedCore returns a core whose memory is blank but for 05 at 0300 and 0301 and 09 at 0302, and a
+// search started on it.
+func newSearchedCore() (*chip8.Chip8Core, *MemorySearch) {
+	core := chip8.NewChip8Core()
+	core.Memory = make([]byte, 0x1000)
+	core.Memory[0x300], core.Memory[0x301], core.Memory[0x302] = 5, 5, 9
+	return core, NewMemorySearch(core)
+}
+
+func TestMemorySearchFirstStep(t *testing.T) {
+	cases := []struct {
+		Comparison MemoryComparison
+		Value      byte
+		Expected   []uint32
+		Count      int
+	}{
+		{CompareEqual, 6, []uint32{0x300}, 1},
+		{CompareEqual, 0, []uint32{0, 1, 2}, 0x1000 - 3},
+		{CompareChanged, 0, []uint32{0x300, 0x301}, 2},
+		{CompareUnchanged, 0, []uint32{0, 1, 2}, 0x1000 - 2},
+		{CompareIncreased, 0, []uint32{0x300}, 1},
+		{CompareDecreased, 0, []uint32{0x301}, 1},
+	}
+	for _, searchCase := range cases {
+		core, memorySearch := newSearchedCore()
+		core.Memory[0x300], core.Memory[0x301] = 6, 4
+		memorySearch.Narrow(core, searchCase.Comparison, searchCase.Value)
+		candidates := memorySearch.Candidates(3)
+		if memorySearch.Count() != searchCase.Count || !slices.Equal(candidates, searchCase.Expected) {
+			t.Errorf("comparison %d with %d kept %d candidates starting with %X, want %d starting with %X", searchCase.Comparison,
+				searchCase.Value, memorySearch.Count(), candidates, searchCase.Count, searchCase.Expected)
+		}
+	}
+}
+
+func TestMemorySearchNarrowing(t *testing.T) {
+	core, memorySearch := newSearchedCore()
+	if memorySearch.Count() != 0x1000 || !slices.Equal(memorySearch.Candidates(2), []uint32{0, 1}) {
+		t.Fatalf("a new search has %d candidates starting with %X, want all of memory", memorySearch.Count(), memorySearch.Candidates(2))
+	}
+
+	core.Memory[0x300], core.Memory[0x301], core.Memory[0x305] = 6, 6, 1
+	memorySearch.Narrow(core, CompareIncreased, 0)
+	if candidates := memorySearch.Candidates(10); !slices.Equal(candidates, []uint32{0x300, 0x301, 0x305}) {
+		t.Fatalf("the bytes that grew are at %X, want 300, 301 and 305", candidates)
+	}
+	// Each step compares with the memory of the step before, and only among the candidates left.
+	core.Memory[0x300], core.Memory[0x302] = 7, 10
+	memorySearch.Narrow(core, CompareUnchanged, 0)
+	if candidates := memorySearch.Candidates(10); !slices.Equal(candidates, []uint32{0x301, 0x305}) {
+		t.Fatalf("the unchanged candidates are at %X, want 301 and 305", candidates)
+	}
+	memorySearch.Narrow(core, CompareEqual, 6)
+	if candidates := memorySearch.Candidates(10); !slices.Equal(candidates, []uint32{0x301}) || memorySearch.Count() != 1 {
+		t.Errorf("the candidates holding 6 are at %X, want 301", candidates)
+	}
+	memorySearch.Narrow(core, CompareChanged, 0)
+	if memorySearch.Count() != 0 || len(memorySearch.Candidates(10)) != 0 {
+		t.Errorf("%d candidates changed, want none", memorySearch.Count())
+	}
+}
+
+func TestParseMemoryComparison(t *testing.T) {
+	for name, expected := range memoryComparisonNames {
+		if comparison, err := ParseMemoryComparison(name); err != nil || comparison != expected {
+			t.Errorf("%s parsed as %d with error %v, want %d", name, comparison, err, expected)
+		}
+	}
+	if _, err := ParseMemoryComparison("more"); err == nil {
+		t.Error("the comparison more was accepted")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// MemoryViewColumns is the number of bytes shown per row of a MemoryView.
+const MemoryViewColumns = 16
+
+// defaultSpriteRows is the height of the sprite preview when the instruction at PC does not draw a sprite.
+const defaultSpriteRows = 8
+
+// MemoryHighlight tells why a byte of Memory stands out in a MemoryView.
+type MemoryHighlight int
+
+const (
+	HighlightNone   MemoryHighlight = iota
+	HighlightPC                     // HighlightPC marks the instruction at PC.
+	HighlightI                      // HighlightI marks the byte at I.
+	HighlightSprite                 // HighlightSprite marks the rest of the sprite data at I.
+)
+
+// MemoryView is the state of a hex/ASCII view of Memory shared by the debugger frontends: which rows are shown,
+// the byte under the cursor and the edit in progress. In follow-I mode the view keeps the byte at I in sight.
+type MemoryView struct {
+	Address uint32 // Address is the first address shown, a multiple of MemoryViewColumns.
+	Cursor  uint32 // Cursor is the address of the selected byte.
+	Rows    int    // Rows is the number of rows shown.
+	FollowI bool   // FollowI moves the cursor to I at every update.
+
+	lowNibble bool
+}
+
+func NewMemoryView(rows int) *MemoryView {
+	return &MemoryView{Address: 0x200, Cursor: 0x200, Rows: rows}
+}
+
+// Update follows I when asked to and scrolls so the cursor stays visible.
+func (memoryView *MemoryView) Update(core *chip8.Chip8Core) {
+	if memoryView.FollowI {
+		memoryView.Cursor = core.I
+		memoryView.lowNibble = false
+	}
+	if int(memoryView.Cursor) >= len(core.Memory) {
+		memoryView.Cursor = uint32(len(core.Memory) - 1)
+	}
+	rowStart := memoryView.Cursor - memoryView.Cursor%MemoryViewColumns
+	switch {
+	case rowStart < memoryView.Address:
+		memoryView.Address = rowStart
+	case int(rowStart) >= int(memoryView.Address)+memoryView.Rows*MemoryViewColumns:
+		memoryView.Address = rowStart - uint32((memoryView.Rows-1)*MemoryViewColumns)
+	}
+}
+
+// MoveCursor moves the cursor by delta bytes, staying in Memory; it cancels a half-typed byte.
+func (memoryView *MemoryView) MoveCursor(core *chip8.Chip8Core, delta int) {
+	cursor := int(memoryView.Cursor) + delta
+	cursor = max(0, min(cursor, len(core.Memory)-1))
+	memoryView.Cursor = uint32(cursor)
+	memoryView.lowNibble = false
+	memoryView.FollowI = false
+	memoryView.Update(core)
+}
+
+// EditNibble types a hex digit at the cursor: the first digit replaces the high nibble of the byte,
+// the second one the low nibble, after which the cursor moves to the next byte.
+func (memoryView *MemoryView) EditNibble(core *chip8.Chip8Core, digit byte) {
+	address := memoryView.Cursor
+	if memoryView.lowNibble {
+		core.Memory[address] = core.Memory[address]&0xF0 | digit&0x0F
+		memoryView.MoveCursor(core, 1)
+		return
+	}
+	core.Memory[address] = core.Memory[address]&0x0F | digit<<4
+	memoryView.lowNibble = true
+}
+
+// SpriteRows is the height of the sprite at I: the N of the DXYN at PC, or a default height otherwise.
+func (memoryView *MemoryView) SpriteRows(core *chip8.Chip8Core) int {
+	opcode := core.FetchOpcode()
+	if opcode&0xF000 == 0xD000 && opcode&0x000F != 0 {
+		return int(opcode & 0x000F)
+	}
+	return defaultSpriteRows
+}
+
+// Highlight tells whether the byte at address is part of the instruction at PC or of the sprite at I.
+func (memoryView *MemoryView) Highlight(core *chip8.Chip8Core, address uint32) MemoryHighlight {
+	switch {
+	case address == uint32(core.PC) || address == uint32(core.PC)+1:
+		return HighlightPC
+	case address == core.I:
+		return HighlightI
+	case address > core.I && int(address) < int(core.I)+memoryView.SpriteRows(core):
+		return HighlightSprite
+	}
+	return HighlightNone
+}
+
+// Sprite renders the bytes at I as an 8 pixels wide bitmap, one row per byte.
+func (memoryView *MemoryView) Sprite(core *chip8.Chip8Core) [][]bool {
+	rows := make([][]bool, memoryView.SpriteRows(core))
+	for row := range rows {
+		rows[row] = make([]bool, 8)
+		address := (int(core.I) + row) % len(core.Memory)
+		for column := 0; column < 8; column++ {
+			rows[row][column] = core.Memory[address]&(0x80>>column) != 0
+		}
+	}
+	return rows
+}
+
+// RowAddresses returns the address of the first byte of every shown row still inside Memory.
+func (memoryView *MemoryView) RowAddresses(core *chip8.Chip8Core) []uint32 {
+	var addresses []uint32
+	for row := 0; row < memoryView.Rows; row++ {
+		address := int(memoryView.Address) + row*MemoryViewColumns
+		if address >= len(core.Memory) {
+			break
+		}
+		addresses = append(addresses, uint32(address))
+	}
+	return addresses
+}
+
+// Status describes the cursor, for the title line of the views.
+func (memoryView *MemoryView) Status(core *chip8.Chip8Core) string {
+	status := fmt.Sprintf("MEMORY %s = %02X", FormatMemoryAddress(core, memoryView.Cursor), core.Memory[memoryView.Cursor])
+	if memoryView.FollowI {
+		status += "  FOLLOW I"
+	}
+	return status
+}
+
+// MemoryASCII shows printable characters as themselves and the other bytes as dots.
+func MemoryASCII(value byte) string {
+	if value >= 0x20 && value < 0x7F {
+		return string(rune(value))
+	}
+	return "."
+}
+
+// FormatMemoryAddress writes an address in hex with as many digits as the last address of Memory needs, at
+// least 4, so the rows of the views stay aligned.
+func FormatMemoryAddress(core *chip8.Chip8Core, address uint32) string {
+	digits := max(4, len(fmt.Sprintf("%X", len(core.Memory)-1)))
+	return fmt.Sprintf("%0*X", digits, address)
+}
+
+// FormatMemoryRow renders a row as plain text: the address, the bytes in hex and the bytes as ASCII.
+func FormatMemoryRow(core *chip8.Chip8Core, address uint32) string {
+	var hexPart, asciiPart strings.Builder
+	for column := 0; column < MemoryViewColumns; column++ {
+		current := int(address) + column
+		if current >= len(core.Memory) {
+			hexPart.WriteString("   ")
+			continue
+		}
+		fmt.Fprintf(&hexPart, " %02X", core.Memory[current])
+		asciiPart.WriteString(MemoryASCII(core.Memory[current]))
+	}
+	return fmt.Sprintf("%s %s  %s", FormatMemoryAddress(core, address), hexPart.String(), asciiPart.String())
+}
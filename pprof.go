@@ -0,0 +1,141 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+)
+
+// pprofBuilder writes profiles in the gzipped protocol buffer format of pprof (profile.proto), limited to what
+// the profiler needs: one value per sample, locations keyed by address and function, and functions keyed by name.
+type pprofBuilder struct {
+	profile   []byte
+	strings   map[string]int64
+	locations map[pprofLocationKey]uint64
+	functions map[string]uint64
+}
+
+type pprofLocationKey struct {
+	address  uint16
+	function string
+	line     int
+}
+
+// The field numbers of profile.proto.
+const (
+	pprofProfileSampleType  = 1
+	pprofProfileSample      = 2
+	pprofProfileLocation    = 4
+	pprofProfileFunction    = 5
+	pprofProfileStringTable = 6
+	pprofValueTypeType      = 1
+	pprofValueTypeUnit      = 2
+	pprofSampleLocationID   = 1
+	pprofSampleValue        = 2
+	pprofLocationID         = 1
+	pprofLocationAddress    = 3
+	pprofLocationLine       = 4
+	pprofLineFunctionID     = 1
+	pprofLineLine           = 2
+	pprofFunctionID         = 1
+	pprofFunctionName       = 2
+	pprofFunctionSystemName = 3
+)
+
+func newPprofBuilder(sampleType string, unit string) *pprofBuilder {
+	pprof := &pprofBuilder{
+		strings:   map[string]int64{},
+		locations: map[pprofLocationKey]uint64{},
+		functions: map[string]uint64{},
+	}
+	// The string table starts with the empty string.
+	pprof.stringIndex("")
+	var valueType []byte
+	valueType = protoVarint(valueType, pprofValueTypeType, uint64(pprof.stringIndex(sampleType)))
+	valueType = protoVarint(valueType, pprofValueTypeUnit, uint64(pprof.stringIndex(unit)))
+	pprof.profile = protoBytes(pprof.profile, pprofProfileSampleType, valueType)
+	return pprof
+}
+
+func (pprof *pprofBuilder) stringIndex(text string) int64 {
+	index, exists := pprof.strings[text]
+	if !exists {
+		index = int64(len(pprof.strings))
+		pprof.strings[text] = index
+	}
+	return index
+}
+
+func (pprof *pprofBuilder) function(name string) uint64 {
+	id, exists := pprof.functions[name]
+	if !exists {
+		id = uint64(len(pprof.functions) + 1)
+		pprof.functions[name] = id
+		var function []byte
+		function = protoVarint(function, pprofFunctionID, id)
+		function = protoVarint(function, pprofFunctionName, uint64(pprof.stringIndex(name)))
+		function = protoVarint(function, pprofFunctionSystemName, uint64(pprof.stringIndex(name)))
+		pprof.profile = protoBytes(pprof.profile, pprofProfileFunction, function)
+	}
+	return id
+}
+
+// location returns the id of the location of an address in a function, at a line of the source or 0 when unknown.
+func (pprof *pprofBuilder) location(address uint16, function string, line int) uint64 {
+	key := pprofLocationKey{address: address, function: function, line: line}
+	id, exists := pprof.locations[key]
+	if !exists {
+		id = uint64(len(pprof.locations) + 1)
+		pprof.locations[key] = id
+		var lineMessage []byte
+		lineMessage = protoVarint(lineMessage, pprofLineFunctionID, pprof.function(function))
+		lineMessage = protoVarint(lineMessage, pprofLineLine, uint64(line))
+		var location []byte
+		location = protoVarint(location, pprofLocationID, id)
+		location = protoVarint(location, pprofLocationAddress, uint64(address))
+		location = protoBytes(location, pprofLocationLine, lineMessage)
+		pprof.profile = protoBytes(pprof.profile, pprofProfileLocation, location)
+	}
+	return id
+}
+
+// sample adds a sample whose locations go from the innermost to the outermost.
+func (pprof *pprofBuilder) sample(locations []uint64, value int64) {
+	var ids []byte
+	for _, id := range locations {
+		ids = binary.AppendUvarint(ids, id)
+	}
+	var sample []byte
+	sample = protoBytes(sample, pprofSampleLocationID, ids)
+	sample = protoBytes(sample, pprofSampleValue, binary.AppendUvarint(nil, uint64(value)))
+	pprof.profile = protoBytes(pprof.profile, pprofProfileSample, sample)
+}
+
+func (pprof *pprofBuilder) write(writer io.Writer) error {
+	table := make([]string, len(pprof.strings))
+	for text, index := range pprof.strings {
+		table[index] = text
+	}
+	profile := pprof.profile
+	for _, text := range table {
+		profile = protoBytes(profile, pprofProfileStringTable, []byte(text))
+	}
+	compressor := gzip.NewWriter(writer)
+	if _, err := compressor.Write(profile); err != nil {
+		return err
+	}
+	return compressor.Close()
+}
+
+// protoVarint appends a varint field to a protocol buffer message.
+func protoVarint(message []byte, field int, value uint64) []byte {
+	message = binary.AppendUvarint(message, uint64(field)<<3)
+	return binary.AppendUvarint(message, value)
+}
+
+// protoBytes appends a length-delimited field (a string, a message or packed numbers) to a protocol buffer message.
+func protoBytes(message []byte, field int, value []byte) []byte {
+	message = binary.AppendUvarint(message, uint64(field)<<3|2)
+	message = binary.AppendUvarint(message, uint64(len(value)))
+	return append(message, value...)
+}
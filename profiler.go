@@ -0,0 +1,221 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// ProfileReportAddresses is the number of addresses listed by Profiler.WriteReport, the hottest first.
+const ProfileReportAddresses = 40
+
+// SubroutineProfile tells how many cycles a subroutine took.
+type SubroutineProfile struct {
+	Address uint16 // Address is the entry of the subroutine; the program itself counts as the subroutine at its load address.
+	Calls   uint64 // Calls is the number of times it was called.
+	Self    uint64 // Self is the number of cycles spent in the subroutine itself.
+	Total   uint64 // Total is the number of cycles spent in the subroutine and the ones it called.
+}
+
+// Profiler counts where a program spends its cycles: the executions of every address, the cycles of every
+// subroutine and the cycles spent waiting for a key in FX0A. Subroutines are found through the stack of
+// the core, whose entries are the addresses of the calls, so jumping out of a subroutine or resetting the
+// machine does not confuse it.
+type Profiler struct {
+	Labels map[string]uint16 // Labels name the addresses in the report and the profile; they come from the program when it was assembled from source.
+
+	machine     *chip8.Machine
+	cycles      uint64
+	waitCycles  uint64
+	counts      map[uint16]uint64
+	subroutines map[uint16]*SubroutineProfile
+	samples     map[string]*profilerSample
+}
+
+// profilerSample counts the cycles of a call stack, from the executed address to the outermost call.
+type profilerSample struct {
+	stack  []uint16
+	cycles uint64
+}
+
+// NewProfiler starts profiling the machine from its next instruction.
+func NewProfiler(machine *chip8.Machine) *Profiler {
+	profiler := &Profiler{
+		Labels:      map[string]uint16{},
+		machine:     machine,
+		counts:      map[uint16]uint64{},
+		subroutines: map[uint16]*SubroutineProfile{},
+		samples:     map[string]*profilerSample{},
+	}
+	if machine.Program != nil {
+		for label, address := range machine.Program.Labels {
+			profiler.Labels[label] = address
+		}
+	}
+	machine.OnStep(profiler.step)
+	return profiler
+}
+
+func (profiler *Profiler) step(address uint16) {
+	core := profiler.machine.Core
+	opcode := profiler.opcode(address)
+	profiler.cycles++
+	profiler.counts[address]++
+
+	stack := core.Stack[:min(int(core.SP), len(core.Stack))]
+	entries := make([]uint16, 0, len(stack)+1)
+	entries = append(entries, profiler.machine.Platform.LoadAddress)
+	for _, callSite := range stack {
+		entries = append(entries, profiler.opcode(callSite)&0x0FFF)
+	}
+	profiler.subroutine(entries[len(entries)-1]).Self++
+	for index, entry := range entries {
+		if !slices.Contains(entries[:index], entry) {
+			profiler.subroutine(entry).Total++
+		}
+	}
+	if opcode&0xF000 == 0x2000 {
+		profiler.subroutine(opcode&0x0FFF).Calls++
+	}
+	if opcode&0xF0FF == 0xF00A && !slices.Contains(core.Keys[:], true) {
+		profiler.waitCycles++
+	}
+
+	key := make([]byte, 0, 2*(len(stack)+1))
+	key = append(key, byte(address>>8), byte(address))
+	for index := len(stack) - 1; index >= 0; index-- {
+		key = append(key, byte(stack[index]>>8), byte(stack[index]))
+	}
+	sample, exists := profiler.samples[string(key)]
+	if !exists {
+		sample = &profilerSample{stack: []uint16{address}}
+		for index := len(stack) - 1; index >= 0; index-- {
+			sample.stack = append(sample.stack, stack[index])
+		}
+		profiler.samples[string(key)] = sample
+	}
+	sample.cycles++
+}
+
+// opcode reads the opcode at an address, 0 outside of Memory.
+func (profiler *Profiler) opcode(address uint16) uint16 {
+	memory := profiler.machine.Core.Memory
+	if int(address)+1 >= len(memory) {
+		return 0
+	}
+	return uint16(memory[address])<<8 | uint16(memory[address+1])
+}
+
+func (profiler *Profiler) subroutine(address uint16) *SubroutineProfile {
+	subroutine, exists := profiler.subroutines[address]
+	if !exists {
+		subroutine = &SubroutineProfile{Address: address}
+		profiler.subroutines[address] = subroutine
+	}
+	return subroutine
+}
+
+// Cycles returns the number of instructions executed since the profiler started.
+func (profiler *Profiler) Cycles() uint64 {
+	return profiler.cycles
+}
+
+// Count returns the number of times the instruction at an address was executed.
+func (profiler *Profiler) Count(address uint16) uint64 {
+	return profiler.counts[address]
+}
+
+// WaitCycles returns the number of cycles spent in FX0A with no key pressed.
+func (profiler *Profiler) WaitCycles() uint64 {
+	return profiler.waitCycles
+}
+
+// Subroutines returns the profiles of the subroutines that ran, the most expensive first.
+func (profiler *Profiler) Subroutines() []SubroutineProfile {
+	var subroutines []SubroutineProfile
+	for _, subroutine := range profiler.subroutines {
+		subroutines = append(subroutines, *subroutine)
+	}
+	slices.SortFunc(subroutines, func(first, second SubroutineProfile) int {
+		if first.Total != second.Total {
+			return cmp.Compare(second.Total, first.Total)
+		}
+		return int(first.Address) - int(second.Address)
+	})
+	return subroutines
+}
+
+// symbol names an address after the labels, as "0246 draw+4", or just gives the address without labels.
+func (profiler *Profiler) symbol(address uint16) string {
+	if name := chip8.LabelSymbol(profiler.Labels, address); name != "" {
+		return fmt.Sprintf("%04X %s", address, name)
+	}
+	return fmt.Sprintf("%04X", address)
+}
+
+// functionName names a subroutine in the profile, after its label or as sub_0246 without one.
+func (profiler *Profiler) functionName(address uint16) string {
+	if name := chip8.LabelSymbol(profiler.Labels, address); name != "" {
+		return name
+	}
+	return fmt.Sprintf("sub_%04X", address)
+}
+
+// WriteReport writes the hottest addresses with their instructions, the subroutines and the time spent waiting for keys.
+func (profiler *Profiler) WriteReport(writer io.Writer) error {
+	percent := func(count uint64) float64 {
+		return 100 * float64(count) / float64(max(profiler.cycles, 1))
+	}
+	seconds := float64(profiler.cycles) / float64(60*max(profiler.machine.CyclesPerFrame, 1))
+	fmt.Fprintf(writer, "%d cycles, %.1f s at %d cycles per frame\n", profiler.cycles, seconds, profiler.machine.CyclesPerFrame)
+	fmt.Fprintf(writer, "%d cycles (%.1f%%) waiting for a key in FX0A\n", profiler.waitCycles, percent(profiler.waitCycles))
+
+	var addresses []uint16
+	for address := range profiler.counts {
+		addresses = append(addresses, address)
+	}
+	slices.SortFunc(addresses, func(first, second uint16) int {
+		if profiler.counts[first] != profiler.counts[second] {
+			return cmp.Compare(profiler.counts[second], profiler.counts[first])
+		}
+		return int(first) - int(second)
+	})
+	fmt.Fprintf(writer, "\n%10s %6s  %-16s %s\n", "CYCLES", "%", "INSTRUCTION", "ADDRESS")
+	for _, address := range addresses[:min(len(addresses), ProfileReportAddresses)] {
+		instruction := profiler.machine.Decoder.Decode(profiler.opcode(address))
+		fmt.Fprintf(writer, "%10d %5.1f%%  %-16s %s\n", profiler.counts[address], percent(profiler.counts[address]), instruction, profiler.symbol(address))
+	}
+
+	fmt.Fprintf(writer, "\n%8s %10s %6s %10s %6s  %s\n", "CALLS", "SELF", "%", "TOTAL", "%", "SUBROUTINE")
+	for _, subroutine := range profiler.Subroutines() {
+		fmt.Fprintf(writer, "%8d %10d %5.1f%% %10d %5.1f%%  %s\n", subroutine.Calls, subroutine.Self, percent(subroutine.Self),
+			subroutine.Total, percent(subroutine.Total), profiler.symbol(subroutine.Address))
+	}
+	return nil
+}
+
+// WriteProfile writes the cycles in the pprof format, one location per address and one function per subroutine,
+// for go tool pprof and the other pprof viewers.
+func (profiler *Profiler) WriteProfile(writer io.Writer) error {
+	pprof := newPprofBuilder("cycles", "count")
+	for _, sample := range profiler.samples {
+		locations := make([]uint64, 0, len(sample.stack))
+		for index, address := range sample.stack {
+			// The caller of each frame is the subroutine called by the next one out, the outermost being the program.
+			entry := profiler.machine.Platform.LoadAddress
+			if index+1 < len(sample.stack) {
+				entry = profiler.opcode(sample.stack[index+1]) & 0x0FFF
+			}
+			line := 0
+			if profiler.machine.Program != nil {
+				line = profiler.machine.Program.Lines[address]
+			}
+			locations = append(locations, pprof.location(address, profiler.functionName(entry), line))
+		}
+		pprof.sample(locations, int64(sample.cycles))
+	}
+	return pprof.write(writer)
+}
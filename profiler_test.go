@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// profiledProgram calls a subroutine at 0206 then waits for a key:
+// 0200: call 0206, 0202: v0 := key, 0204: jump 0204, 0206: v0 := 1, 0208: return
+var profiledProgram = []byte{0x22, 0x06, 0xF0, 0x0A, 0x12, 0x04, 0x60, 0x01, 0x00, 0xEE}
+
+func newTestProfiler(t *testing.T) (*chip8.Machine, *Profiler) {
+	t.Helper()
+	machine := chip8.NewMachine(chip8.NewManualClock())
+	if err := machine.LoadROM(profiledProgram); err != nil {
+		t.Fatal(err)
+	}
+	return machine, NewProfiler(machine)
+}
+
+func TestProfiler(t *testing.T) {
+	machine, profiler := newTestProfiler(t)
+	machine.RunCycles(10)
+	machine.Core.SetKey(5, true)
+	machine.RunCycles(2)
+
+	if profiler.Cycles() != 12 {
+		t.Errorf("counted %d cycles, want 12", profiler.Cycles())
+	}
+	for address, expected := range map[uint16]uint64{0x200: 1, 0x202: 8, 0x204: 1, 0x206: 1, 0x208: 1, 0x20A: 0} {
+		if count := profiler.Count(address); count != expected {
+			t.Errorf("%04X ran %d times, want %d", address, count, expected)
+		}
+	}
+	// FX0A waited 7 cycles before the key was pressed.
+	if profiler.WaitCycles() != 7 {
+		t.Errorf("counted %d cycles waiting for a key, want 7", profiler.WaitCycles())
+	}
+	expected := []SubroutineProfile{
+		{Address: 0x200, Calls: 0, Self: 10, Total: 12},
+		{Address: 0x206, Calls: 1, Self: 2, Total: 2},
+	}
+	if subroutines := profiler.Subroutines(); !slices.Equal(subroutines, expected) {
+		t.Errorf("the subroutines are %+v, want %+v", subroutines, expected)
+	}
+}
+
+// TestProfilerNestedCalls checks that a subroutine counts the cycles of the ones it calls in its total only.
+func TestProfilerNestedCalls(t *testing.T) {
+	machine := chip8.NewMachine(chip8.NewManualClock())
+	// 0200: call 0204, 0202: jump 0202, 0204: call 0208, 0206: return, 0208: return
+	if err := machine.LoadROM([]byte{0x22, 0x04, 0x12, 0x02, 0x22, 0x08, 0x00, 0xEE, 0x00, 0xEE}); err != nil {
+		t.Fatal(err)
+	}
+	profiler := NewProfiler(machine)
+	machine.RunCycles(6)
+	expected := []SubroutineProfile{
+		{Address: 0x200, Calls: 0, Self: 3, Total: 6},
+		{Address: 0x204, Calls: 1, Self: 2, Total: 3},
+		{Address: 0x208, Calls: 1, Self: 1, Total: 1},
+	}
+	if subroutines := profiler.Subroutines(); !slices.Equal(subroutines, expected) {
+		t.Errorf("the subroutines are %+v, want %+v", subroutines, expected)
+	}
+}
+
+// protoFields splits a protocol buffer message into its fields, the values of the length-delimited ones being
+// their content and the others being empty.
+func protoFields(t *testing.T, message []byte) (fields []int, values [][]byte) {
+	t.Helper()
+	for len(message) > 0 {
+		key, size := binary.Uvarint(message)
+		if size <= 0 {
+			t.Fatalf("invalid field key in % X", message)
+		}
+		message = message[size:]
+		var value []byte
+		switch key & 7 {
+		case 0:
+			_, size = binary.Uvarint(message)
+		case 2:
+			var length uint64
+			length, size = binary.Uvarint(message)
+			if size <= 0 || uint64(len(message)-size) < length {
+				t.Fatalf("invalid field length in % X", message)
+			}
+			value = message[size : size+int(length)]
+			size += int(length)
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		message = message[size:]
+		fields = append(fields, int(key>>3))
+		values = append(values, value)
+	}
+	return fields, values
+}
+
+func TestWriteProfile(t *testing.T) {
+	machine, profiler := newTestProfiler(t)
+	profiler.Labels["main"] = 0x200
+	profiler.Labels["one"] = 0x206
+	machine.RunCycles(10)
+
+	var output bytes.Buffer
+	if err := profiler.WriteProfile(&output); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := gzip.NewReader(&output)
+	if err != nil {
+		t.Fatalf("the profile is not gzipped: %v", err)
+	}
+	profile, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var table []string
+	var samples, cycles int
+	fields, values := protoFields(t, profile)
+	for index, field := range fields {
+		switch field {
+		case pprofProfileStringTable:
+			table = append(table, string(values[index]))
+		case pprofProfileSample:
+			samples++
+			sampleFields, sampleValues := protoFields(t, values[index])
+			for sampleIndex, sampleField := range sampleFields {
+				if sampleField == pprofSampleValue {
+					value, _ := binary.Uvarint(sampleValues[sampleIndex])
+					cycles += int(value)
+				}
+			}
+		}
+	}
+	// The names of the functions follow in the order of the samples.
+	if len(table) > 3 {
+		slices.Sort(table[3:])
+	}
+	if expected := []string{"", "cycles", "count", "main", "one"}; !slices.Equal(table, expected) {
+		t.Errorf("the string table is %q, want %q", table, expected)
+	}
+	// One sample per call stack: 0200, 0202, and 0206 and 0208 called from 0200.
+	if samples != 4 || cycles != 10 {
+		t.Errorf("the profile has %d samples of %d cycles, want 4 of 10", samples, cycles)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/nebul/chip8-go/chip8"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Script runs a Lua program alongside the machine, for bots, automated testers and training harnesses.
+// The program runs once when loaded and registers hooks through the chip8 table:
+//
+//	chip8.on_frame(function() end)                      after every frame
+//	chip8.on_pc(address, function() end)                before the instruction at address runs
+//	chip8.on_write(function(address, value) end [, from, to])  after the program writes to memory
+//
+// The same table reads and changes the machine:
+//
+//	chip8.v(x), chip8.set_v(x, value), chip8.i(), chip8.set_i(value), chip8.pc(), chip8.set_pc(address)
+//	chip8.sp(), chip8.stack(index), chip8.dt(), chip8.set_dt(value), chip8.st(), chip8.set_st(value)
+//	chip8.peek(address), chip8.poke(address, value), chip8.pixel(x, y), chip8.width(), chip8.height()
+//	chip8.press(key), chip8.release(key), chip8.key(key), chip8.frame()
+//	chip8.text(x, y, text [, "#RRGGBB"]), chip8.rect(x, y, width, height [, "#RRGGBB"]), chip8.clear_overlay()
+//	chip8.pause(), chip8.resume(), chip8.reset(), chip8.quit()
+//
+// Addresses outside of memory and register or key numbers above 15 raise a Lua error.
+// Hooks run on the goroutine driving the machine, with the machine locked. An error in a hook stops the machine.
+type Script struct {
+	machine    *chip8.Machine
+	state      *lua.LState
+	frame      int
+	frameHooks []*lua.LFunction
+	pcHooks    map[uint16][]*lua.LFunction
+	writeHooks []scriptWriteHook
+}
+
+// scriptWriteHook is a memory write hook watching the addresses from from to to, inclusive.
+type scriptWriteHook struct {
+	function *lua.LFunction
+	from, to uint32
+}
+
+// LoadScript runs the Lua program in the file and connects its hooks to the machine.
+func LoadScript(machine *chip8.Machine, path string) (*Script, error) {
+	script := &Script{machine: machine, state: lua.NewState(), pcHooks: map[uint16][]*lua.LFunction{}}
+	script.state.SetGlobal("chip8", script.state.SetFuncs(script.state.NewTable(), script.functions()))
+	if err := script.state.DoFile(path); err != nil {
+		script.state.Close()
+		return nil, err
+	}
+	machine.OnFrame(func(core *chip8.Chip8Core) {
+		script.frame++
+		for _, function := range script.frameHooks {
+			script.call(function)
+		}
+	})
+	machine.OnStep(func(address uint16) {
+		for _, function := range script.pcHooks[address] {
+			script.call(function)
+		}
+	})
+	machine.OnMemoryWrite(func(address uint32, value byte) {
+		for _, hook := range script.writeHooks {
+			if address >= hook.from && address <= hook.to {
+				script.call(hook.function, lua.LNumber(address), lua.LNumber(value))
+			}
+		}
+	})
+	return script, nil
+}
+
+func (script *Script) Close() {
+	script.state.Close()
+}
+
+// call runs a hook; once a hook has failed the script is left alone.
+func (script *Script) call(function *lua.LFunction, arguments ...lua.LValue) {
+	if err := script.state.CallByParam(lua.P{Fn: function, Protect: true}, arguments...); err != nil {
+		fmt.Println("Script error:", err)
+		script.machine.Stop()
+		script.frameHooks, script.writeHooks = nil, nil
+		clear(script.pcHooks)
+	}
+}
+
+// checkAddress reads an argument that must be an address inside Memory.
+func (script *Script) checkAddress(state *lua.LState, position int) uint32 {
+	address := state.CheckInt(position)
+	if address < 0 || address >= len(script.machine.Core.Memory) {
+		state.ArgError(position, "address outside of memory")
+	}
+	return uint32(address)
+}
+
+// checkIndex reads an argument that must be a register or key number.
+func checkIndex(state *lua.LState, position int) uint8 {
+	index := state.CheckInt(position)
+	if index < 0 || index > 0xF {
+		state.ArgError(position, "index must be between 0 and 15")
+	}
+	return uint8(index)
+}
+
+// optionalColor reads an optional "#RRGGBB" argument, white when missing.
+func optionalColor(state *lua.LState, position int) color.RGBA {
+	text := state.OptString(position, "#FFFFFF")
+	fill, err := ParseColor(text)
+	if err != nil {
+		state.ArgError(position, err.Error())
+	}
+	return fill
+}
+
+func (script *Script) functions() map[string]lua.LGFunction {
+	machine := script.machine
+	number := func(state *lua.LState, value int) int {
+		state.Push(lua.LNumber(value))
+		return 1
+	}
+	getter := func(value func() int) lua.LGFunction {
+		return func(state *lua.LState) int { return number(state, value()) }
+	}
+	setter := func(set func(value int)) lua.LGFunction {
+		return func(state *lua.LState) int {
+			set(state.CheckInt(1))
+			return 0
+		}
+	}
+	return map[string]lua.LGFunction{
+		"on_frame": func(state *lua.LState) int {
+			script.frameHooks = append(script.frameHooks, state.CheckFunction(1))
+			return 0
+		},
+		"on_pc": func(state *lua.LState) int {
+			address := uint16(script.checkAddress(state, 1))
+			script.pcHooks[address] = append(script.pcHooks[address], state.CheckFunction(2))
+			return 0
+		},
+		"on_write": func(state *lua.LState) int {
+			hook := scriptWriteHook{function: state.CheckFunction(1), to: uint32(len(machine.Core.Memory) - 1)}
+			if state.GetTop() >= 2 {
+				hook.from = script.checkAddress(state, 2)
+				hook.to = hook.from
+			}
+			if state.GetTop() >= 3 {
+				hook.to = script.checkAddress(state, 3)
+			}
+			script.writeHooks = append(script.writeHooks, hook)
+			return 0
+		},
+		"v": func(state *lua.LState) int {
+			return number(state, int(machine.Core.V[checkIndex(state, 1)]))
+		},
+		"set_v": func(state *lua.LState) int {
+			machine.Core.V[checkIndex(state, 1)] = byte(state.CheckInt(2))
+			return 0
+		},
+		"i": getter(func() int { return int(machine.Core.I) }),
+		"set_i": func(state *lua.LState) int {
+			machine.Core.SetI(script.checkAddress(state, 1))
+			return 0
+		},
+		"pc": getter(func() int { return int(machine.Core.PC) }),
+		"set_pc": func(state *lua.LState) int {
+			address := script.checkAddress(state, 1)
+			if address > 0xFFFF {
+				state.ArgError(1, "PC cannot go past 0xFFFF")
+			}
+			machine.Core.SetPC(uint16(address))
+			return 0
+		},
+		"sp": getter(func() int { return int(machine.Core.SP) }),
+		"stack": func(state *lua.LState) int {
+			return number(state, int(machine.Core.Stack[checkIndex(state, 1)]))
+		},
+		"dt":     getter(func() int { return int(machine.Core.DelayTimer) }),
+		"set_dt": setter(func(value int) { machine.Core.DelayTimer = byte(value) }),
+		"st":     getter(func() int { return int(machine.Core.SoundTimer) }),
+		"set_st": setter(func(value int) { machine.Core.SoundTimer = byte(value) }),
+		"peek": func(state *lua.LState) int {
+			return number(state, int(machine.Core.Memory[script.checkAddress(state, 1)]))
+		},
+		"poke": func(state *lua.LState) int {
+			machine.Core.Memory[script.checkAddress(state, 1)] = byte(state.CheckInt(2))
+			return 0
+		},
+		"pixel": func(state *lua.LState) int {
+			positionX, positionY := state.CheckInt(1), state.CheckInt(2)
+			core := machine.Core
+			lit := positionX >= 0 && positionY >= 0 && positionX < core.ScreenWidth() && positionY < core.ScreenHeight() &&
+				core.GetPixel(uint8(positionX), uint8(positionY))
+			state.Push(lua.LBool(lit))
+			return 1
+		},
+		"width":  getter(func() int { return machine.Core.ScreenWidth() }),
+		"height": getter(func() int { return machine.Core.ScreenHeight() }),
+		"press": func(state *lua.LState) int {
+			machine.Core.SetKey(checkIndex(state, 1), true)
+			return 0
+		},
+		"release": func(state *lua.LState) int {
+			machine.Core.SetKey(checkIndex(state, 1), false)
+			return 0
+		},
+		"key": func(state *lua.LState) int {
+			state.Push(lua.LBool(machine.Core.GetKey(checkIndex(state, 1))))
+			return 1
+		},
+		"frame": getter(func() int { return script.frame }),
+		"text": func(state *lua.LState) int {
+			machine.Overlay.AddText(state.CheckInt(1), state.CheckInt(2), state.CheckString(3), optionalColor(state, 4))
+			return 0
+		},
+		"rect": func(state *lua.LState) int {
+			machine.Overlay.AddRectangle(state.CheckInt(1), state.CheckInt(2), state.CheckInt(3), state.CheckInt(4), optionalColor(state, 5))
+			return 0
+		},
+		"clear_overlay": func(state *lua.LState) int {
+			machine.Overlay.Clear()
+			return 0
+		},
+		"pause": func(state *lua.LState) int {
+			machine.Pause()
+			return 0
+		},
+		"resume": func(state *lua.LState) int {
+			machine.Resume()
+			return 0
+		},
+		"reset": func(state *lua.LState) int {
+			machine.Reset()
+			return 0
+		},
+		"quit": func(state *lua.LState) int {
+			machine.Stop()
+			return 0
+		},
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nebul/chip8-go/chip8"
+)
+
+// loadTestScript loads a Lua script alongside a machine running V0 += 1 in a loop that saves V0 to 0x300 on every turn.
+func loadTestScript(t *testing.T, source string) (*chip8.Machine, *Script, error) {
+	t.Helper()
+	machine := chip8.NewMachine(chip8.NewManualClock())
+	// 0200: I := 0x300, 0202: V0 += 1, 0204: save V0, 0206: jump 0202
+	if err := machine.LoadROM([]byte{0xA3, 0x00, 0x70, 0x01, 0xF0, 0x55, 0x12, 0x02}); err != nil {
+		t.Fatal(err)
+	}
+	machine.Core.Quirks.LoadStore = true
+	path := filepath.Join(t.TempDir(), "test.lua")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script, err := LoadScript(machine, path)
+	if err == nil {
+		t.Cleanup(script.Close)
+	}
+	return machine, script, err
+}
+
+// scriptResults runs Lua code in the state of the script and joins what it returns.
+func scriptResults(t *testing.T, script *Script, code string) string {
+	t.Helper()
+	if err := script.state.DoString(code); err != nil {
+		t.Fatal(err)
+	}
+	var results []string
+	for index := 1; index <= script.state.GetTop(); index++ {
+		results = append(results, script.state.Get(index).String())
+	}
+	script.state.SetTop(0)
+	return strings.Join(results, " ")
+}
+
+func TestScriptHooks(t *testing.T) {
+	machine, script, err := loadTestScript(t, `
+		frames, visits, writes = 0, 0, {}
+		chip8.on_frame(function() frames = chip8.frame() end)
+		chip8.on_pc(0x204, function() visits = visits + 1 end)
+		chip8.on_write(function(address, value) table.insert(writes, address .. "=" .. value) end, 0x300)
+		chip8.on_pc(0x206, function()
+			if chip8.v(0) == 3 then
+				chip8.set_v(1, chip8.peek(0x300) * 2)
+				chip8.poke(0x301, 0xAA)
+				chip8.pause()
+			end
+		end)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine.RunFrame()
+	machine.RunFrame()
+
+	if machine.Core.V[0] != 3 || machine.Core.V[1] != 6 || machine.Core.Memory[0x301] != 0xAA || !machine.Paused() {
+		t.Errorf("V0 %d, V1 %d, 0x301 %02X and paused %t, want 3, 6, AA and paused",
+			machine.Core.V[0], machine.Core.V[1], machine.Core.Memory[0x301], machine.Paused())
+	}
+	state := scriptResults(t, script, "return frames, visits, table.concat(writes, ',')")
+	if state != "2 3 768=1,768=2,768=3" {
+		t.Errorf("the hooks saw %q, want 2 frames, 3 visits of 0x204 and 3 writes to 0x300", state)
+	}
+}
+
+func TestScriptRegisters(t *testing.T) {
+	machine, _, err := loadTestScript(t, `
+		chip8.set_i(0x345)
+		chip8.set_pc(0x206)
+		chip8.set_dt(30)
+		chip8.set_st(40)
+		chip8.press(0xA)
+		chip8.text(1, 2, "score", "#FF0000")
+		chip8.rect(3, 4, 5, 6)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	core := machine.Core
+	if core.I != 0x345 || core.PC != 0x206 || core.DelayTimer != 30 || core.SoundTimer != 40 || !core.GetKey(0xA) {
+		t.Errorf("I %03X, PC %03X, DT %d, ST %d and key A %t, want 345, 206, 30, 40 and pressed",
+			core.I, core.PC, core.DelayTimer, core.SoundTimer, core.GetKey(0xA))
+	}
+	expected := []chip8.OverlayItem{
+		{X: 1, Y: 2, Text: "score", Color: color.RGBA{R: 0xFF, A: 0xFF}},
+		{X: 3, Y: 4, Width: 5, Height: 6, Color: color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}},
+	}
+	if len(machine.Overlay.Items) != len(expected) || machine.Overlay.Items[0] != expected[0] || machine.Overlay.Items[1] != expected[1] {
+		t.Errorf("overlay holds %+v, want %+v", machine.Overlay.Items, expected)
+	}
+}
+
+func TestScriptErrors(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Source string
+		Error  string
+	}{
+		{"set_pc outside of memory", "chip8.set_pc(0x1000)", "address outside of memory"},
+		{"set_i outside of memory", "chip8.set_i(-1)", "address outside of memory"},
+		{"peek outside of memory", "chip8.peek(0x2000)", "address outside of memory"},
+		{"register above 15", "chip8.set_v(16, 1)", "index must be between 0 and 15"},
+		{"invalid color", `chip8.rect(0, 0, 1, 1, "red")`, "#RRGGBB"},
+		{"syntax error", "chip8.pause(", ""},
+	}
+	for _, errorCase := range cases {
+		t.Run(errorCase.Name, func(t *testing.T) {
+			_, _, err := loadTestScript(t, errorCase.Source)
+			if err == nil || !strings.Contains(err.Error(), errorCase.Error) {
+				t.Errorf("got error %v, want one containing %q", err, errorCase.Error)
+			}
+		})
+	}
+
+	t.Run("failing hook", func(t *testing.T) {
+		machine, script, err := loadTestScript(t, `
+			calls = 0
+			chip8.on_frame(function() calls = calls + 1; error("broken") end)
+		`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		machine.RunFrame()
+		machine.RunFrame()
+		if calls := scriptResults(t, script, "return calls"); calls != "1" {
+			t.Errorf("the failing hook ran %s times, want once", calls)
+		}
+	})
+}